@@ -4,15 +4,22 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/quasttyy/pr-reviewer/internal/config"
 	"github.com/quasttyy/pr-reviewer/internal/handlers"
+	"github.com/quasttyy/pr-reviewer/internal/metrics"
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
 	"github.com/quasttyy/pr-reviewer/internal/postgres"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
 	"github.com/quasttyy/pr-reviewer/internal/service"
+	"github.com/quasttyy/pr-reviewer/internal/tracing"
 	logger "github.com/quasttyy/pr-reviewer/internal/utils"
-	"github.com/go-chi/chi/v5"
 )
 
 func main() {
@@ -22,8 +29,22 @@ func main() {
 	// Инициализируем логгер 
 	logger.Init(cfg.Env)
 
-	// Инициализируем контекст
-	ctx := context.Background()
+	// Инициализируем контекст: он отменяется по SIGINT/SIGTERM и останавливает фоновые задачи
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Инициализируем трассировку
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		File:         cfg.Tracing.File,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		ServiceName:  cfg.Tracing.ServiceName,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("invalid tracing config", "error", err)
+	}
 
 	// Инициализируем пул соединений
 	pool := postgres.InitPool(
@@ -31,8 +52,8 @@ func main() {
 		cfg.Database.DSN,
 		cfg.Database.MinConns,
 		cfg.Database.MaxConns,
+		tracing.QueryTracer{},
 	)
-	defer pool.Close()
 
 	// Инициализируем репозитории и сервисы
 	teamRepo := repo.NewTeamRepo(pool)
@@ -40,39 +61,124 @@ func main() {
 	teamH := handlers.NewTeamHandlers(teamSvc)
 	userRepo := repo.NewUserRepo(pool)
 	prRepo := repo.NewPRRepo(pool)
-	userSvc := service.NewUserService(userRepo, prRepo)
-	userH := handlers.NewUserHandlers(userSvc)
-	prSvc := service.NewPRService(prRepo)
+	txManager := repo.NewTxManager(pool)
+	selectors, err := service.NewSelectorRegistry(cfg.Reviewers.Strategy, cfg.Reviewers.TeamStrategies, prRepo)
+	if err != nil {
+		logger.Fatal("invalid reviewers config", "error", err)
+	}
+	mergePolicy := service.MergePolicy{
+		RequiredApprovals:          cfg.MergePolicy.RequiredApprovals,
+		BlockOnChangesRequested:    cfg.MergePolicy.BlockOnChangesRequested,
+		BlockWhenNeedMoreReviewers: cfg.MergePolicy.BlockWhenNeedMoreReviewers,
+	}
+	workingHours := service.WorkingHoursPolicy{
+		Mode:     cfg.WorkingHours.Mode,
+		Fallback: cfg.WorkingHours.Fallback,
+	}
+	if err := workingHours.Validate(); err != nil {
+		logger.Fatal("invalid working_hours config", "error", err)
+	}
+	prSvc := service.NewPRService(prRepo, teamRepo, selectors, mergePolicy, workingHours, txManager)
+	appMetrics := metrics.New(pool, prSvc.CountUnderReviewed)
+	prSvc.SetMetrics(appMetrics)
+	webhookRepo := repo.NewWebhookRepo(pool)
+	subscriptionSvc := service.NewSubscriptionService(webhookRepo)
+	subscriptionH := handlers.NewSubscriptionHandlers(subscriptionSvc)
 	prH := handlers.NewPRHandlers(prSvc)
+	userSvc := service.NewUserService(userRepo, prRepo, txManager, prSvc)
+	userSvc.SetMetrics(appMetrics)
+	userH := handlers.NewUserHandlers(userSvc)
+	availabilitySvc := service.NewAvailabilityService(userRepo)
+	availabilityH := handlers.NewAvailabilityHandlers(availabilitySvc)
+	identitySvc := service.NewIdentityService(userRepo)
+	identityH := handlers.NewIdentityHandlers(identitySvc)
+	chatSvc := service.NewChatService(userRepo)
+	chatH := handlers.NewChatHandlers(chatSvc)
+	webhookSvc := service.NewWebhookService(prSvc, identitySvc)
+	webhookH := handlers.NewWebhookHandlers(webhookSvc, handlers.WebhookSecrets{
+		GitHub: cfg.Webhooks.GitHubSecret,
+		GitLab: cfg.Webhooks.GitLabToken,
+	})
 
-	// Создаем роутер chi
-	r := chi.NewRouter()
+	// Фоновые задачи; при остановке их ждём, чтобы они не работали с закрытым пулом
+	var workers sync.WaitGroup
 
-	// Health
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	// Фоновый добор ревьюверов
+	if cfg.Reconciler.Interval > 0 {
+		reconciler := service.NewReconciler(prSvc, cfg.Reconciler.Interval)
+		workers.Go(func() { reconciler.Run(ctx) })
+	}
 
-	// Team
-	r.Route("/team", func(rt chi.Router) {
-		rt.Post("/add", teamH.AddTeam)
-		rt.Get("/get", teamH.GetTeam)
-	})
+	// Фоновая публикация событий PR из outbox. Приёмник chat доступен,
+	// только если задан входящий вебхук мессенджера
+	serviceSinks := []outbox.Sink{service.NewWebhookSink(subscriptionSvc)}
+	if cfg.Chat.WebhookURL != "" {
+		notifier, err := service.NewChatNotifier(userRepo, outbound.NewSender(cfg.Chat.Timeout), cfg.Chat.WebhookURL, service.ChatTemplates{
+			Link:       cfg.Chat.LinkTemplate,
+			Assigned:   cfg.Chat.AssignedTemplate,
+			Reassigned: cfg.Chat.ReassignedTemplate,
+		})
+		if err != nil {
+			logger.Fatal("invalid chat templates", "error", err)
+		}
+		serviceSinks = append(serviceSinks, notifier)
+	}
+	sinks, err := outbox.NewSinks(outbox.Config{
+		Sinks:       cfg.Outbox.Sinks,
+		File:        cfg.Outbox.File,
+		HTTPURL:     cfg.Outbox.HTTPURL,
+		HTTPSecret:  cfg.Outbox.HTTPSecret,
+		HTTPTimeout: cfg.Outbox.HTTPTimeout,
+	}, serviceSinks...)
+	if err != nil {
+		logger.Fatal("invalid outbox config", "error", err)
+	}
+	if cfg.Outbox.Interval > 0 {
+		retry := service.RetryPolicy{
+			MaxAttempts: cfg.Outbox.MaxAttempts,
+			BackoffBase: cfg.Outbox.BackoffBase,
+			BackoffMax:  cfg.Outbox.BackoffMax,
+		}
+		if err := retry.Validate(); err != nil {
+			logger.Fatal("invalid outbox config", "error", err)
+		}
+		relay := service.NewRelay(repo.NewOutboxRepo(pool), sinks, retry, cfg.Outbox.Interval, cfg.Outbox.Retention)
+		workers.Go(func() { relay.Run(ctx) })
+	}
 
-	// Users
-	r.Route("/users", func(ru chi.Router) {
-		ru.Post("/setIsActive", userH.SetIsActive)
-		ru.Get("/getReview", userH.GetReview)
-	})
+	// Фоновая отправка исходящих вебхуков
+	if cfg.OutboundWebhooks.Interval > 0 {
+		retry := service.RetryPolicy{
+			MaxAttempts: cfg.OutboundWebhooks.MaxAttempts,
+			BackoffBase: cfg.OutboundWebhooks.BackoffBase,
+			BackoffMax:  cfg.OutboundWebhooks.BackoffMax,
+		}
+		if err := retry.Validate(); err != nil {
+			logger.Fatal("invalid outbound_webhooks config", "error", err)
+		}
+		sender := outbound.NewSender(cfg.OutboundWebhooks.Timeout)
+		dispatcher := service.NewDispatcher(webhookRepo, sender, retry, cfg.OutboundWebhooks.Interval)
+		workers.Go(func() { dispatcher.Run(ctx) })
+	}
+	auth := handlers.NewAuth(cfg.Security.AdminToken, cfg.Security.UserToken, cfg.Security.MetricsToken)
 
-	// Pull Requests
-	r.Route("/pullRequest", func(rp chi.Router) {
-		rp.Post("/create", prH.Create)
-		rp.Post("/merge", prH.Merge)
-		rp.Post("/reassign", prH.Reassign)
-		rp.Get("/stats", prH.GetReviewerAssignments)
+	// Собираем роутер
+	r, err := handlers.NewRouter(handlers.RouterConfig{
+		Auth:          auth,
+		Team:          teamH,
+		Users:         userH,
+		PR:            prH,
+		Availability:  availabilityH,
+		Identities:    identityH,
+		Chat:          chatH,
+		Webhooks:      webhookH,
+		Subscriptions: subscriptionH,
+		Metrics:       appMetrics.Handler(),
+		Middlewares:   []func(http.Handler) http.Handler{tracing.Middleware, appMetrics.Middleware},
 	})
+	if err != nil {
+		logger.Fatal("failed to build router", "error", err)
+	}
 
 	// Указываем адрес и порт
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -83,8 +189,36 @@ func main() {
 	}
 
 	// Запускаем сервер
-	logger.Info("starting http server", "addr", addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Fatal("http server failed", "error", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting http server", "addr", addr)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// Ждём сигнала остановки или падения сервера
+	failed := false
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	case err := <-serverErr:
+		logger.Error("http server failed", "error", err)
+		failed = true
+	}
+	stop()
+
+	// Дожидаемся текущих запросов и фоновых задач, затем закрываем пул
+	// и выгружаем накопленные спаны
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("http server shutdown failed", "error", err)
+	}
+	workers.Wait()
+	pool.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("tracing shutdown failed", "error", err)
+	}
+	if failed {
+		os.Exit(1)
 	}
 }
\ No newline at end of file
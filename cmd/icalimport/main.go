@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/quasttyy/pr-reviewer/internal/config"
+	"github.com/quasttyy/pr-reviewer/internal/postgres"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// Импорт периодов недоступности ревьюверов из файла iCalendar:
+//
+//	go run ./cmd/icalimport -file team.ics -source backend-calendar
+func main() {
+	file := flag.String("file", "", "path to .ics file")
+	source := flag.String("source", service.DefaultICalSource, "calendar name; re-import with the same source updates periods instead of duplicating them")
+	configPath := flag.String("config", "config.yaml", "path to config file")
+	flag.Parse()
+
+	// Загружаем конфиг
+	cfg := config.MustLoad(*configPath)
+
+	// Инициализируем логгер
+	logger.Init(cfg.Env)
+
+	if *file == "" {
+		logger.Fatal("-file is required")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		logger.Fatal("failed to open calendar", "error", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	pool := postgres.InitPool(ctx, cfg.Database.DSN, cfg.Database.MinConns, cfg.Database.MaxConns, nil)
+	defer pool.Close()
+
+	svc := service.NewAvailabilityService(repo.NewUserRepo(pool))
+	report, err := svc.ImportICal(ctx, *source, f)
+	if err != nil {
+		logger.Fatal("import failed", "error", err)
+	}
+	for _, sk := range report.Skipped {
+		logger.Warn("event skipped", "uid", sk.UID, "summary", sk.Summary, "reason", sk.Reason)
+	}
+	logger.Info("calendar imported",
+		"source", *source,
+		"created", report.Created,
+		"updated", report.Updated,
+		"unchanged", report.Unchanged,
+		"skipped", len(report.Skipped),
+	)
+}
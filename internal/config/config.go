@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -25,7 +26,104 @@ type Config struct {
 	Security struct {
 		AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN"`
 		UserToken  string `yaml:"user_token" env:"USER_TOKEN"`
+
+		// Токен, дающий только чтение /metrics; пустой — метрики доступны лишь администратору
+		MetricsToken string `yaml:"metrics_token" env:"METRICS_TOKEN"`
 	} `yaml:"security"`
+
+	Reviewers struct {
+		// Стратегия выбора ревьюверов: random, round_robin, least_loaded
+		Strategy string `yaml:"strategy" env:"REVIEWER_STRATEGY" env-default:"random"`
+		// Переопределение стратегии для отдельных команд: team_name -> strategy
+		TeamStrategies map[string]string `yaml:"team_strategies"`
+	} `yaml:"reviewers"`
+
+	WorkingHours struct {
+		// Учёт рабочих часов при выборе ревьюверов: off, prefer, require
+		Mode string `yaml:"mode" env:"WORKING_HOURS_MODE" env-default:"off"`
+		// В режиме require, если в рабочих часах никого нет: any — выбрать без учёта часов, none — никого
+		Fallback string `yaml:"fallback" env:"WORKING_HOURS_FALLBACK" env-default:"any"`
+	} `yaml:"working_hours"`
+
+	MergePolicy struct {
+		// Сколько назначенных ревьюверов должны ответить APPROVED; 0 — не проверять
+		RequiredApprovals int `yaml:"required_approvals" env:"MERGE_REQUIRED_APPROVALS"`
+		// Запрещать merge, пока у кого-то из ревьюверов CHANGES_REQUESTED
+		BlockOnChangesRequested bool `yaml:"block_on_changes_requested" env:"MERGE_BLOCK_ON_CHANGES_REQUESTED"`
+		// Запрещать merge PR с need_more_reviewers
+		BlockWhenNeedMoreReviewers bool `yaml:"block_when_need_more_reviewers" env:"MERGE_BLOCK_WHEN_NEED_MORE_REVIEWERS"`
+	} `yaml:"merge_policy"`
+
+	Reconciler struct {
+		// Период фонового добора ревьюверов; 0 отключает фоновый добор
+		Interval time.Duration `yaml:"interval" env:"RECONCILE_INTERVAL" env-default:"1m"`
+	} `yaml:"reconciler"`
+
+	Tracing struct {
+		// Экспортёр трейсов: none, stdout, file, otlp
+		Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+		// Файл для экспортёра file
+		File string `yaml:"file" env:"TRACING_FILE" env-default:"traces.jsonl"`
+		// Адрес OTLP/HTTP коллектора (host:port) для экспортёра otlp
+		OTLPEndpoint string `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" env-default:"localhost:4318"`
+		// Подключаться к коллектору без TLS
+		OTLPInsecure bool `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE" env-default:"true"`
+		// service.name в трейсах
+		ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME" env-default:"pr-reviewer"`
+		// Доля сэмплируемых трейсов от 0 до 1
+		SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+	} `yaml:"tracing"`
+
+	Webhooks struct {
+		// Секрет подписи вебхуков GitHub (X-Hub-Signature-256); пустой отключает приём
+		GitHubSecret string `yaml:"github_secret" env:"GITHUB_WEBHOOK_SECRET"`
+		// Токен вебхуков GitLab (X-Gitlab-Token); пустой отключает приём
+		GitLabToken string `yaml:"gitlab_token" env:"GITLAB_WEBHOOK_TOKEN"`
+	} `yaml:"webhooks"`
+
+	OutboundWebhooks struct {
+		// Период отправки исходящих вебхуков; 0 отключает отправку, события копятся в очереди
+		Interval time.Duration `yaml:"interval" env:"OUTBOUND_WEBHOOKS_INTERVAL" env-default:"5s"`
+		// Число попыток (не меньше 1), после которого доставка помечается failed
+		MaxAttempts int `yaml:"max_attempts" env:"OUTBOUND_WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+		// Задержка перед второй попыткой; дальше удваивается до backoff_max
+		BackoffBase time.Duration `yaml:"backoff_base" env:"OUTBOUND_WEBHOOKS_BACKOFF_BASE" env-default:"30s"`
+		BackoffMax  time.Duration `yaml:"backoff_max" env:"OUTBOUND_WEBHOOKS_BACKOFF_MAX" env-default:"1h"`
+		// Таймаут одного запроса к получателю
+		Timeout time.Duration `yaml:"timeout" env:"OUTBOUND_WEBHOOKS_TIMEOUT" env-default:"10s"`
+	} `yaml:"outbound_webhooks"`
+
+	Outbox struct {
+		// Период публикации событий из outbox; 0 отключает публикацию, события копятся в таблице
+		Interval time.Duration `yaml:"interval" env:"OUTBOX_INTERVAL" env-default:"1s"`
+		// Сколько хранить опубликованные события; 0 — бессрочно
+		Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" env-default:"168h"`
+		// Повторы в приёмник, отклонивший событие: после max_attempts попыток (не меньше 1)
+		// приёмник пропускает событие. Задержка удваивается от backoff_base до backoff_max
+		MaxAttempts int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" env-default:"10"`
+		BackoffBase time.Duration `yaml:"backoff_base" env:"OUTBOX_BACKOFF_BASE" env-default:"5s"`
+		BackoffMax  time.Duration `yaml:"backoff_max" env:"OUTBOX_BACKOFF_MAX" env-default:"10m"`
+		// Приёмники событий по порядку: webhooks, chat, log, file, http
+		Sinks []string `yaml:"sinks" env:"OUTBOX_SINKS" env-default:"webhooks"`
+		// Файл для приёмника file
+		File string `yaml:"file" env:"OUTBOX_FILE" env-default:"outbox.jsonl"`
+		// Адрес, секрет подписи и таймаут для приёмника http
+		HTTPURL     string        `yaml:"http_url" env:"OUTBOX_HTTP_URL"`
+		HTTPSecret  string        `yaml:"http_secret" env:"OUTBOX_HTTP_SECRET"`
+		HTTPTimeout time.Duration `yaml:"http_timeout" env:"OUTBOX_HTTP_TIMEOUT" env-default:"10s"`
+	} `yaml:"outbox"`
+
+	Chat struct {
+		// Входящий вебхук Slack или совместимого мессенджера для приёмника outbox chat
+		WebhookURL string `yaml:"webhook_url" env:"CHAT_WEBHOOK_URL"`
+		// Таймаут одного запроса к мессенджеру
+		Timeout time.Duration `yaml:"timeout" env:"CHAT_TIMEOUT" env-default:"10s"`
+		// Шаблоны text/template: ссылка на PR и сообщения о назначении и переназначении.
+		// Пустые сообщения — шаблоны по умолчанию, пустая ссылка — без ссылки
+		LinkTemplate       string `yaml:"link_template" env:"CHAT_LINK_TEMPLATE"`
+		AssignedTemplate   string `yaml:"assigned_template" env:"CHAT_ASSIGNED_TEMPLATE"`
+		ReassignedTemplate string `yaml:"reassigned_template" env:"CHAT_REASSIGNED_TEMPLATE"`
+	} `yaml:"chat"`
 }
 
 // MustLoad читает YAML и ENV в одну структуру
@@ -6,10 +6,30 @@ import "time"
 type PRStatus string
 
 const (
+	PRStatusDraft  PRStatus = "DRAFT"
 	PRStatusOpen   PRStatus = "OPEN"
 	PRStatusMerged PRStatus = "MERGED"
+	PRStatusClosed PRStatus = "CLOSED"
 )
 
+// Решение ревьювера по PR
+type ReviewVerdict string
+
+const (
+	VerdictApproved         ReviewVerdict = "APPROVED"
+	VerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED"
+	VerdictCommented        ReviewVerdict = "COMMENTED"
+)
+
+// Valid сообщает, является ли значение одним из допустимых решений
+func (v ReviewVerdict) Valid() bool {
+	switch v {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return true
+	}
+	return false
+}
+
 // Структура пулл реквеста
 type PullRequest struct {
 	ID                string
@@ -20,5 +40,6 @@ type PullRequest struct {
 	NeedMoreReviewers bool
 	CreatedAt         time.Time
 	MergedAt          *time.Time
+	ClosedAt          *time.Time
 }
 
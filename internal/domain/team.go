@@ -12,3 +12,16 @@ type TeamMember struct {
 	Username string
 	IsActive bool
 }
+
+// Настройки команды: сколько ревьюверов назначать на PR её участников.
+// MinReviewers — кворум, ниже которого PR помечается need_more_reviewers.
+// FallbackTeams — упорядоченный список команд, из которых добираются
+// недостающие ревьюверы. DefaultMaxOpenReviews — лимит одновременных ревью
+// для участников без собственного max_open_reviews (nil — без ограничения)
+type TeamSettings struct {
+	TeamName              string
+	MinReviewers          int
+	MaxReviewers          int
+	FallbackTeams         []string
+	DefaultMaxOpenReviews *int
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+// Role — уровень доступа, который даёт предъявленный токен
+type Role string
+
+const (
+	RoleAdmin Role = "admin"
+	RoleUser  Role = "user"
+	// RoleMetrics даёт только чтение /metrics, например для Prometheus
+	RoleMetrics Role = "metrics"
+)
+
+type roleCtxKey struct{}
+
+// RoleFromContext возвращает роль, проставленную middleware авторизации
+func RoleFromContext(ctx context.Context) (Role, bool) {
+	role, ok := ctx.Value(roleCtxKey{}).(Role)
+	return role, ok
+}
+
+// Auth проверяет bearer-токены из config.Security
+type Auth struct {
+	adminToken   string
+	userToken    string
+	metricsToken string
+}
+
+func NewAuth(adminToken, userToken, metricsToken string) *Auth {
+	return &Auth{adminToken: adminToken, userToken: userToken, metricsToken: metricsToken}
+}
+
+// RequireUser пропускает запросы с токеном пользователя или администратора
+func (a *Auth) RequireUser(next http.Handler) http.Handler {
+	return a.require(RoleUser, next)
+}
+
+// RequireAdmin пропускает только запросы с токеном администратора
+func (a *Auth) RequireAdmin(next http.Handler) http.Handler {
+	return a.require(RoleAdmin, next)
+}
+
+// RequireMetrics пропускает запросы с токеном сбора метрик или администратора
+func (a *Auth) RequireMetrics(next http.Handler) http.Handler {
+	return a.require(RoleMetrics, next)
+}
+
+func (a *Auth) require(need Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing bearer token")
+			return
+		}
+		role, ok := a.roleFor(token)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid token")
+			return
+		}
+		// Администратору доступно всё, остальным — только маршруты своей роли
+		if role != RoleAdmin && role != need {
+			writeError(w, http.StatusForbidden, "FORBIDDEN", string(need)+" token required")
+			return
+		}
+		ctx := context.WithValue(r.Context(), roleCtxKey{}, role)
+		ctx = service.WithActor(ctx, actorFor(r, role))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// roleFor сопоставляет токен с ролью. Пустые токены в конфиге ничего не разрешают
+func (a *Auth) roleFor(token string) (Role, bool) {
+	if tokenEqual(token, a.adminToken) {
+		return RoleAdmin, true
+	}
+	if tokenEqual(token, a.userToken) {
+		return RoleUser, true
+	}
+	if tokenEqual(token, a.metricsToken) {
+		return RoleMetrics, true
+	}
+	return "", false
+}
+
+func tokenEqual(got, want string) bool {
+	if want == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// actorFor формирует автора изменений для журнала событий: роль токена и,
+// если клиент его передал, имя из заголовка X-Actor ("admin:alice").
+// Проверена только роль: токены общие на роль, поэтому имя — подпись клиента,
+// которую любой владелец токена может задать произвольно. Для аудита
+// доверять можно только части до двоеточия
+func actorFor(r *http.Request, role Role) string {
+	if name := strings.TrimSpace(r.Header.Get("X-Actor")); name != "" {
+		return string(role) + ":" + name
+	}
+	return string(role)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+func TestAuth_Require(t *testing.T) {
+	auth := NewAuth("adm", "usr", "mtr")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, found := RoleFromContext(r.Context()); !found {
+			t.Fatalf("role must be set in context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		mw     func(http.Handler) http.Handler
+		header string
+		want   int
+	}{
+		{"user route without token", auth.RequireUser, "", http.StatusUnauthorized},
+		{"user route with wrong scheme", auth.RequireUser, "Basic usr", http.StatusUnauthorized},
+		{"user route with unknown token", auth.RequireUser, "Bearer nope", http.StatusUnauthorized},
+		{"user route with user token", auth.RequireUser, "Bearer usr", http.StatusOK},
+		{"user route with admin token", auth.RequireUser, "Bearer adm", http.StatusOK},
+		{"admin route with user token", auth.RequireAdmin, "Bearer usr", http.StatusForbidden},
+		{"admin route with admin token", auth.RequireAdmin, "bearer adm", http.StatusOK},
+		{"user route with metrics token", auth.RequireUser, "Bearer mtr", http.StatusForbidden},
+		{"admin route with metrics token", auth.RequireAdmin, "Bearer mtr", http.StatusForbidden},
+		{"metrics route without token", auth.RequireMetrics, "", http.StatusUnauthorized},
+		{"metrics route with user token", auth.RequireMetrics, "Bearer usr", http.StatusForbidden},
+		{"metrics route with metrics token", auth.RequireMetrics, "Bearer mtr", http.StatusOK},
+		{"metrics route with admin token", auth.RequireMetrics, "Bearer adm", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			tc.mw(ok).ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestAuth_EmptyConfiguredTokenRejected(t *testing.T) {
+	auth := NewAuth("adm", "", "")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer  ")
+	rec := httptest.NewRecorder()
+	auth.RequireUser(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuth_ActorKeepsVerifiedRole(t *testing.T) {
+	auth := NewAuth("adm", "usr", "mtr")
+	var actor string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = service.ActorFromContext(r.Context())
+	})
+	// Имя из X-Actor не проверяется, но роль в actor берётся только из токена
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer usr")
+	req.Header.Set("X-Actor", "admin")
+	auth.RequireUser(next).ServeHTTP(httptest.NewRecorder(), req)
+	if actor != "user:admin" {
+		t.Fatalf("actor = %q, want user:admin", actor)
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/ical"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+type AvailabilityHandlers struct {
+	svc *service.AvailabilityService
+}
+
+func NewAvailabilityHandlers(svc *service.AvailabilityService) *AvailabilityHandlers {
+	return &AvailabilityHandlers{svc: svc}
+}
+
+type unavailabilityDTO struct {
+	ID       int64     `json:"id"`
+	UserID   string    `json:"user_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+	Source   string    `json:"source,omitempty"`
+}
+
+func toUnavailabilityDTO(row repo.UnavailabilityRow) unavailabilityDTO {
+	return unavailabilityDTO{
+		ID:       row.ID,
+		UserID:   row.UserID,
+		StartsAt: row.StartsAt,
+		EndsAt:   row.EndsAt,
+		Reason:   row.Reason,
+		Source:   row.Source,
+	}
+}
+
+// GET /users/availability?user_id=...
+// Текущие и будущие периоды недоступности пользователя
+func (h *AvailabilityHandlers) List(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	rows, err := h.svc.List(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		UserID  string              `json:"user_id"`
+		Periods []unavailabilityDTO `json:"periods"`
+	}{
+		UserID:  userID,
+		Periods: []unavailabilityDTO{},
+	}
+	for _, row := range rows {
+		resp.Periods = append(resp.Periods, toUnavailabilityDTO(row))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /users/availability
+// from и to — RFC 3339 или дата YYYY-MM-DD (UTC); дата в to включается в период целиком
+func (h *AvailabilityHandlers) Add(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string `json:"user_id"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" || req.From == "" || req.To == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id, from and to are required")
+		return
+	}
+	startsAt, err := parseBound(req.From, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "from must be RFC 3339 time or YYYY-MM-DD")
+		return
+	}
+	endsAt, err := parseBound(req.To, true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "to must be RFC 3339 time or YYYY-MM-DD")
+		return
+	}
+	row, err := h.svc.Add(r.Context(), req.UserID, startsAt, endsAt, req.Reason)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		Period unavailabilityDTO `json:"period"`
+	}{Period: toUnavailabilityDTO(row)}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// DELETE /users/availability?user_id=...&id=...
+func (h *AvailabilityHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if userID == "" || err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id and numeric id are required")
+		return
+	}
+	if err := h.svc.Delete(r.Context(), userID, id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type workingHoursDTO struct {
+	UserID    string `json:"user_id"`
+	TimeZone  string `json:"time_zone"`
+	WorkStart string `json:"work_start,omitempty"`
+	WorkEnd   string `json:"work_end,omitempty"`
+}
+
+func writeWorkingHours(w http.ResponseWriter, row repo.WorkingHoursRow) {
+	writeJSON(w, http.StatusOK, workingHoursDTO{
+		UserID:    row.UserID,
+		TimeZone:  row.TimeZone,
+		WorkStart: row.WorkStart,
+		WorkEnd:   row.WorkEnd,
+	})
+}
+
+// GET /users/workingHours?user_id=...
+func (h *AvailabilityHandlers) GetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	row, err := h.svc.GetWorkingHours(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeWorkingHours(w, row)
+}
+
+// POST /users/workingHours
+// time_zone — IANA-имя часового пояса; work_start и work_end — "HH:MM",
+// окно может переходить через полночь. Без work_start/work_end ограничения по часам нет
+func (h *AvailabilityHandlers) SetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	var req workingHoursDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" || req.TimeZone == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id and time_zone are required")
+		return
+	}
+	row, err := h.svc.SetWorkingHours(r.Context(), repo.WorkingHoursRow{
+		UserID:    req.UserID,
+		TimeZone:  req.TimeZone,
+		WorkStart: req.WorkStart,
+		WorkEnd:   req.WorkEnd,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeWorkingHours(w, row)
+}
+
+// max_open_reviews — собственный лимит пользователя, effective_max_open_reviews —
+// действующий с учётом лимита команды по умолчанию. null — без ограничения
+type capacityDTO struct {
+	UserID                  string `json:"user_id"`
+	MaxOpenReviews          *int   `json:"max_open_reviews"`
+	EffectiveMaxOpenReviews *int   `json:"effective_max_open_reviews"`
+	OpenReviews             int    `json:"open_reviews"`
+	AtCapacity              bool   `json:"at_capacity"`
+}
+
+func writeCapacity(w http.ResponseWriter, row repo.ReviewCapacityRow) {
+	writeJSON(w, http.StatusOK, capacityDTO{
+		UserID:                  row.UserID,
+		MaxOpenReviews:          row.MaxOpenReviews,
+		EffectiveMaxOpenReviews: row.Limit,
+		OpenReviews:             row.OpenReviews,
+		AtCapacity:              row.AtCapacity(),
+	})
+}
+
+// GET /users/capacity?user_id=...
+func (h *AvailabilityHandlers) GetCapacity(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	row, err := h.svc.GetCapacity(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeCapacity(w, row)
+}
+
+// POST /users/capacity (Admin)
+// max_open_reviews = null снимает собственный лимит пользователя
+func (h *AvailabilityHandlers) SetCapacity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID         string `json:"user_id"`
+		MaxOpenReviews *int   `json:"max_open_reviews"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	row, err := h.svc.SetCapacity(r.Context(), req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeCapacity(w, row)
+}
+
+// maxICalSize ограничивает размер импортируемого календаря
+const maxICalSize = 5 << 20
+
+// POST /users/availability/import?source=... (Admin)
+// Тело запроса — файл iCalendar (.ics). source — имя календаря, по умолчанию "ical"
+func (h *AvailabilityHandlers) ImportICal(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxICalSize)
+	report, err := h.svc.ImportICal(r.Context(), r.URL.Query().Get("source"), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.Is(err, ical.ErrMalformed):
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "calendar file is too large")
+		default:
+			writeServiceError(w, err)
+		}
+		return
+	}
+	type skippedDTO struct {
+		UID     string `json:"uid"`
+		Summary string `json:"summary,omitempty"`
+		Reason  string `json:"reason"`
+	}
+	resp := struct {
+		Created   int          `json:"created"`
+		Updated   int          `json:"updated"`
+		Unchanged int          `json:"unchanged"`
+		Skipped   []skippedDTO `json:"skipped"`
+	}{
+		Created:   report.Created,
+		Updated:   report.Updated,
+		Unchanged: report.Unchanged,
+		Skipped:   []skippedDTO{},
+	}
+	for _, sk := range report.Skipped {
+		resp.Skipped = append(resp.Skipped, skippedDTO{UID: sk.UID, Summary: sk.Summary, Reason: sk.Reason})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseBound разбирает границу периода. Для даты без времени конец периода
+// (end = true) сдвигается на начало следующего дня, чтобы день входил целиком
+func parseBound(v string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBound(t *testing.T) {
+	cases := []struct {
+		in   string
+		end  bool
+		want time.Time
+	}{
+		{"2026-03-10", false, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-10", true, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-10T09:30:00Z", true, time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := parseBound(tc.in, tc.end)
+		if err != nil {
+			t.Fatalf("parseBound(%q): %v", tc.in, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Fatalf("parseBound(%q, %v) = %v, want %v", tc.in, tc.end, got, tc.want)
+		}
+	}
+	if _, err := parseBound("10.03.2026", false); err == nil {
+		t.Fatalf("expected error for unsupported format")
+	}
+}
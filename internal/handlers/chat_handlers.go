@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+type ChatHandlers struct {
+	svc *service.ChatService
+}
+
+func NewChatHandlers(svc *service.ChatService) *ChatHandlers {
+	return &ChatHandlers{svc: svc}
+}
+
+// chat_handle пустой, если ник не задан
+type chatHandleDTO struct {
+	UserID     string `json:"user_id"`
+	ChatHandle string `json:"chat_handle"`
+}
+
+func writeChatHandle(w http.ResponseWriter, row repo.ChatHandleRow) {
+	writeJSON(w, http.StatusOK, chatHandleDTO{UserID: row.UserID, ChatHandle: row.Handle})
+}
+
+// GET /users/chatHandle?user_id=...
+func (h *ChatHandlers) GetHandle(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	row, err := h.svc.GetHandle(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeChatHandle(w, row)
+}
+
+// POST /users/chatHandle
+// Пустой chat_handle снимает ник
+func (h *ChatHandlers) SetHandle(w http.ResponseWriter, r *http.Request) {
+	var req chatHandleDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	row, err := h.svc.SetHandle(r.Context(), req.UserID, req.ChatHandle)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeChatHandle(w, row)
+}
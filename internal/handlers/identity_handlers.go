@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+type IdentityHandlers struct {
+	svc *service.IdentityService
+}
+
+func NewIdentityHandlers(svc *service.IdentityService) *IdentityHandlers {
+	return &IdentityHandlers{svc: svc}
+}
+
+type identityDTO struct {
+	Provider   string `json:"provider"`
+	Login      string `json:"login"`
+	UserID     string `json:"user_id"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+func toIdentityDTO(row repo.IdentityRow) identityDTO {
+	return identityDTO{Provider: row.Provider, Login: row.Login, UserID: row.UserID, ExternalID: row.ExternalID}
+}
+
+// GET /users/identities?user_id=...
+// Логины пользователя во внешних системах
+func (h *IdentityHandlers) List(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
+		return
+	}
+	rows, err := h.svc.List(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		UserID     string        `json:"user_id"`
+		Identities []identityDTO `json:"identities"`
+	}{UserID: userID, Identities: []identityDTO{}}
+	for _, row := range rows {
+		resp.Identities = append(resp.Identities, toIdentityDTO(row))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /users/identities (Admin)
+// Привязывает логин внешней системы к пользователю; занятый логин перепривязывается.
+// external_id — постоянный ID пользователя во внешней системе (author_id в GitLab)
+func (h *IdentityHandlers) Link(w http.ResponseWriter, r *http.Request) {
+	var req identityDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Provider == "" || req.Login == "" || req.UserID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "provider, login and user_id are required")
+		return
+	}
+	row, err := h.svc.Link(r.Context(), req.Provider, req.Login, req.UserID, req.ExternalID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		Identity identityDTO `json:"identity"`
+	}{Identity: toIdentityDTO(row)}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DELETE /users/identities?provider=...&login=... (Admin)
+func (h *IdentityHandlers) Unlink(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("provider") == "" || q.Get("login") == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "provider and login are required")
+		return
+	}
+	if err := h.svc.Unlink(r.Context(), q.Get("provider"), q.Get("login")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// LoadSpec разбирает и проверяет встроенную спецификацию OpenAPI
+func LoadSpec() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return doc, nil
+}
+
+// SpecHandler отдаёт спецификацию в JSON (GET /openapi.json)
+func SpecHandler(doc *openapi3.T) (http.Handler, error) {
+	body, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshal openapi spec: %w", err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}), nil
+}
+
+// Validator проверяет запросы по спецификации до вызова хендлера
+type Validator struct {
+	router routers.Router
+}
+
+func NewValidator(doc *openapi3.T) (*Validator, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi router: %w", err)
+	}
+	return &Validator{router: router}, nil
+}
+
+// Validate отвечает 400 BAD_REQUEST со списком ошибок по полям, если параметры
+// или JSON-тело не соответствуют спецификации. Авторизацию проверяет Auth, а тела
+// не в JSON (календарь iCalendar) — сам хендлер
+func (v *Validator) Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			// Маршрута нет в спецификации — расхождение ловит TestRouter_MatchesSpec
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Хендлеры никогда не требовали Content-Type: тело без заголовка считаем JSON
+		jsonBody := hasJSONBody(route.Operation)
+		if jsonBody && r.Header.Get("Content-Type") == "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				ExcludeRequestBody: !jsonBody,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasJSONBody(op *openapi3.Operation) bool {
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		return false
+	}
+	return op.RequestBody.Value.Content.Get("application/json") != nil
+}
+
+// fieldErrorDTO — ошибка в конкретном поле: параметре запроса или пути внутри
+// JSON-тела через точку (members.0.user_id)
+type fieldErrorDTO struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	p := newProblem(http.StatusBadRequest, "BAD_REQUEST", "request does not match the API specification")
+	p.Errors = fieldErrors(err)
+	writeProblem(w, p)
+}
+
+// fieldErrors раскладывает ошибки kin-openapi по полям
+func fieldErrors(err error) []fieldErrorDTO {
+	// errors.As не подходит: RequestError разворачивается во вложенный MultiError ошибок тела
+	if multi, ok := err.(openapi3.MultiError); ok {
+		var out []fieldErrorDTO
+		for _, e := range multi {
+			out = append(out, fieldErrors(e)...)
+		}
+		return out
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return []fieldErrorDTO{{Field: "request", Message: err.Error()}}
+	}
+	if reqErr.Parameter != nil {
+		return []fieldErrorDTO{{Field: reqErr.Parameter.Name, Message: reasonOf(reqErr)}}
+	}
+
+	// Ошибки тела: по одной на каждое поле схемы
+	var out []fieldErrorDTO
+	var bodyMulti openapi3.MultiError
+	if errors.As(reqErr.Err, &bodyMulti) {
+		for _, e := range bodyMulti {
+			out = append(out, schemaFieldError(e))
+		}
+		return out
+	}
+	if reqErr.Err != nil {
+		return []fieldErrorDTO{schemaFieldError(reqErr.Err)}
+	}
+	return []fieldErrorDTO{{Field: "body", Message: reqErr.Reason}}
+}
+
+func schemaFieldError(err error) fieldErrorDTO {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+		if field == "" {
+			field = "body"
+		}
+		return fieldErrorDTO{Field: field, Message: schemaErr.Reason}
+	}
+	return fieldErrorDTO{Field: "body", Message: err.Error()}
+}
+
+// reasonOf возвращает причину ошибки параметра без повторения его имени
+func reasonOf(reqErr *openapi3filter.RequestError) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		return schemaErr.Reason
+	}
+	if reqErr.Err != nil {
+		return reqErr.Err.Error()
+	}
+	return reqErr.Reason
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestRouter собирает роутер без сервисов: до хендлеров запросы в этих тестах не доходят
+func newTestRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+	r, err := NewRouter(RouterConfig{
+		Auth:          NewAuth("adm", "usr", "mtr"),
+		Team:          NewTeamHandlers(nil),
+		Users:         NewUserHandlers(nil),
+		PR:            NewPRHandlers(nil),
+		Availability:  NewAvailabilityHandlers(nil),
+		Identities:    NewIdentityHandlers(nil),
+		Chat:          NewChatHandlers(nil),
+		Webhooks:      NewWebhookHandlers(nil, WebhookSecrets{}),
+		Subscriptions: NewSubscriptionHandlers(nil),
+		Metrics:       http.NotFoundHandler(),
+	})
+	if err != nil {
+		t.Fatalf("router: %v", err)
+	}
+	return r
+}
+
+// TestRouter_MatchesSpec падает, если маршрут добавлен без описания в openapi.yaml или наоборот
+func TestRouter_MatchesSpec(t *testing.T) {
+	doc, err := LoadSpec()
+	if err != nil {
+		t.Fatalf("spec: %v", err)
+	}
+	inSpec := map[string]bool{}
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			inSpec[method+" "+path] = true
+		}
+	}
+	inRouter := map[string]bool{}
+	err = chi.Walk(newTestRouter(t), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		inRouter[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+
+	var drift []string
+	for op := range inRouter {
+		if !inSpec[op] {
+			drift = append(drift, op+": routed but missing from openapi.yaml")
+		}
+	}
+	for op := range inSpec {
+		if !inRouter[op] {
+			drift = append(drift, op+": in openapi.yaml but not routed")
+		}
+	}
+	sort.Strings(drift)
+	if len(drift) > 0 {
+		t.Fatalf("router and spec differ:\n%s", strings.Join(drift, "\n"))
+	}
+}
+
+// TestRouter_RolesMatchSpec сверяет security и x-role операций с тем, что проверяет Auth
+func TestRouter_RolesMatchSpec(t *testing.T) {
+	doc, err := LoadSpec()
+	if err != nil {
+		t.Fatalf("spec: %v", err)
+	}
+	r := newTestRouter(t)
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			public := op.Security != nil && len(*op.Security) == 0
+			role, _ := op.Extensions["x-role"].(string)
+			if public {
+				if role != "" {
+					t.Errorf("%s %s: public operation must not have x-role", method, path)
+				}
+				continue
+			}
+			if role != "user" && role != "admin" {
+				t.Errorf("%s %s: x-role must be user or admin, got %q", method, path, role)
+				continue
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s without token: status %d, want 401", method, path, rec.Code)
+			}
+			if role == "admin" {
+				req := httptest.NewRequest(method, path, nil)
+				req.Header.Set("Authorization", "Bearer usr")
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				if rec.Code != http.StatusForbidden {
+					t.Errorf("%s %s with user token: status %d, want 403", method, path, rec.Code)
+				}
+			}
+		}
+	}
+}
+
+func TestRouter_ServesSpec(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestRouter(t).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var spec struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" || spec.Paths["/pullRequest/create"] == nil {
+		t.Fatalf("unexpected spec: openapi=%q, %d paths", spec.OpenAPI, len(spec.Paths))
+	}
+}
+
+func TestValidator_ReportsFieldErrors(t *testing.T) {
+	r := newTestRouter(t)
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		fields []string
+	}{
+		{
+			name:   "missing and mistyped body fields",
+			method: http.MethodPost,
+			target: "/pullRequest/create",
+			body:   `{"pull_request_id": "", "author_id": 5}`,
+			fields: []string{"author_id", "pull_request_id", "pull_request_name"},
+		},
+		{
+			name:   "enum in body",
+			method: http.MethodPost,
+			target: "/pullRequest/review",
+			body:   `{"pull_request_id": "pr-1", "reviewer_id": "u2", "verdict": "LGTM"}`,
+			fields: []string{"verdict"},
+		},
+		{
+			name:   "nested array item",
+			method: http.MethodPost,
+			target: "/team/add",
+			body:   `{"team_name": "backend", "members": [{"user_id": "u1", "is_active": true}]}`,
+			fields: []string{"members.0.username"},
+		},
+		{
+			name:   "query parameters",
+			method: http.MethodDelete,
+			target: "/users/availability?id=abc",
+			fields: []string{"id", "user_id"},
+		},
+		{
+			name:   "invalid json",
+			method: http.MethodPost,
+			target: "/pullRequest/merge",
+			body:   `{`,
+			fields: []string{"body"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer adm")
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400; body %s", rec.Code, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+				t.Fatalf("content type = %q, want %s", ct, problemContentType)
+			}
+			var resp problem
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Code != "BAD_REQUEST" || resp.Status != http.StatusBadRequest {
+				t.Fatalf("code = %q, status = %d, want BAD_REQUEST 400", resp.Code, resp.Status)
+			}
+			var got []string
+			for _, d := range resp.Errors {
+				if d.Message == "" {
+					t.Fatalf("field %q has empty message", d.Field)
+				}
+				got = append(got, d.Field)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.fields, ",") {
+				t.Fatalf("fields = %v, want %v", got, tc.fields)
+			}
+		})
+	}
+}
+
+func TestValidator_PassesValidRequest(t *testing.T) {
+	doc, err := LoadSpec()
+	if err != nil {
+		t.Fatalf("spec: %v", err)
+	}
+	v, err := NewValidator(doc)
+	if err != nil {
+		t.Fatalf("validator: %v", err)
+	}
+	const body = `{"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1", "unknown": 1}`
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	})
+
+	// Без Content-Type тело считается JSON, неизвестные поля допустимы
+	req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	v.Validate(next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", rec.Code, rec.Body)
+	}
+	if got != body {
+		t.Fatalf("handler must receive the original body, got %q", got)
+	}
+
+	// Тело календаря не разбирается валидатором
+	req = httptest.NewRequest(http.MethodPost, "/users/availability/import", strings.NewReader("BEGIN:VCALENDAR"))
+	req.Header.Set("Content-Type", "text/calendar")
+	rec = httptest.NewRecorder()
+	v.Validate(next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || got != "BEGIN:VCALENDAR" {
+		t.Fatalf("calendar body must reach handler unchanged: status %d, body %q", rec.Code, got)
+	}
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/domain"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
 	"github.com/quasttyy/pr-reviewer/internal/service"
 )
 
@@ -17,179 +20,420 @@ func NewPRHandlers(svc *service.PRService) *PRHandlers {
 	return &PRHandlers{svc: svc}
 }
 
+// reviewerDTO — назначенный ревьювер; verdict = null, пока ревью не отправлено
+type reviewerDTO struct {
+	UserID     string     `json:"user_id"`
+	SourceTeam string     `json:"source_team"`
+	AssignedAt *time.Time `json:"assignedAt,omitempty"`
+	Verdict    *string    `json:"verdict"`
+	VerdictAt  *time.Time `json:"verdictAt,omitempty"`
+}
+
+type prDTO struct {
+	ID                string        `json:"pull_request_id"`
+	Name              string        `json:"pull_request_name"`
+	AuthorID          string        `json:"author_id"`
+	Status            string        `json:"status"`
+	AssignedReviewers []string      `json:"assigned_reviewers"`
+	Reviewers         []reviewerDTO `json:"reviewers"`
+	CreatedAt         *time.Time    `json:"createdAt,omitempty"`
+	MergedAt          *time.Time    `json:"mergedAt,omitempty"`
+	ClosedAt          *time.Time    `json:"closedAt,omitempty"`
+	NeedMoreReviewers bool          `json:"need_more_reviewers"`
+	CapacityExhausted bool          `json:"capacity_exhausted"`
+}
+
+func toPRDTO(pr repo.PRFull) prDTO {
+	dto := prDTO{
+		ID:                pr.ID,
+		Name:              pr.Name,
+		AuthorID:          pr.AuthorID,
+		Status:            pr.Status,
+		AssignedReviewers: pr.Assigned,
+		Reviewers:         make([]reviewerDTO, 0, len(pr.Reviewers)),
+		CreatedAt:         pr.CreatedAt,
+		MergedAt:          pr.MergedAt,
+		ClosedAt:          pr.ClosedAt,
+		NeedMoreReviewers: pr.NeedMoreReviewers,
+		CapacityExhausted: pr.CapacityExhausted,
+	}
+	if dto.AssignedReviewers == nil {
+		dto.AssignedReviewers = []string{}
+	}
+	for _, rv := range pr.Reviewers {
+		dto.Reviewers = append(dto.Reviewers, reviewerDTO{
+			UserID:     rv.ReviewerID,
+			SourceTeam: rv.SourceTeam,
+			AssignedAt: rv.AssignedAt,
+			Verdict:    verdictOrNil(rv.Verdict),
+			VerdictAt:  rv.VerdictAt,
+		})
+	}
+	return dto
+}
+
+// verdictOrNil превращает пустое решение в null для JSON-ответа
+func verdictOrNil(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
 // POST /pullRequest/create
 func (h *PRHandlers) Create(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ID   string `json:"pull_request_id"`
-		Name string `json:"pull_request_name"`
-		Auth string `json:"author_id"`
+		ID    string `json:"pull_request_id"`
+		Name  string `json:"pull_request_name"`
+		Auth  string `json:"author_id"`
+		Draft bool   `json:"draft"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.Name == "" || req.Auth == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "invalid request")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid request")
 		return
 	}
-	pr, err := h.svc.Create(r.Context(), req.ID, req.Name, req.Auth)
-	if err != nil {
-		switch err {
-		case service.ErrPRExists:
-			writeError(w, http.StatusConflict, "PR_EXISTS", "PR id already exists")
-			return
-		case service.ErrNotFoundUser:
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
-			return
-		default:
-			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
-			return
-		}
+	create := h.svc.Create
+	if req.Draft {
+		create = h.svc.CreateDraft
 	}
-	type respPR struct {
-		ID       string   `json:"pull_request_id"`
-		Name     string   `json:"pull_request_name"`
-		AuthorID string   `json:"author_id"`
-		Status   string   `json:"status"`
-		Reviewers []string `json:"assigned_reviewers"`
-		CreatedAt *time.Time `json:"createdAt,omitempty"`
-		MergedAt  *time.Time `json:"mergedAt,omitempty"`
-		NeedMoreReviewers bool `json:"need_more_reviewers"`
+	pr, err := create(r.Context(), req.ID, req.Name, req.Auth)
+	if err != nil {
+		writeServiceError(w, err)
+		return
 	}
 	resp := struct {
-		PR respPR `json:"pr"`
+		PR prDTO `json:"pr"`
 	}{}
-	resp.PR = respPR{
-		ID:       pr.ID,
-		Name:     pr.Name,
-		AuthorID: pr.AuthorID,
-		Status:   pr.Status,
-		Reviewers: pr.Assigned,
-		CreatedAt: pr.CreatedAt,
-		MergedAt:  pr.MergedAt,
-		NeedMoreReviewers: pr.NeedMoreReviewers,
-	}
+	resp.PR = toPRDTO(pr)
 	writeJSON(w, http.StatusCreated, resp)
 }
 
 // POST /pullRequest/merge
+// Заголовок X-Force-Merge: true (только с токеном администратора) пропускает
+// проверку политики merge
 func (h *PRHandlers) Merge(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ID string `json:"pull_request_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "pull_request_id is required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
 		return
 	}
-	pr, err := h.svc.Merge(r.Context(), req.ID)
-	if err != nil {
-		if err == pgx.ErrNoRows || err == service.ErrNotFoundPR {
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+	force := strings.EqualFold(r.Header.Get("X-Force-Merge"), "true")
+	if force {
+		if role, _ := RoleFromContext(r.Context()); role != RoleAdmin {
+			writeError(w, http.StatusForbidden, "FORBIDDEN", "admin token required for forced merge")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
-		return
 	}
-	type respPR struct {
-		ID       string   `json:"pull_request_id"`
-		Name     string   `json:"pull_request_name"`
-		AuthorID string   `json:"author_id"`
-		Status   string   `json:"status"`
-		Reviewers []string `json:"assigned_reviewers"`
-		CreatedAt *time.Time `json:"createdAt,omitempty"`
-		MergedAt  *time.Time `json:"mergedAt,omitempty"`
-		NeedMoreReviewers bool `json:"need_more_reviewers"`
+	merge := h.svc.Merge
+	if force {
+		merge = h.svc.ForceMerge
+	}
+	pr, err := merge(r.Context(), req.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
 	}
 	resp := struct {
-		PR respPR `json:"pr"`
+		PR prDTO `json:"pr"`
 	}{}
-	resp.PR = respPR{
-		ID:       pr.ID,
-		Name:     pr.Name,
-		AuthorID: pr.AuthorID,
-		Status:   pr.Status,
-		Reviewers: pr.Assigned,
-		CreatedAt: pr.CreatedAt,
-		MergedAt:  pr.MergedAt,
-		NeedMoreReviewers: pr.NeedMoreReviewers,
+	resp.PR = toPRDTO(pr)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /pullRequest/ready
+// Переводит DRAFT PR в OPEN и назначает ревьюверов
+func (h *PRHandlers) Ready(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.svc.Ready)
+}
+
+// POST /pullRequest/close
+func (h *PRHandlers) Close(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.svc.Close)
+}
+
+// POST /pullRequest/reopen
+func (h *PRHandlers) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.svc.Reopen)
+}
+
+// transition обрабатывает запрос {pull_request_id} на смену статуса PR
+func (h *PRHandlers) transition(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, prID string) (repo.PRFull, error)) {
+	var req struct {
+		ID string `json:"pull_request_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+	pr, err := apply(r.Context(), req.ID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
 	}
+	resp := struct {
+		PR prDTO `json:"pr"`
+	}{}
+	resp.PR = toPRDTO(pr)
 	writeJSON(w, http.StatusOK, resp)
 }
 
 // POST /pullRequest/reassign
 func (h *PRHandlers) Reassign(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ID  string `json:"pull_request_id"`
-		Old string `json:"old_user_id"`
+		ID     string `json:"pull_request_id"`
+		Old    string `json:"old_user_id"`
+		Reason string `json:"reason"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.Old == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "pull_request_id and old_user_id are required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id and old_user_id are required")
 		return
 	}
-	pr, replacedBy, err := h.svc.Reassign(r.Context(), req.ID, req.Old)
+	pr, replacedBy, err := h.svc.Reassign(r.Context(), req.ID, req.Old, req.Reason)
 	if err != nil {
-		switch err {
-		case service.ErrPRMerged:
-			writeError(w, http.StatusConflict, "PR_MERGED", "cannot reassign on merged PR")
-			return
-		case service.ErrNotAssigned:
-			writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
-			return
-		case service.ErrNoCandidate:
-			writeError(w, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		PR         prDTO  `json:"pr"`
+		ReplacedBy string `json:"replaced_by"`
+	}{}
+	resp.PR = toPRDTO(pr)
+	resp.ReplacedBy = replacedBy
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /pullRequest/review
+// Назначенный ревьювер отправляет решение: APPROVED, CHANGES_REQUESTED или COMMENTED
+func (h *PRHandlers) Review(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID         string `json:"pull_request_id"`
+		ReviewerID string `json:"reviewer_id"`
+		Verdict    string `json:"verdict"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" || req.ReviewerID == "" || req.Verdict == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id, reviewer_id and verdict are required")
+		return
+	}
+	pr, err := h.svc.SubmitReview(r.Context(), req.ID, req.ReviewerID, domain.ReviewVerdict(req.Verdict))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		PR prDTO `json:"pr"`
+	}{}
+	resp.PR = toPRDTO(pr)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /pullRequest/topUp (Admin)
+// Добирает ревьюверов на один PR (если передан pull_request_id) или на все
+// недоукомплектованные OPEN PR. Возвращает изменённые PR и ошибки добора
+// отдельных PR: изменения остальных PR при этом уже зафиксированы
+func (h *PRHandlers) TopUp(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"pull_request_id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
 			return
-		case service.ErrNotFoundPR, pgx.ErrNoRows:
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
+		}
+	}
+	var results []service.TopUpResult
+	var failed []service.TopUpFailure
+	if req.ID != "" {
+		res, err := h.svc.TopUp(r.Context(), req.ID)
+		if err != nil {
+			writeServiceError(w, err)
 			return
-		default:
-			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		}
+		if res.Changed {
+			results = append(results, res)
+		}
+	} else {
+		var err error
+		results, failed, err = h.svc.TopUpAll(r.Context())
+		if err != nil {
+			writeServiceError(w, err)
 			return
 		}
 	}
-	type respPR struct {
-		ID       string   `json:"pull_request_id"`
-		Name     string   `json:"pull_request_name"`
-		AuthorID string   `json:"author_id"`
-		Status   string   `json:"status"`
-		Reviewers []string `json:"assigned_reviewers"`
-		CreatedAt *time.Time `json:"createdAt,omitempty"`
-		MergedAt  *time.Time `json:"mergedAt,omitempty"`
-		NeedMoreReviewers bool `json:"need_more_reviewers"`
+	type itemDTO struct {
+		PR    prDTO    `json:"pr"`
+		Added []string `json:"added"`
+	}
+	type failureDTO struct {
+		ID      string `json:"pull_request_id"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
 	}
 	resp := struct {
-		PR         respPR `json:"pr"`
-		ReplacedBy string `json:"replaced_by"`
-	}{}
-	resp.PR = respPR{
-		ID:       pr.ID,
-		Name:     pr.Name,
-		AuthorID: pr.AuthorID,
-		Status:   pr.Status,
-		Reviewers: pr.Assigned,
-		CreatedAt: pr.CreatedAt,
-		MergedAt:  pr.MergedAt,
-		NeedMoreReviewers: pr.NeedMoreReviewers,
+		Items  []itemDTO    `json:"items"`
+		Errors []failureDTO `json:"errors"`
+	}{Items: []itemDTO{}, Errors: []failureDTO{}}
+	for _, res := range results {
+		added := res.Added
+		if added == nil {
+			added = []string{}
+		}
+		resp.Items = append(resp.Items, itemDTO{PR: toPRDTO(res.PR), Added: added})
+	}
+	for _, f := range failed {
+		p := serviceProblem(f.Err)
+		msg := p.Title
+		if p.Detail != "" {
+			msg = p.Detail
+		}
+		resp.Errors = append(resp.Errors, failureDTO{ID: f.PullRequestID, Code: p.Code, Message: msg})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GET /pullRequest/timeline?pull_request_id=...
+func (h *PRHandlers) Timeline(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "pull_request_id is required")
+		return
+	}
+	events, err := h.svc.Timeline(r.Context(), prID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	type eventDTO struct {
+		EventID    int64     `json:"event_id"`
+		Type       string    `json:"type"`
+		Actor      string    `json:"actor"`
+		UserID     string    `json:"user_id,omitempty"`
+		FromUserID string    `json:"from_user_id,omitempty"`
+		ToUserID   string    `json:"to_user_id,omitempty"`
+		Reason     string    `json:"reason,omitempty"`
+		Verdict    string    `json:"verdict,omitempty"`
+		CreatedAt  time.Time `json:"createdAt"`
+	}
+	resp := struct {
+		PullRequestID string     `json:"pull_request_id"`
+		Events        []eventDTO `json:"events"`
+	}{
+		PullRequestID: prID,
+		Events:        []eventDTO{},
+	}
+	for _, ev := range events {
+		resp.Events = append(resp.Events, eventDTO{
+			EventID:    ev.ID,
+			Type:       ev.Type,
+			Actor:      ev.Actor,
+			UserID:     ev.UserID,
+			FromUserID: ev.FromUserID,
+			ToUserID:   ev.ToUserID,
+			Reason:     ev.Reason,
+			Verdict:    ev.Verdict,
+			CreatedAt:  ev.CreatedAt,
+		})
 	}
-	resp.ReplacedBy = replacedBy
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// GET /pullRequest/stats
-// Простая статистика: сколько PR назначено на каждого ревьювера.
+// GET /pullRequest/stats?from=...&to=...&team_name=...&status=...&group_by=reviewer|team|week
+// Статистика назначений ревьюверов за период: всего, открытые, смерженные, снятые
+// при переназначении и медиана времени от назначения до merge. from/to — RFC 3339
+// или дата YYYY-MM-DD (дата в to входит в период целиком). При группировке по
+// ревьюверам добавляются текущие лимит и загрузка. capacity_exhausted — OPEN PR,
+// упёршиеся в лимиты ревьюверов, без учёта фильтров.
 func (h *PRHandlers) GetReviewerAssignments(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.svc.GetReviewerStats(r.Context())
+	q := r.URL.Query()
+	filter := repo.StatsFilter{
+		TeamName: q.Get("team_name"),
+		Status:   q.Get("status"),
+		GroupBy:  q.Get("group_by"),
+	}
+	if v := q.Get("from"); v != "" {
+		from, err := parseBound(v, false)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "from must be RFC 3339 or YYYY-MM-DD")
+			return
+		}
+		filter.From = &from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := parseBound(v, true)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "to must be RFC 3339 or YYYY-MM-DD")
+			return
+		}
+		filter.To = &to
+	}
+	stats, err := h.svc.GetReviewerStats(r.Context(), filter)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	exhausted, err := h.svc.CapacityExhausted(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		writeServiceError(w, err)
 		return
 	}
+	// Ключ группы — в reviewer_id, team_name или week. max_open_reviews отсутствует,
+	// если лимита нет; median_time_to_merge_seconds = null, если смерженных PR нет
 	type statDTO struct {
-		ReviewerID    string `json:"reviewer_id"`
-		TotalAssigned int64  `json:"total_assigned"`
+		ReviewerID        string `json:"reviewer_id,omitempty"`
+		TeamName          string `json:"team_name,omitempty"`
+		Week              string `json:"week,omitempty"`
+		TotalAssigned     int64  `json:"total_assigned"`
+		OpenReviews       int64  `json:"open_reviews"`
+		Merged            int64  `json:"merged"`
+		ReassignedAway    int64  `json:"reassigned_away"`
+		MedianTimeToMerge *int64 `json:"median_time_to_merge_seconds"`
+		MaxOpenReviews    *int   `json:"max_open_reviews,omitempty"`
+		AtCapacity        *bool  `json:"at_capacity,omitempty"`
+	}
+	groupBy := filter.GroupBy
+	if groupBy == "" {
+		groupBy = repo.StatsGroupByReviewer
 	}
 	resp := struct {
-		Items []statDTO `json:"items"`
-	}{}
+		GroupBy           string     `json:"group_by"`
+		From              *time.Time `json:"from,omitempty"`
+		To                *time.Time `json:"to,omitempty"`
+		Items             []statDTO  `json:"items"`
+		CapacityExhausted []string   `json:"capacity_exhausted"`
+	}{
+		GroupBy:           groupBy,
+		From:              filter.From,
+		To:                filter.To,
+		Items:             []statDTO{},
+		CapacityExhausted: []string{},
+	}
 	for _, s := range stats {
-		resp.Items = append(resp.Items, statDTO{
-			ReviewerID:    s.ReviewerID,
-			TotalAssigned: s.TotalAssigned,
-		})
+		dto := statDTO{
+			TotalAssigned:  s.TotalAssigned,
+			OpenReviews:    s.OpenReviews,
+			Merged:         s.Merged,
+			ReassignedAway: s.ReassignedAway,
+		}
+		switch groupBy {
+		case repo.StatsGroupByTeam:
+			dto.TeamName = s.Key
+		case repo.StatsGroupByWeek:
+			dto.Week = s.Key
+		default:
+			dto.ReviewerID = s.Key
+		}
+		if s.MedianTimeToMerge != nil {
+			secs := int64(s.MedianTimeToMerge.Seconds())
+			dto.MedianTimeToMerge = &secs
+		}
+		if s.Capacity != nil {
+			atCapacity := s.Capacity.AtCapacity()
+			dto.MaxOpenReviews = s.Capacity.Limit
+			dto.AtCapacity = &atCapacity
+		}
+		resp.Items = append(resp.Items, dto)
 	}
+	resp.CapacityExhausted = append(resp.CapacityExhausted, exhausted...)
 	writeJSON(w, http.StatusOK, resp)
 }
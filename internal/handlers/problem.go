@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/quasttyy/pr-reviewer/internal/service"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// problemContentType — тип ответа с ошибкой по RFC 7807
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix — префикс URI типа проблемы, за ним следует код ошибки
+const problemTypePrefix = "urn:pr-reviewer:problem:"
+
+// problem — ошибка в формате RFC 7807. code — стабильный код (PR_EXISTS, NOT_FOUND...),
+// errors и conditions — расширения для ошибок проверки запроса и политики merge
+type problem struct {
+	Type       string          `json:"type"`
+	Title      string          `json:"title"`
+	Status     int             `json:"status"`
+	Detail     string          `json:"detail,omitempty"`
+	Code       string          `json:"code"`
+	Errors     []fieldErrorDTO `json:"errors,omitempty"`
+	Conditions []conditionDTO  `json:"conditions,omitempty"`
+}
+
+// conditionDTO — невыполненное условие политики merge
+type conditionDTO struct {
+	Condition string `json:"condition"`
+	Message   string `json:"message"`
+}
+
+func newProblem(status int, code, title string) problem {
+	return problem{Type: problemTypePrefix + code, Title: title, Status: status, Code: code}
+}
+
+func writeProblem(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// writeError отвечает ошибкой, которой нет в каталоге сервиса: авторизация, разбор запроса
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeProblem(w, newProblem(status, code, message))
+}
+
+// kindStatus — HTTP-статус для класса ошибки сервиса
+var kindStatus = map[service.Kind]int{
+	service.KindInvalid:  http.StatusBadRequest,
+	service.KindNotFound: http.StatusNotFound,
+	service.KindConflict: http.StatusConflict,
+}
+
+// writeServiceError отвечает ошибкой, которую вернул сервис. Для ошибок каталога
+// статус определяется классом, title — описание из каталога, detail — контекст
+// обёртки, если он есть. Остальные ошибки логируются и отдаются как 500 INTERNAL
+func writeServiceError(w http.ResponseWriter, err error) {
+	writeProblem(w, serviceProblem(err))
+}
+
+// serviceProblem строит ответ writeServiceError, не записывая его
+func serviceProblem(err error) problem {
+	var svcErr *service.Error
+	status, ok := 0, errors.As(err, &svcErr)
+	if ok {
+		status, ok = kindStatus[svcErr.Kind]
+	}
+	if !ok {
+		logger.Error("request failed", "error", err)
+		return newProblem(http.StatusInternalServerError, "INTERNAL", "internal error")
+	}
+
+	p := newProblem(status, svcErr.Code, svcErr.Message)
+	if msg := err.Error(); msg != svcErr.Message {
+		p.Detail = msg
+	}
+	var blocked *service.MergeBlockedError
+	if errors.As(err, &blocked) {
+		for _, c := range blocked.Unmet {
+			p.Conditions = append(p.Conditions, conditionDTO{Condition: c.Code, Message: c.Message})
+		}
+	}
+	return p
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quasttyy/pr-reviewer/internal/service"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+func TestWriteServiceError(t *testing.T) {
+	logger.Init("test")
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"conflict", service.ErrTeamExists, http.StatusConflict, "TEAM_EXISTS", ""},
+		{"not found", service.ErrNotFoundPR, http.StatusNotFound, "NOT_FOUND", ""},
+		{"invalid", service.ErrInvalidVerdict, http.StatusBadRequest, "INVALID_VERDICT", ""},
+		{
+			name:   "wrapped keeps detail",
+			err:    fmt.Errorf("%w: MERGED -> CLOSED", service.ErrInvalidTransition),
+			status: http.StatusConflict,
+			code:   "INVALID_TRANSITION",
+			detail: "invalid PR status transition: MERGED -> CLOSED",
+		},
+		{"refined error keeps its own code", service.ErrCapacityExhausted, http.StatusConflict, "CAPACITY_EXHAUSTED", ""},
+		{"unknown error hides details", errors.New("connection refused"), http.StatusInternalServerError, "INTERNAL", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeServiceError(rec, tc.err)
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+				t.Fatalf("content type = %q, want %s", ct, problemContentType)
+			}
+			var p problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if p.Code != tc.code || p.Status != tc.status || p.Type != problemTypePrefix+tc.code {
+				t.Fatalf("problem = %+v, want code %s", p, tc.code)
+			}
+			if p.Detail != tc.detail {
+				t.Fatalf("detail = %q, want %q", p.Detail, tc.detail)
+			}
+		})
+	}
+}
+
+func TestWriteServiceError_MergeBlocked(t *testing.T) {
+	err := &service.MergeBlockedError{Unmet: []service.UnmetCondition{
+		{Code: "required_approvals", Message: "need 2 approvals, have 1"},
+	}}
+	rec := httptest.NewRecorder()
+	writeServiceError(rec, err)
+	var p problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Code != http.StatusConflict || p.Code != "MERGE_BLOCKED" {
+		t.Fatalf("status %d code %q, want 409 MERGE_BLOCKED", rec.Code, p.Code)
+	}
+	if len(p.Conditions) != 1 || p.Conditions[0].Condition != "required_approvals" {
+		t.Fatalf("conditions = %+v", p.Conditions)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouterConfig — хендлеры и middleware, из которых собирается HTTP API
+type RouterConfig struct {
+	Auth          *Auth
+	Team          *TeamHandlers
+	Users         *UserHandlers
+	PR            *PRHandlers
+	Availability  *AvailabilityHandlers
+	Identities    *IdentityHandlers
+	Chat          *ChatHandlers
+	Webhooks      *WebhookHandlers
+	Subscriptions *SubscriptionHandlers
+	// Metrics отдаёт метрики Prometheus на /metrics (токен сбора метрик или администратора)
+	Metrics http.Handler
+	// Middlewares применяются ко всем запросам до маршрутизации (трассировка, метрики)
+	Middlewares []func(http.Handler) http.Handler
+}
+
+// NewRouter собирает маршруты API. Каждый маршрут описан в openapi.yaml
+// (проверяет TestRouter_MatchesSpec), запросы проверяются по спецификации после авторизации
+func NewRouter(cfg RouterConfig) (*chi.Mux, error) {
+	doc, err := LoadSpec()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := SpecHandler(doc)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := NewValidator(doc)
+	if err != nil {
+		return nil, err
+	}
+	user := chi.Middlewares{cfg.Auth.RequireUser, validator.Validate}
+	admin := chi.Middlewares{cfg.Auth.RequireAdmin, validator.Validate}
+
+	r := chi.NewRouter()
+	r.Use(cfg.Middlewares...)
+
+	// Health
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	// Метрики Prometheus раскрывают нагрузку команд и ревьюверов, поэтому доступны
+	// только с токеном сбора метрик или администратора. Спецификация API публична
+	r.With(cfg.Auth.RequireMetrics).Method(http.MethodGet, "/metrics", cfg.Metrics)
+	r.Method(http.MethodGet, "/openapi.json", spec)
+
+	// Team
+	r.Route("/team", func(rt chi.Router) {
+		rt.With(admin...).Post("/add", cfg.Team.AddTeam)
+		rt.With(user...).Get("/get", cfg.Team.GetTeam)
+		rt.With(user...).Get("/settings", cfg.Team.GetSettings)
+		rt.With(admin...).Post("/settings", cfg.Team.UpdateSettings)
+	})
+
+	// Users
+	r.Route("/users", func(ru chi.Router) {
+		ru.With(admin...).Post("/setIsActive", cfg.Users.SetIsActive)
+		ru.With(user...).Get("/getReview", cfg.Users.GetReview)
+		ru.With(user...).Get("/availability", cfg.Availability.List)
+		ru.With(user...).Post("/availability", cfg.Availability.Add)
+		ru.With(user...).Delete("/availability", cfg.Availability.Delete)
+		ru.With(admin...).Post("/availability/import", cfg.Availability.ImportICal)
+		ru.With(user...).Get("/workingHours", cfg.Availability.GetWorkingHours)
+		ru.With(user...).Post("/workingHours", cfg.Availability.SetWorkingHours)
+		ru.With(user...).Get("/capacity", cfg.Availability.GetCapacity)
+		ru.With(admin...).Post("/capacity", cfg.Availability.SetCapacity)
+		ru.With(user...).Get("/chatHandle", cfg.Chat.GetHandle)
+		ru.With(user...).Post("/chatHandle", cfg.Chat.SetHandle)
+		ru.With(user...).Get("/identities", cfg.Identities.List)
+		ru.With(admin...).Post("/identities", cfg.Identities.Link)
+		ru.With(admin...).Delete("/identities", cfg.Identities.Unlink)
+	})
+
+	// Pull Requests
+	r.Route("/pullRequest", func(rp chi.Router) {
+		rp.With(user...).Post("/create", cfg.PR.Create)
+		rp.With(user...).Post("/merge", cfg.PR.Merge)
+		rp.With(user...).Post("/ready", cfg.PR.Ready)
+		rp.With(user...).Post("/close", cfg.PR.Close)
+		rp.With(user...).Post("/reopen", cfg.PR.Reopen)
+		rp.With(admin...).Post("/reassign", cfg.PR.Reassign)
+		rp.With(user...).Post("/review", cfg.PR.Review)
+		rp.With(admin...).Post("/topUp", cfg.PR.TopUp)
+		rp.With(user...).Get("/stats", cfg.PR.GetReviewerAssignments)
+		rp.With(user...).Get("/timeline", cfg.PR.Timeline)
+	})
+
+	// Вебхуки внешних систем: вместо токена проверяется подпись провайдера.
+	// Подписки на исходящие вебхуки и журнал доставок — для администратора
+	r.Route("/webhooks", func(rw chi.Router) {
+		rw.Post("/github", cfg.Webhooks.GitHub)
+		rw.Post("/gitlab", cfg.Webhooks.GitLab)
+		rw.With(admin...).Get("/subscriptions", cfg.Subscriptions.List)
+		rw.With(admin...).Post("/subscriptions", cfg.Subscriptions.Create)
+		rw.With(admin...).Delete("/subscriptions", cfg.Subscriptions.Delete)
+		rw.With(admin...).Get("/deliveries", cfg.Subscriptions.Deliveries)
+	})
+
+	return r, nil
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+type SubscriptionHandlers struct {
+	svc *service.SubscriptionService
+}
+
+func NewSubscriptionHandlers(svc *service.SubscriptionService) *SubscriptionHandlers {
+	return &SubscriptionHandlers{svc: svc}
+}
+
+// subscriptionDTO — подписка в ответах API. Секрет не возвращается
+type subscriptionDTO struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toSubscriptionDTO(row repo.SubscriptionRow) subscriptionDTO {
+	return subscriptionDTO{ID: row.ID, URL: row.URL, Events: row.Events, CreatedAt: row.CreatedAt}
+}
+
+type deliveryDTO struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func toDeliveryDTO(row repo.DeliveryRow) deliveryDTO {
+	dto := deliveryDTO{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Status:         row.Status,
+		Attempts:       row.Attempts,
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		CreatedAt:      row.CreatedAt,
+		DeliveredAt:    row.DeliveredAt,
+		Payload:        row.Payload,
+	}
+	// Время следующей попытки имеет смысл только для ещё не доставленных
+	if row.Status == repo.DeliveryPending {
+		dto.NextAttemptAt = &row.NextAttemptAt
+	}
+	return dto
+}
+
+// GET /webhooks/subscriptions (Admin)
+func (h *SubscriptionHandlers) List(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.svc.List(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		Subscriptions []subscriptionDTO `json:"subscriptions"`
+	}{Subscriptions: []subscriptionDTO{}}
+	for _, row := range rows {
+		resp.Subscriptions = append(resp.Subscriptions, toSubscriptionDTO(row))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /webhooks/subscriptions (Admin)
+// Подписывает URL на события; тело каждого события подписывается секретом
+func (h *SubscriptionHandlers) Create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" || req.Secret == "" || len(req.Events) == 0 {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "url, secret and events are required")
+		return
+	}
+	row, err := h.svc.Create(r.Context(), req.URL, req.Secret, req.Events)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		Subscription subscriptionDTO `json:"subscription"`
+	}{Subscription: toSubscriptionDTO(row)}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// DELETE /webhooks/subscriptions?id=... (Admin)
+func (h *SubscriptionHandlers) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "numeric id is required")
+		return
+	}
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /webhooks/deliveries?subscription_id=...&status=...&limit=... (Admin)
+// Журнал доставок, новые первыми
+func (h *SubscriptionHandlers) Deliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := repo.DeliveryFilter{Status: q.Get("status")}
+	if v := q.Get("subscription_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "subscription_id must be numeric")
+			return
+		}
+		f.SubscriptionID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "limit must be numeric")
+			return
+		}
+		f.Limit = limit
+	}
+	rows, err := h.svc.Deliveries(r.Context(), f)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	resp := struct {
+		Deliveries []deliveryDTO `json:"deliveries"`
+	}{Deliveries: []deliveryDTO{}}
+	for _, row := range rows {
+		resp.Deliveries = append(resp.Deliveries, toDeliveryDTO(row))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
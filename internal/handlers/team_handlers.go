@@ -28,17 +28,17 @@ func (h *TeamHandlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 		Members  []memberDTO `json:"members"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "invalid json")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid json")
 		return
 	}
 	if req.TeamName == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "team_name is required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
 		return
 	}
 	members := make([]domain.TeamMember, 0, len(req.Members))
 	for _, m := range req.Members {
 		if m.UserID == "" || m.Username == "" {
-			writeError(w, http.StatusBadRequest, "NOT_FOUND", "member.user_id and username are required")
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "member.user_id and username are required")
 			return
 		}
 		members = append(members, domain.TeamMember{
@@ -52,11 +52,7 @@ func (h *TeamHandlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 		Members: members,
 	})
 	if err != nil {
-		if err == service.ErrTeamExists {
-			writeError(w, http.StatusBadRequest, "TEAM_EXISTS", "team_name already exists")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		writeServiceError(w, err)
 		return
 	}
 
@@ -72,6 +68,7 @@ func (h *TeamHandlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 		} `json:"team"`
 	}{}
 	resp.Team.TeamName = req.TeamName
+	resp.Team.Members = []memberResp{}
 	for _, m := range members {
 		resp.Team.Members = append(resp.Team.Members, memberResp{
 			UserID:   m.ID,
@@ -86,16 +83,12 @@ func (h *TeamHandlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 func (h *TeamHandlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	teamName := r.URL.Query().Get("team_name")
 	if teamName == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "team_name is required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
 		return
 	}
 	team, err := h.svc.GetTeam(r.Context(), teamName)
 	if err != nil {
-		if err == service.ErrNotFound {
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		writeServiceError(w, err)
 		return
 	}
 	type memberResp struct {
@@ -108,6 +101,7 @@ func (h *TeamHandlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 		Members  []memberResp `json:"members"`
 	}{
 		TeamName: team.Name,
+		Members:  []memberResp{},
 	}
 	for _, m := range team.Members {
 		resp.Members = append(resp.Members, memberResp{
@@ -119,21 +113,67 @@ func (h *TeamHandlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// default_max_open_reviews = null — у участников команды нет лимита по умолчанию
+type teamSettingsDTO struct {
+	TeamName              string   `json:"team_name"`
+	MinReviewers          int      `json:"min_reviewers"`
+	MaxReviewers          int      `json:"max_reviewers"`
+	FallbackTeams         []string `json:"fallback_teams"`
+	DefaultMaxOpenReviews *int     `json:"default_max_open_reviews"`
+}
+
+func toTeamSettingsDTO(s domain.TeamSettings) teamSettingsDTO {
+	dto := teamSettingsDTO{
+		TeamName:              s.TeamName,
+		MinReviewers:          s.MinReviewers,
+		MaxReviewers:          s.MaxReviewers,
+		FallbackTeams:         s.FallbackTeams,
+		DefaultMaxOpenReviews: s.DefaultMaxOpenReviews,
+	}
+	if dto.FallbackTeams == nil {
+		dto.FallbackTeams = []string{}
+	}
+	return dto
+}
+
+// GET /team/settings?team_name=...
+func (h *TeamHandlers) GetSettings(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name is required")
+		return
+	}
+	settings, err := h.svc.GetSettings(r.Context(), teamName)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTeamSettingsDTO(settings))
+}
+
+// POST /team/settings (Admin)
+func (h *TeamHandlers) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req teamSettingsDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TeamName == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "team_name, min_reviewers and max_reviewers are required")
+		return
+	}
+	settings, err := h.svc.UpdateSettings(r.Context(), domain.TeamSettings{
+		TeamName:              req.TeamName,
+		MinReviewers:          req.MinReviewers,
+		MaxReviewers:          req.MaxReviewers,
+		FallbackTeams:         req.FallbackTeams,
+		DefaultMaxOpenReviews: req.DefaultMaxOpenReviews,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toTeamSettingsDTO(settings))
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
-
-func writeError(w http.ResponseWriter, status int, code, message string) {
-	type errBody struct {
-		Error struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-	var body errBody
-	body.Error.Code = code
-	body.Error.Message = message
-	writeJSON(w, status, body)
-}
\ No newline at end of file
@@ -3,8 +3,8 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/quasttyy/pr-reviewer/internal/service"
 )
 
@@ -23,18 +23,19 @@ func (h *UserHandlers) SetIsActive(w http.ResponseWriter, r *http.Request) {
 		IsActive bool   `json:"is_active"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "user_id and is_active are required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id and is_active are required")
 		return
 	}
-	row, err := h.svc.SetIsActiveAdmin(r.Context(), req.UserID, req.IsActive)
+	row, report, err := h.svc.SetIsActiveAdmin(r.Context(), req.UserID, req.IsActive)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		writeServiceError(w, err)
 		return
 	}
+	type reassignedDTO struct {
+		PullRequestID string `json:"pull_request_id"`
+		OldUserID     string `json:"old_user_id"`
+		ReplacedBy    string `json:"replaced_by"`
+	}
 	resp := struct {
 		User struct {
 			UserID   string `json:"user_id"`
@@ -42,11 +43,24 @@ func (h *UserHandlers) SetIsActive(w http.ResponseWriter, r *http.Request) {
 			TeamName string `json:"team_name"`
 			IsActive bool   `json:"is_active"`
 		} `json:"user"`
-	}{}
+		Reassigned []reassignedDTO `json:"reassigned"`
+		Uncovered  []string        `json:"uncovered"`
+	}{
+		Reassigned: []reassignedDTO{},
+		Uncovered:  []string{},
+	}
 	resp.User.UserID = row.UserID
 	resp.User.Username = row.Username
 	resp.User.TeamName = row.TeamName
 	resp.User.IsActive = row.IsActive
+	for _, ra := range report.Reassigned {
+		resp.Reassigned = append(resp.Reassigned, reassignedDTO{
+			PullRequestID: ra.PullRequestID,
+			OldUserID:     ra.OldReviewerID,
+			ReplacedBy:    ra.NewReviewerID,
+		})
+	}
+	resp.Uncovered = append(resp.Uncovered, report.Uncovered...)
 	writeJSON(w, http.StatusOK, resp)
 }
 
@@ -54,29 +68,29 @@ func (h *UserHandlers) SetIsActive(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandlers) GetReview(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		writeError(w, http.StatusBadRequest, "NOT_FOUND", "user_id is required")
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "user_id is required")
 		return
 	}
 	rows, err := h.svc.GetUserReviews(r.Context(), userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "NOT_FOUND", "resource not found")
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		writeServiceError(w, err)
 		return
 	}
+	// verdict = null — ревью по PR ещё не отправлено
 	type prShort struct {
-		PullRequestID   string `json:"pull_request_id"`
-		PullRequestName string `json:"pull_request_name"`
-		AuthorID        string `json:"author_id"`
-		Status          string `json:"status"`
+		PullRequestID   string     `json:"pull_request_id"`
+		PullRequestName string     `json:"pull_request_name"`
+		AuthorID        string     `json:"author_id"`
+		Status          string     `json:"status"`
+		Verdict         *string    `json:"verdict"`
+		VerdictAt       *time.Time `json:"verdictAt,omitempty"`
 	}
 	resp := struct {
 		UserID       string    `json:"user_id"`
 		PullRequests []prShort `json:"pull_requests"`
 	}{
-		UserID: userID,
+		UserID:       userID,
+		PullRequests: []prShort{},
 	}
 	for _, p := range rows {
 		resp.PullRequests = append(resp.PullRequests, prShort{
@@ -84,6 +98,8 @@ func (h *UserHandlers) GetReview(w http.ResponseWriter, r *http.Request) {
 			PullRequestName: p.Name,
 			AuthorID:        p.AuthorID,
 			Status:          p.Status,
+			Verdict:         verdictOrNil(p.Verdict),
+			VerdictAt:       p.VerdictAt,
 		})
 	}
 	writeJSON(w, http.StatusOK, resp)
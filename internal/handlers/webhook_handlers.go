@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/quasttyy/pr-reviewer/internal/service"
+)
+
+// maxWebhookSize ограничивает размер тела вебхука
+const maxWebhookSize = 5 << 20
+
+// WebhookSecrets — секреты, которыми подписаны вебхуки. Пустой секрет
+// отключает приём вебхуков провайдера: все запросы отклоняются
+type WebhookSecrets struct {
+	GitHub string
+	GitLab string
+}
+
+type WebhookHandlers struct {
+	svc     *service.WebhookService
+	secrets WebhookSecrets
+}
+
+func NewWebhookHandlers(svc *service.WebhookService, secrets WebhookSecrets) *WebhookHandlers {
+	return &WebhookHandlers{svc: svc, secrets: secrets}
+}
+
+// POST /webhooks/github
+// Принимает события pull_request: opened, ready_for_review, converted_to_draft,
+// closed (с merge и без), reopened. Подпись X-Hub-Signature-256 проверяется секретом config.Webhooks.GitHubSecret.
+// Остальные события и действия подтверждаются без изменений (result = ignored)
+func (h *WebhookHandlers) GitHub(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "webhook payload is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "cannot read request body")
+		return
+	}
+	if !validGitHubSignature(h.secrets.GitHub, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "X-Hub-Signature-256 does not match the payload")
+		return
+	}
+	ev, ok, err := parseGitHubEvent(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	if !ok {
+		writeWebhookResult(w, string(service.WebhookIgnored), nil)
+		return
+	}
+	h.apply(w, r, ev)
+}
+
+// POST /webhooks/gitlab
+// Принимает Merge Request Hook: open, merge, close, reopen и update со сменой
+// признака draft. Токен X-Gitlab-Token сверяется с config.Webhooks.GitLabToken.
+// Остальные события и действия подтверждаются без изменений (result = ignored)
+func (h *WebhookHandlers) GitLab(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Gitlab-Token")
+	if h.secrets.GitLab == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.secrets.GitLab)) != 1 {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "X-Gitlab-Token does not match")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "webhook payload is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "cannot read request body")
+		return
+	}
+	ev, ok, err := parseGitLabEvent(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	if !ok {
+		writeWebhookResult(w, string(service.WebhookIgnored), nil)
+		return
+	}
+	h.apply(w, r, ev)
+}
+
+func (h *WebhookHandlers) apply(w http.ResponseWriter, r *http.Request, ev service.PREvent) {
+	pr, result, err := h.svc.Apply(r.Context(), ev)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if result != service.WebhookApplied {
+		writeWebhookResult(w, string(result), nil)
+		return
+	}
+	dto := toPRDTO(pr)
+	writeWebhookResult(w, string(result), &dto)
+}
+
+// writeWebhookResult отвечает 200: провайдеры повторяют доставку при любом другом статусе
+func writeWebhookResult(w http.ResponseWriter, result string, pr *prDTO) {
+	resp := struct {
+		Result string `json:"result"`
+		PR     *prDTO `json:"pr,omitempty"`
+	}{Result: result, PR: pr}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validGitHubSignature сверяет заголовок "sha256=<hex>" с HMAC-SHA256 тела
+func validGitHubSignature(secret string, body []byte, header string) bool {
+	if secret == "" {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// githubPREvent — поля события pull_request, которые нужны сервису
+type githubPREvent struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// parseGitHubEvent переводит событие GitHub в service.PREvent. ok = false для
+// событий и действий, которые сервис не отслеживает. pull_request_id — owner/repo#number
+func parseGitHubEvent(eventType string, body []byte) (_ service.PREvent, ok bool, _ error) {
+	if eventType != "pull_request" {
+		return service.PREvent{}, false, nil
+	}
+	var p githubPREvent
+	if err := json.Unmarshal(body, &p); err != nil {
+		return service.PREvent{}, false, fmt.Errorf("invalid pull_request payload: %v", err)
+	}
+	if p.Repository.FullName == "" || p.PullRequest.Number == 0 {
+		return service.PREvent{}, false, errors.New("repository.full_name and pull_request.number are required")
+	}
+	ev := service.PREvent{
+		Provider:    service.ProviderGitHub,
+		PRID:        fmt.Sprintf("%s#%d", p.Repository.FullName, p.PullRequest.Number),
+		Title:       p.PullRequest.Title,
+		AuthorLogin: p.PullRequest.User.Login,
+		Draft:       p.PullRequest.Draft,
+		Sender:      p.Sender.Login,
+	}
+	switch p.Action {
+	case "opened":
+		ev.Action = service.PRActionOpened
+	case "ready_for_review":
+		ev.Action = service.PRActionReady
+	case "converted_to_draft":
+		ev.Action = service.PRActionDraft
+	case "reopened":
+		ev.Action = service.PRActionReopened
+	case "closed":
+		ev.Action = service.PRActionClosed
+		if p.PullRequest.Merged {
+			ev.Action = service.PRActionMerged
+		}
+	default:
+		return service.PREvent{}, false, nil
+	}
+	return ev, true, nil
+}
+
+// gitlabMREvent — поля Merge Request Hook, которые нужны сервису. Автор MR
+// в событии есть только как author_id; user — тот, кто выполнил действие
+type gitlabMREvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID            int    `json:"iid"`
+		AuthorID       int64  `json:"author_id"`
+		Title          string `json:"title"`
+		Action         string `json:"action"`
+		Draft          bool   `json:"draft"`
+		WorkInProgress bool   `json:"work_in_progress"`
+	} `json:"object_attributes"`
+	Changes struct {
+		// draft в GitLab 15+, work_in_progress — в более ранних версиях
+		Draft          *gitlabBoolChange `json:"draft"`
+		WorkInProgress *gitlabBoolChange `json:"work_in_progress"`
+	} `json:"changes"`
+}
+
+type gitlabBoolChange struct {
+	Previous bool `json:"previous"`
+	Current  bool `json:"current"`
+}
+
+// parseGitLabEvent переводит Merge Request Hook в service.PREvent. ok = false для
+// событий и действий, которые сервис не отслеживает. pull_request_id — group/project!iid
+func parseGitLabEvent(eventType string, body []byte) (_ service.PREvent, ok bool, _ error) {
+	if eventType != "Merge Request Hook" {
+		return service.PREvent{}, false, nil
+	}
+	var p gitlabMREvent
+	if err := json.Unmarshal(body, &p); err != nil {
+		return service.PREvent{}, false, fmt.Errorf("invalid merge request payload: %v", err)
+	}
+	if p.ObjectKind != "merge_request" {
+		return service.PREvent{}, false, nil
+	}
+	attrs := p.ObjectAttributes
+	if p.Project.PathWithNamespace == "" || attrs.IID == 0 {
+		return service.PREvent{}, false, errors.New("project.path_with_namespace and object_attributes.iid are required")
+	}
+	ev := service.PREvent{
+		Provider: service.ProviderGitLab,
+		PRID:     fmt.Sprintf("%s!%d", p.Project.PathWithNamespace, attrs.IID),
+		Title:    attrs.Title,
+		Draft:    attrs.Draft || attrs.WorkInProgress,
+		Sender:   p.User.Username,
+	}
+	// Автор ищется по author_id, а по username — только если действие выполнил сам автор
+	if attrs.AuthorID != 0 {
+		ev.AuthorExternalID = strconv.FormatInt(attrs.AuthorID, 10)
+		if p.User.ID == attrs.AuthorID {
+			ev.AuthorLogin = p.User.Username
+		}
+	}
+	switch attrs.Action {
+	case "open":
+		ev.Action = service.PRActionOpened
+	case "merge":
+		ev.Action = service.PRActionMerged
+	case "close":
+		ev.Action = service.PRActionClosed
+	case "reopen":
+		ev.Action = service.PRActionReopened
+	case "update":
+		change := p.Changes.Draft
+		if change == nil {
+			change = p.Changes.WorkInProgress
+		}
+		if change == nil || change.Previous == change.Current {
+			return service.PREvent{}, false, nil
+		}
+		ev.Action = service.PRActionReady
+		if change.Current {
+			ev.Action = service.PRActionDraft
+		}
+	default:
+		return service.PREvent{}, false, nil
+	}
+	return ev, true, nil
+}
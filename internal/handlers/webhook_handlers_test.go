@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quasttyy/pr-reviewer/internal/domain"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	"github.com/quasttyy/pr-reviewer/internal/service"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+const (
+	testGitHubSecret = "It's a Secret to Everybody"
+	testGitLabToken  = "gl-webhook-token"
+)
+
+// fakeLifecycle записывает вызовы PRService, сделанные вебхуком
+type fakeLifecycle struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeLifecycle) call(op, prID, actor string) (repo.PRFull, error) {
+	f.calls = append(f.calls, op+" "+prID+" by "+actor)
+	return repo.PRFull{ID: prID, Status: "OPEN"}, f.err
+}
+
+func (f *fakeLifecycle) Create(ctx context.Context, prID, prName, authorID string) (repo.PRFull, error) {
+	return f.call("create("+prName+", "+authorID+")", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) CreateDraft(ctx context.Context, prID, prName, authorID string) (repo.PRFull, error) {
+	return f.call("draft("+prName+", "+authorID+")", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) Ready(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("ready", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) MarkDraft(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("draft", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) MergeExternal(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("merge", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) Close(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("close", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) Reopen(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("reopen", prID, service.ActorFromContext(ctx))
+}
+
+func (f *fakeLifecycle) ReopenDraft(ctx context.Context, prID string) (repo.PRFull, error) {
+	return f.call("reopen-draft", prID, service.ActorFromContext(ctx))
+}
+
+// fakeIdentities знает только u1: логины Zakhar-Dev в GitHub и zakhar в GitLab
+// и ID 77 в GitLab. ID 51 из записанных событий не привязан
+type fakeIdentities struct{}
+
+func (fakeIdentities) Resolve(ctx context.Context, provider, login string) (string, error) {
+	if (provider == service.ProviderGitHub && login == "Zakhar-Dev") || (provider == service.ProviderGitLab && login == "zakhar") {
+		return "u1", nil
+	}
+	return "", service.ErrUnknownIdentity
+}
+
+func (fakeIdentities) ResolveExternalID(ctx context.Context, provider, externalID string) (string, error) {
+	if provider == service.ProviderGitLab && externalID == "77" {
+		return "u1", nil
+	}
+	return "", service.ErrUnknownIdentity
+}
+
+func newTestWebhookHandlers(prs *fakeLifecycle, secrets WebhookSecrets) *WebhookHandlers {
+	return NewWebhookHandlers(service.NewWebhookService(prs, fakeIdentities{}), secrets)
+}
+
+// checkWebhookResult проверяет ответ 200 с result и единственный вызов сервиса call
+func checkWebhookResult(t *testing.T, rec *httptest.ResponseRecorder, prs *fakeLifecycle, result, call string) {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Result string `json:"result"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Result != result {
+		t.Fatalf("result = %q, want %q", resp.Result, result)
+	}
+	var want []string
+	if call != "" {
+		want = []string{call}
+	}
+	if len(prs.calls) != len(want) || (len(want) > 0 && prs.calls[0] != want[0]) {
+		t.Fatalf("calls = %q, want %q", prs.calls, want)
+	}
+}
+
+func signGitHub(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testGitHubSecret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// readFixture читает записанное тело вебхука из testdata/<provider>
+func readFixture(t *testing.T, provider, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", provider, name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return body
+}
+
+func postGitHub(t *testing.T, h *WebhookHandlers, event, fixture string, sign func([]byte) string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := readFixture(t, "github", fixture)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", bytes.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", sign(body))
+	rec := httptest.NewRecorder()
+	h.GitHub(rec, req)
+	return rec
+}
+
+func TestGitHubWebhook_Fixtures(t *testing.T) {
+	cases := []struct {
+		fixture string
+		event   string
+		result  string
+		call    string
+	}{
+		{"pull_request_opened.json", "pull_request", "applied", "create(Add search by reviewer, u1) octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_opened_draft.json", "pull_request", "applied", "draft(Add search by reviewer, u1) octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_ready_for_review.json", "pull_request", "applied", "ready octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_converted_to_draft.json", "pull_request", "applied", "draft octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_closed_merged.json", "pull_request", "applied", "merge octo-org/api#42 by github:daniil-k"},
+		{"pull_request_closed.json", "pull_request", "applied", "close octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_reopened.json", "pull_request", "applied", "reopen octo-org/api#42 by github:Zakhar-Dev"},
+		{"pull_request_labeled.json", "pull_request", "ignored", ""},
+		{"ping.json", "ping", "ignored", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			prs := &fakeLifecycle{}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitHub: testGitHubSecret})
+			rec := postGitHub(t, h, tc.event, tc.fixture, signGitHub)
+			checkWebhookResult(t, rec, prs, tc.result, tc.call)
+		})
+	}
+}
+
+func TestGitHubWebhook_RejectsBadSignature(t *testing.T) {
+	cases := map[string]struct {
+		secret string
+		sign   func([]byte) string
+	}{
+		"wrong signature": {testGitHubSecret, func([]byte) string { return "sha256=" + hex.EncodeToString(make([]byte, 32)) }},
+		"no signature":    {testGitHubSecret, func([]byte) string { return "" }},
+		"sha1 signature":  {testGitHubSecret, func(b []byte) string { return "sha1=" + signGitHub(b)[len("sha256="):] }},
+		"no secret":       {"", signGitHub},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prs := &fakeLifecycle{}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitHub: tc.secret})
+			rec := postGitHub(t, h, "pull_request", "pull_request_opened.json", tc.sign)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", rec.Code)
+			}
+			if len(prs.calls) != 0 {
+				t.Fatalf("unsigned event must not reach the service, got %q", prs.calls)
+			}
+		})
+	}
+}
+
+func TestGitHubWebhook_Redelivered(t *testing.T) {
+	cases := []struct {
+		fixture string
+		err     error
+		status  int
+		result  string
+	}{
+		{"pull_request_opened.json", service.ErrPRExists, http.StatusOK, `"duplicate"`},
+		{"pull_request_closed.json", &service.TransitionError{From: "CLOSED", To: domain.PRStatusClosed}, http.StatusOK, `"duplicate"`},
+		{"pull_request_ready_for_review.json", &service.TransitionError{From: "OPEN", To: domain.PRStatusOpen}, http.StatusOK, `"duplicate"`},
+		{"pull_request_reopened.json", &service.TransitionError{From: "OPEN", To: domain.PRStatusOpen}, http.StatusOK, `"duplicate"`},
+		// PR открыт до подключения вебхука
+		{"pull_request_closed.json", service.ErrNotFoundPR, http.StatusOK, `"ignored"`},
+		// Событие вне очереди — настоящий конфликт
+		{"pull_request_reopened.json", &service.TransitionError{From: "MERGED", To: domain.PRStatusOpen}, http.StatusConflict, "INVALID_TRANSITION"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture+"/"+tc.err.Error(), func(t *testing.T) {
+			logger.Init("test")
+			prs := &fakeLifecycle{err: tc.err}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitHub: testGitHubSecret})
+			rec := postGitHub(t, h, "pull_request", tc.fixture, signGitHub)
+			if rec.Code != tc.status || !bytes.Contains(rec.Body.Bytes(), []byte(tc.result)) {
+				t.Fatalf("status %d, body %s; want %d with %s", rec.Code, rec.Body, tc.status, tc.result)
+			}
+		})
+	}
+}
+
+func postGitLab(t *testing.T, h *WebhookHandlers, event, fixture, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := readFixture(t, "gitlab", fixture)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(body))
+	req.Header.Set("X-Gitlab-Event", event)
+	req.Header.Set("X-Gitlab-Token", token)
+	rec := httptest.NewRecorder()
+	h.GitLab(rec, req)
+	return rec
+}
+
+func TestGitLabWebhook_Fixtures(t *testing.T) {
+	const mrHook = "Merge Request Hook"
+	cases := []struct {
+		fixture string
+		event   string
+		result  string
+		call    string
+	}{
+		{"mr_open.json", mrHook, "applied", "create(Per-tenant invoice numbering, u1) backend/billing!17 by gitlab:zakhar"},
+		{"mr_open_draft.json", mrHook, "applied", "draft(Draft: Per-tenant invoice numbering, u1) backend/billing!17 by gitlab:zakhar"},
+		{"mr_update_draft.json", mrHook, "applied", "draft backend/billing!17 by gitlab:zakhar"},
+		{"mr_update_ready.json", mrHook, "applied", "ready backend/billing!17 by gitlab:zakhar"},
+		{"mr_update_ready_legacy.json", mrHook, "applied", "ready backend/billing!17 by gitlab:zakhar"},
+		{"mr_merge.json", mrHook, "applied", "merge backend/billing!17 by gitlab:daniil.k"},
+		{"mr_close.json", mrHook, "applied", "close backend/billing!17 by gitlab:zakhar"},
+		{"mr_reopen.json", mrHook, "applied", "reopen backend/billing!17 by gitlab:zakhar"},
+		{"mr_reopen_draft.json", mrHook, "applied", "reopen-draft backend/billing!17 by gitlab:zakhar"},
+		{"mr_update_title.json", mrHook, "ignored", ""},
+		{"mr_approved.json", mrHook, "ignored", ""},
+		{"mr_open.json", "Push Hook", "ignored", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.event+"/"+tc.fixture, func(t *testing.T) {
+			prs := &fakeLifecycle{}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitLab: testGitLabToken})
+			rec := postGitLab(t, h, tc.event, tc.fixture, testGitLabToken)
+			checkWebhookResult(t, rec, prs, tc.result, tc.call)
+		})
+	}
+}
+
+func TestGitLabWebhook_RejectsBadToken(t *testing.T) {
+	cases := map[string]struct{ configured, sent string }{
+		"wrong token":   {testGitLabToken, "guess"},
+		"missing token": {testGitLabToken, ""},
+		"no token set":  {"", ""},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prs := &fakeLifecycle{}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitLab: tc.configured})
+			rec := postGitLab(t, h, "Merge Request Hook", "mr_open.json", tc.sent)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", rec.Code)
+			}
+			if len(prs.calls) != 0 {
+				t.Fatalf("rejected event must not reach the service, got %q", prs.calls)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhook_Author(t *testing.T) {
+	cases := map[string]struct {
+		replace []string // пары old, new для mr_open.json
+		status  int
+		want    string
+	}{
+		// ID 51 не привязан, но MR открыл сам автор — ищется по его username
+		"author by username": {nil, http.StatusOK, "create(Per-tenant invoice numbering, u1)"},
+		"author by id":       {[]string{`"author_id": 51`, `"author_id": 77`, `"username": "zakhar"`, `"username": "stranger"`}, http.StatusOK, "create(Per-tenant invoice numbering, u1)"},
+		// MR открыл не автор: username отправителя не должен стать автором
+		"opened by someone else": {[]string{`"author_id": 51`, `"author_id": 99`}, http.StatusBadRequest, "UNKNOWN_IDENTITY"},
+		"unknown author":         {[]string{`"username": "zakhar"`, `"username": "stranger"`}, http.StatusBadRequest, "UNKNOWN_IDENTITY"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			prs := &fakeLifecycle{}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitLab: testGitLabToken})
+			body := readFixture(t, "gitlab", "mr_open.json")
+			for i := 0; i < len(tc.replace); i += 2 {
+				body = bytes.Replace(body, []byte(tc.replace[i]), []byte(tc.replace[i+1]), 1)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/gitlab", bytes.NewReader(body))
+			req.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+			req.Header.Set("X-Gitlab-Token", testGitLabToken)
+			rec := httptest.NewRecorder()
+			h.GitLab(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, body %s; want %d", rec.Code, rec.Body, tc.status)
+			}
+			if tc.status != http.StatusOK {
+				if !bytes.Contains(rec.Body.Bytes(), []byte(tc.want)) || len(prs.calls) != 0 {
+					t.Fatalf("body %s, calls %q; want %s and no calls", rec.Body, prs.calls, tc.want)
+				}
+				return
+			}
+			if len(prs.calls) != 1 || !strings.HasPrefix(prs.calls[0], tc.want) {
+				t.Fatalf("calls = %q, want %s", prs.calls, tc.want)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhook_Redelivered(t *testing.T) {
+	logger.Init("test")
+	cases := []struct {
+		fixture string
+		err     error
+		result  string
+	}{
+		{"mr_open.json", service.ErrPRExists, "duplicate"},
+		{"mr_close.json", &service.TransitionError{From: "CLOSED", To: domain.PRStatusClosed}, "duplicate"},
+		{"mr_reopen.json", &service.TransitionError{From: "OPEN", To: domain.PRStatusOpen}, "duplicate"},
+		{"mr_reopen_draft.json", &service.TransitionError{From: "DRAFT", To: domain.PRStatusDraft}, "duplicate"},
+		{"mr_update_ready.json", &service.TransitionError{From: "OPEN", To: domain.PRStatusOpen}, "duplicate"},
+		{"mr_merge.json", service.ErrNotFoundPR, "ignored"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			prs := &fakeLifecycle{err: tc.err}
+			h := newTestWebhookHandlers(prs, WebhookSecrets{GitLab: testGitLabToken})
+			rec := postGitLab(t, h, "Merge Request Hook", tc.fixture, testGitLabToken)
+			if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte(`"`+tc.result+`"`)) {
+				t.Fatalf("status %d, body %s; want 200 with %s", rec.Code, rec.Body, tc.result)
+			}
+		})
+	}
+}
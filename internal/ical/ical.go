@@ -0,0 +1,291 @@
+// Package ical разбирает события (VEVENT) из файлов iCalendar (RFC 5545).
+// Поддерживается подмножество, достаточное для календарей отсутствий:
+// UID, SUMMARY, DTSTART, DTEND, DURATION (дни/часы/минуты) и ATTENDEE
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrMalformed = errors.New("malformed icalendar")
+
+// Attendee — участник события: CN и адрес из mailto:
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+// Event — событие календаря. End не включается в событие.
+// AllDay — DTSTART задан датой без времени. UnknownTZID — TZID из DTSTART
+// или DTEND, которого нет в базе часовых поясов: время такого события
+// прочитано как UTC и может быть неверным
+type Event struct {
+	UID         string
+	Summary     string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Recurring   bool
+	UnknownTZID string
+	Attendees   []Attendee
+}
+
+// property — строка контента: NAME;PARAM=VALUE:value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse читает все VEVENT из r. Время с TZID переводится в соответствующий
+// часовой пояс, «плавающее» время и даты считаются заданными в UTC.
+// Неизвестный TZID не считается ошибкой файла: событие помечается UnknownTZID
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		events []Event
+		cur    *Event
+		depth  int // вложенные компоненты внутри VEVENT (VALARM и т.п.)
+		hasEnd bool
+		dur    time.Duration
+	)
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, i+1, err)
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			if cur != nil {
+				return nil, fmt.Errorf("%w: nested VEVENT at line %d", ErrMalformed, i+1)
+			}
+			cur, depth, hasEnd, dur = &Event{}, 0, false, 0
+		case cur == nil:
+			continue
+		case p.name == "BEGIN":
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if cur.Start.IsZero() {
+				return nil, fmt.Errorf("%w: VEVENT %q without DTSTART", ErrMalformed, cur.UID)
+			}
+			if !hasEnd {
+				// RFC 5545, 3.6.1: без DTEND событие-дата длится один день,
+				// событие со временем — DURATION (или нулевую длительность)
+				cur.End = cur.Start.Add(dur)
+				if cur.AllDay && dur == 0 {
+					cur.End = cur.Start.AddDate(0, 0, 1)
+				}
+			}
+			events = append(events, *cur)
+			cur = nil
+		case depth > 0:
+			continue
+		default:
+			if err := cur.apply(p, &hasEnd, &dur); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, i+1, err)
+			}
+		}
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("%w: unterminated VEVENT", ErrMalformed)
+	}
+	return events, nil
+}
+
+func (e *Event) apply(p property, hasEnd *bool, dur *time.Duration) error {
+	switch p.name {
+	case "UID":
+		e.UID = unescape(p.value)
+	case "SUMMARY":
+		e.Summary = unescape(p.value)
+	case "DTSTART":
+		t, allDay, err := e.parseTime(p)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = t, allDay
+	case "DTEND":
+		t, _, err := e.parseTime(p)
+		if err != nil {
+			return err
+		}
+		e.End, *hasEnd = t, true
+	case "DURATION":
+		d, err := parseDuration(p.value)
+		if err != nil {
+			return err
+		}
+		*dur = d
+	case "RRULE", "RDATE":
+		e.Recurring = true
+	case "ATTENDEE":
+		a := Attendee{Name: unescape(p.params["CN"])}
+		if addr, ok := cutPrefixFold(p.value, "mailto:"); ok {
+			a.Email = addr
+		}
+		e.Attendees = append(e.Attendees, a)
+	}
+	return nil
+}
+
+// unfold склеивает перенесённые строки (RFC 5545, 3.1): строка,
+// начинающаяся с пробела или табуляции, продолжает предыдущую
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// parseLine разбирает NAME;PARAM=VALUE;PARAM="VALUE":value.
+// Двоеточие и точка с запятой внутри кавычек частью синтаксиса не считаются
+func parseLine(line string) (property, error) {
+	p := property{params: map[string]string{}}
+	inQuotes := false
+	start := 0
+	var key string
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == ';' || c == ':':
+			part := line[start:i]
+			if p.name == "" {
+				p.name = strings.ToUpper(part)
+			} else if key != "" {
+				p.params[key] = strings.Trim(part, `"`)
+				key = ""
+			}
+			if c == ':' {
+				p.value = line[i+1:]
+				if p.name == "" {
+					return property{}, errors.New("empty property name")
+				}
+				return p, nil
+			}
+			start = i + 1
+		case c == '=' && p.name != "" && key == "":
+			key = strings.ToUpper(line[start:i])
+			start = i + 1
+		}
+	}
+	return property{}, fmt.Errorf("no value in %q", line)
+}
+
+// parseTime разбирает DTSTART или DTEND. Если TZID неизвестен, время читается
+// как UTC, а TZID запоминается в e.UnknownTZID
+func (e *Event) parseTime(p property) (time.Time, bool, error) {
+	v := p.value
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(v) == len("20060102") {
+		t, err := time.Parse("20060102", v)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			e.UnknownTZID = tzid
+		} else {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// parseDuration разбирает длительность вида P1D, PT4H, P1DT2H30M, P2W
+func parseDuration(v string) (time.Duration, error) {
+	neg := false
+	if v != "" && (v[0] == '+' || v[0] == '-') {
+		neg = v[0] == '-'
+		v = v[1:]
+	}
+	if !strings.HasPrefix(v, "P") {
+		return 0, fmt.Errorf("bad duration %q", v)
+	}
+	var d time.Duration
+	n := 0
+	inTime := false
+	for _, c := range v[1:] {
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("bad duration %q", v)
+		}
+		n = 0
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+func unescape(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
@@ -0,0 +1,105 @@
+package ical
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Fixture(t *testing.T) {
+	f, err := os.Open("testdata/team.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	events, err := Parse(f)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("want 5 events, got %d", len(events))
+	}
+
+	vacation := events[0]
+	if vacation.UID != "ooo-1@backend" || !vacation.AllDay ||
+		!vacation.Start.Equal(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)) ||
+		!vacation.End.Equal(time.Date(2026, 7, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected all-day event: %+v", vacation)
+	}
+	if want := []Attendee{{Name: "Daniil", Email: "u2@example.com"}}; !reflect.DeepEqual(vacation.Attendees, want) {
+		t.Fatalf("attendees = %+v, want %+v", vacation.Attendees, want)
+	}
+
+	conf := events[1]
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	wantStart := time.Date(2026, 9, 10, 9, 0, 0, 0, berlin)
+	if conf.Summary != "u3: conference, Berlin" || !conf.Start.Equal(wantStart) ||
+		!conf.End.Equal(wantStart.Add(56*time.Hour)) {
+		t.Fatalf("unexpected TZID/DURATION event: %+v", conf)
+	}
+
+	sick := events[2]
+	if want := []Attendee{{Name: "Konstantin K", Email: "konstantin@example.com"}}; !reflect.DeepEqual(sick.Attendees, want) {
+		t.Fatalf("folded attendee = %+v, want %+v", sick.Attendees, want)
+	}
+
+	if !events[3].Recurring {
+		t.Fatalf("RRULE event must be marked recurring")
+	}
+
+	oneDay := events[4]
+	if !oneDay.End.Equal(oneDay.Start.AddDate(0, 0, 1)) {
+		t.Fatalf("all-day event without DTEND must last one day, got %v - %v", oneDay.Start, oneDay.End)
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	cases := map[string]string{
+		"unterminated": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\nDTSTART:20260101T000000Z\n",
+		"no dtstart":   "BEGIN:VEVENT\nUID:x\nEND:VEVENT\n",
+		"bad date":     "BEGIN:VEVENT\nDTSTART:2026-01-01\nEND:VEVENT\n",
+		"no value":     "BEGIN:VEVENT\nSUMMARY\nEND:VEVENT\n",
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(in)); !errors.Is(err, ErrMalformed) {
+				t.Fatalf("expected ErrMalformed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParse_UnknownTZID(t *testing.T) {
+	in := "BEGIN:VEVENT\nUID:x\nDTSTART;TZID=Europe/Berlin:20260910T090000\n" +
+		"DTEND;TZID=Russian Standard Time:20260912T170000\nEND:VEVENT\n"
+	events, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(events) != 1 || events[0].UnknownTZID != "Russian Standard Time" {
+		t.Fatalf("unknown TZID must be reported, got %+v", events)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"P1D":       24 * time.Hour,
+		"PT4H":      4 * time.Hour,
+		"P1DT2H30M": 26*time.Hour + 30*time.Minute,
+		"P2W":       14 * 24 * time.Hour,
+		"-PT15M":    -15 * time.Minute,
+	}
+	for in, want := range cases {
+		got, err := parseDuration(in)
+		if err != nil || got != want {
+			t.Fatalf("parseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseDuration("1D"); err == nil {
+		t.Fatalf("expected error for duration without P")
+	}
+}
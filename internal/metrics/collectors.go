@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// PoolStater — источник статистики пула соединений (*pgxpool.Pool)
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolCollector читает pgxpool.Stat при каждом сборе метрик
+type poolCollector struct {
+	pool PoolStater
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquireCount *prometheus.Desc
+	acquireWait  *prometheus.Desc
+	emptyWait    *prometheus.Desc
+}
+
+func newPoolCollector(pool PoolStater) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		pool:         pool,
+		acquired:     desc("acquired_conns", "Connections currently acquired from the pool."),
+		idle:         desc("idle_conns", "Idle connections in the pool."),
+		total:        desc("total_conns", "Total connections in the pool."),
+		max:          desc("max_conns", "Maximum size of the pool."),
+		acquireCount: desc("acquire_total", "Successful acquires from the pool."),
+		acquireWait:  desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+		emptyWait:    desc("empty_acquire_wait_seconds_total", "Total time acquires waited because the pool was empty."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquireCount
+	ch <- c.acquireWait
+	ch <- c.emptyWait
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(st.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(st.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(st.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(st.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(st.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, st.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyWait, prometheus.CounterValue, st.EmptyAcquireWaitTime().Seconds())
+}
+
+// underReviewedTimeout ограничивает запрос числа недоукомплектованных PR при сборе метрик
+const underReviewedTimeout = 2 * time.Second
+
+// underReviewedCollector отдаёт число OPEN PR, которым не хватает ревьюверов.
+// Если запрос не удался, метрика в этом сборе пропускается
+type underReviewedCollector struct {
+	count UnderReviewedCounter
+	desc  *prometheus.Desc
+}
+
+func newUnderReviewedCollector(count UnderReviewedCounter) *underReviewedCollector {
+	return &underReviewedCollector{
+		count: count,
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "open_prs_need_reviewers"),
+			"Open pull requests with fewer reviewers than the team quorum or flagged need_more_reviewers.", nil, nil),
+	}
+}
+
+func (c *underReviewedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *underReviewedCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), underReviewedTimeout)
+	defer cancel()
+	n, err := c.count(ctx)
+	if err != nil {
+		logger.Warn("metrics: count under-reviewed PRs", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
@@ -0,0 +1,121 @@
+// Package metrics собирает метрики сервиса в формате Prometheus:
+// HTTP-запросы по шаблонам маршрутов chi, статистику пула pgxpool и доменные счётчики PR
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pr_reviewer"
+
+// unmatchedRoute — метка route для запросов, не попавших ни в один маршрут
+const unmatchedRoute = "unmatched"
+
+// UnderReviewedCounter возвращает число OPEN PR, которым не хватает ревьюверов
+type UnderReviewedCounter func(ctx context.Context) (int, error)
+
+// Metrics хранит реестр и метрики сервиса. Реализует service.Metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	prsCreated  prometheus.Counter
+	prsMerged   prometheus.Counter
+	reassigned  prometheus.Counter
+	noCandidate prometheus.Counter
+}
+
+// New создаёт реестр с метриками Go-рантайма, процесса, HTTP и доменными счётчиками.
+// pool и underReviewed необязательны: без них соответствующие метрики не собираются
+func New(pool PoolStater, underReviewed UnderReviewedCounter) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, chi route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		prsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "prs_created_total",
+			Help:      "Pull requests created, including drafts.",
+		}),
+		prsMerged: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "prs_merged_total",
+			Help:      "Pull requests merged.",
+		}),
+		reassigned: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reviewers_reassigned_total",
+			Help:      "Reviewers replaced on open pull requests.",
+		}),
+		noCandidate: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "no_candidate_total",
+			Help:      "Reassignments that found no replacement candidate.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.prsCreated, m.prsMerged, m.reassigned, m.noCandidate,
+	)
+	if pool != nil {
+		m.registry.MustRegister(newPoolCollector(pool))
+	}
+	if underReviewed != nil {
+		m.registry.MustRegister(newUnderReviewedCollector(underReviewed))
+	}
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware считает запросы и их длительность. Метка route — шаблон маршрута chi
+// (например /pullRequest/create), а не фактический путь, чтобы число серий было ограничено
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) PRCreated()          { m.prsCreated.Inc() }
+func (m *Metrics) PRMerged()           { m.prsMerged.Inc() }
+func (m *Metrics) ReviewerReassigned() { m.reassigned.Inc() }
+func (m *Metrics) NoCandidate()        { m.noCandidate.Inc() }
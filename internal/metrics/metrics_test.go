@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New(nil, nil)
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Route("/team", func(rt chi.Router) {
+		rt.Get("/get", func(w http.ResponseWriter, r *http.Request) {})
+		rt.Post("/add", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/team/get?team_name=a", nil),
+		httptest.NewRequest(http.MethodGet, "/team/get?team_name=b", nil),
+		httptest.NewRequest(http.MethodPost, "/team/add", nil),
+		httptest.NewRequest(http.MethodGet, "/nope", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`pr_reviewer_http_requests_total{method="GET",route="/team/get",status="200"} 2`,
+		`pr_reviewer_http_requests_total{method="POST",route="/team/add",status="409"} 1`,
+		`pr_reviewer_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`pr_reviewer_http_request_duration_seconds_count{method="GET",route="/team/get",status="200"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics output does not contain %q", want)
+		}
+	}
+}
+
+func TestDomainCounters(t *testing.T) {
+	m := New(nil, nil)
+	m.PRCreated()
+	m.PRCreated()
+	m.PRMerged()
+	m.ReviewerReassigned()
+	m.NoCandidate()
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"pr_reviewer_prs_created_total 2",
+		"pr_reviewer_prs_merged_total 1",
+		"pr_reviewer_reviewers_reassigned_total 1",
+		"pr_reviewer_no_candidate_total 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics output does not contain %q", want)
+		}
+	}
+}
+
+func TestUnderReviewedGauge(t *testing.T) {
+	m := New(nil, func(ctx context.Context) (int, error) { return 3, nil })
+	if out := scrape(t, m); !strings.Contains(out, "pr_reviewer_open_prs_need_reviewers 3") {
+		t.Fatalf("gauge missing in output:\n%s", out)
+	}
+
+	m = New(nil, func(ctx context.Context) (int, error) { return 0, errors.New("db down") })
+	if out := scrape(t, m); strings.Contains(out, "pr_reviewer_open_prs_need_reviewers") {
+		t.Fatalf("gauge must be skipped on error")
+	}
+}
@@ -0,0 +1,82 @@
+// Package outbound отправляет исходящие вебхуки: JSON-тело POST-запросом
+// с подписью HMAC-SHA256 секретом подписки
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Заголовки исходящего вебхука
+const (
+	HeaderEvent     = "X-PR-Reviewer-Event"
+	HeaderDelivery  = "X-PR-Reviewer-Delivery"
+	HeaderSignature = "X-PR-Reviewer-Signature-256"
+)
+
+// Message — одна доставка события. Без Secret запрос не подписывается
+type Message struct {
+	URL        string
+	Secret     string
+	EventType  string
+	DeliveryID string
+	Body       []byte
+}
+
+// Sign возвращает подпись тела в формате sha256=<hex>, как у вебхуков GitHub
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись тела секретом за постоянное время
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Sender отправляет сообщения по HTTP
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender создаёт отправителя; timeout ограничивает один запрос целиком
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{client: &http.Client{Timeout: timeout}}
+}
+
+// Send отправляет сообщение и возвращает код ответа. Ответ не 2xx считается ошибкой;
+// код возвращается и в этом случае, а при сетевой ошибке равен 0
+func (s *Sender) Send(ctx context.Context, m Message) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(m.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pr-reviewer-webhooks")
+	req.Header.Set(HeaderEvent, m.EventType)
+	req.Header.Set(HeaderDelivery, m.DeliveryID)
+	if m.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(m.Secret, m.Body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Дочитываем тело, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
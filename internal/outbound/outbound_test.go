@@ -0,0 +1,67 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSender_SignsBody(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`{"type":"pr.created"}`)
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	code, err := NewSender(time.Second).Send(context.Background(), Message{
+		URL:        srv.URL,
+		Secret:     secret,
+		EventType:  "pr.created",
+		DeliveryID: "42",
+		Body:       body,
+	})
+	if err != nil || code != http.StatusAccepted {
+		t.Fatalf("send = %d, %v; want 202", code, err)
+	}
+	if string(gotBody) != string(body) {
+		t.Fatalf("body = %s", gotBody)
+	}
+	if got.Header.Get(HeaderEvent) != "pr.created" || got.Header.Get(HeaderDelivery) != "42" {
+		t.Fatalf("headers = %v", got.Header)
+	}
+	if got.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("content type = %q", got.Header.Get("Content-Type"))
+	}
+	sig := got.Header.Get(HeaderSignature)
+	if !Verify(secret, gotBody, sig) {
+		t.Fatalf("signature %q does not verify", sig)
+	}
+	if Verify("other", gotBody, sig) {
+		t.Fatalf("signature must depend on secret")
+	}
+}
+
+func TestSender_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	code, err := NewSender(time.Second).Send(context.Background(), Message{URL: srv.URL, Body: []byte(`{}`)})
+	if err == nil || code != http.StatusServiceUnavailable {
+		t.Fatalf("send = %d, %v; want 503 with error", code, err)
+	}
+
+	// Получатель недоступен: кода ответа нет
+	srv.Close()
+	code, err = NewSender(time.Second).Send(context.Background(), Message{URL: srv.URL, Body: []byte(`{}`)})
+	if err == nil || code != 0 {
+		t.Fatalf("send = %d, %v; want 0 with error", code, err)
+	}
+}
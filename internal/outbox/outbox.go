@@ -0,0 +1,168 @@
+// Package outbox содержит приёмники (sink), в которые фоновый процесс публикует
+// события из таблицы outbox. Доставка — at-least-once: после сбоя событие может
+// быть опубликовано повторно, получатели отбрасывают повторы по ID
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// Message — событие из outbox. Type — тип события журнала PR (created, assigned, ...),
+// AggregateID — pull_request_id, Payload — снимок события в JSON
+type Message struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Sink принимает события из outbox. Ошибка означает, что событие не принято
+// и будет опубликовано снова
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, m Message) error
+}
+
+// LogSink пишет события в лог сервиса
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(ctx context.Context, m Message) error {
+	logger.Info("outbox event",
+		"outbox_id", m.ID,
+		"type", m.Type,
+		"pull_request_id", m.AggregateID,
+		"payload", string(m.Payload),
+	)
+	return nil
+}
+
+// FileSink дописывает события в файл построчно в JSON
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open outbox file: %w", err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+// Publish считает событие принятым, только когда строка сброшена на диск
+func (s *FileSink) Publish(ctx context.Context, m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink отправляет каждое событие POST-запросом. С непустым секретом тело
+// подписывается так же, как исходящие вебхуки (X-PR-Reviewer-Signature-256)
+type HTTPSink struct {
+	url    string
+	secret string
+	sender *outbound.Sender
+}
+
+func NewHTTPSink(url, secret string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, secret: secret, sender: outbound.NewSender(timeout)}
+}
+
+func (s *HTTPSink) Name() string { return "http" }
+
+func (s *HTTPSink) Publish(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = s.sender.Send(ctx, outbound.Message{
+		URL:        s.url,
+		Secret:     s.secret,
+		EventType:  m.Type,
+		DeliveryID: strconv.FormatInt(m.ID, 10),
+		Body:       body,
+	})
+	return err
+}
+
+// Config — приёмники, в которые публикуются события
+type Config struct {
+	// Sinks — имена приёмников: webhooks, chat, log, file, http
+	Sinks       []string
+	File        string
+	HTTPURL     string
+	HTTPSecret  string
+	HTTPTimeout time.Duration
+}
+
+// NewSinks создаёт приёмники из конфига в заданном порядке. services — приёмники,
+// которые создаёт сервис (webhooks, chat); они выбираются по Name
+func NewSinks(cfg Config, services ...Sink) ([]Sink, error) {
+	byName := make(map[string]Sink, len(services))
+	for _, s := range services {
+		byName[s.Name()] = s
+	}
+	seen := make(map[string]bool, len(cfg.Sinks))
+	var sinks []Sink
+	for _, name := range cfg.Sinks {
+		if seen[name] {
+			return nil, fmt.Errorf("outbox sink %q listed twice", name)
+		}
+		seen[name] = true
+		if s, ok := byName[name]; ok {
+			sinks = append(sinks, s)
+			continue
+		}
+		switch name {
+		case "log":
+			sinks = append(sinks, LogSink{})
+		case "file":
+			s, err := NewFileSink(cfg.File)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, s)
+		case "http":
+			if cfg.HTTPURL == "" {
+				return nil, fmt.Errorf("outbox sink http requires http_url")
+			}
+			sinks = append(sinks, NewHTTPSink(cfg.HTTPURL, cfg.HTTPSecret, cfg.HTTPTimeout))
+		default:
+			available := []string{"log", "file", "http"}
+			for name := range byName {
+				available = append(available, name)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("unknown or unconfigured outbox sink %q (available: %s)", name, strings.Join(available, ", "))
+		}
+	}
+	return sinks, nil
+}
@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+)
+
+func testMessage(id int64) Message {
+	return Message{
+		ID:          id,
+		Type:        "assigned",
+		AggregateID: "pr-1",
+		Payload:     json.RawMessage(`{"pull_request_id":"pr-1","user_id":"u2"}`),
+		CreatedAt:   time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFileSink_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, id := range []int64{1, 2} {
+		if err := sink.Publish(context.Background(), testMessage(id)); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	defer f.Close()
+	var ids []int64
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		if m.Type != "assigned" || string(m.Payload) != `{"pull_request_id":"pr-1","user_id":"u2"}` {
+			t.Fatalf("message = %+v", m)
+		}
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("ids = %v, want [1 2]", ids)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body []byte
+	var header http.Header
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, "s3cret", time.Second)
+	if err := sink.Publish(context.Background(), testMessage(7)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil || m.ID != 7 {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	if header.Get(outbound.HeaderDelivery) != "7" || !outbound.Verify("s3cret", body, header.Get(outbound.HeaderSignature)) {
+		t.Fatalf("headers = %v", header)
+	}
+
+	// Без секрета запрос не подписывается
+	if err := NewHTTPSink(srv.URL, "", time.Second).Publish(context.Background(), testMessage(8)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if header.Get(outbound.HeaderSignature) != "" {
+		t.Fatalf("unexpected signature %q", header.Get(outbound.HeaderSignature))
+	}
+
+	// Ответ не 2xx — событие не принято
+	status = http.StatusServiceUnavailable
+	if err := sink.Publish(context.Background(), testMessage(9)); err == nil {
+		t.Fatalf("want error on 503")
+	}
+}
+
+// namedSink — приёмник сервиса, который ничего не делает
+type namedSink string
+
+func (s namedSink) Name() string { return string(s) }
+
+func (namedSink) Publish(ctx context.Context, m Message) error { return nil }
+
+func TestNewSinks(t *testing.T) {
+	webhooks := namedSink("webhooks")
+	sinks, err := NewSinks(Config{Sinks: []string{"webhooks", "log", "file"}, File: filepath.Join(t.TempDir(), "o.jsonl")}, webhooks)
+	if err != nil {
+		t.Fatalf("sinks: %v", err)
+	}
+	if len(sinks) != 3 || sinks[0] != webhooks || sinks[2].Name() != "file" {
+		t.Fatalf("sinks = %v", sinks)
+	}
+
+	for _, cfg := range []Config{
+		{Sinks: []string{"kafka"}},
+		{Sinks: []string{"chat"}},
+		{Sinks: []string{"http"}},
+		{Sinks: []string{"log", "log"}},
+	} {
+		if _, err := NewSinks(cfg, webhooks); err == nil {
+			t.Errorf("%v: want error", cfg.Sinks)
+		}
+	}
+}
@@ -3,11 +3,13 @@ package postgres
 import (
 	"context"
 	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"time"
 )
 
-func InitPool(ctx context.Context, dsn string, minConns, maxConns int32) *pgxpool.Pool {
+// InitPool создаёт пул соединений. tracer необязателен: с ним каждый запрос попадает в трейс
+func InitPool(ctx context.Context, dsn string, minConns, maxConns int32, tracer pgx.QueryTracer) *pgxpool.Pool {
 	logger.Info("initializing postgres connection...")
 
 	// Парсим DSN
@@ -22,6 +24,9 @@ func InitPool(ctx context.Context, dsn string, minConns, maxConns int32) *pgxpoo
 	cfg.MaxConnIdleTime = 5 * time.Minute
 	cfg.MaxConnLifetime = 30 * time.Minute
 	cfg.HealthCheckPeriod = 30 * time.Second
+	if tracer != nil {
+		cfg.ConnConfig.Tracer = tracer
+	}
 
 	// Создаём пул
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
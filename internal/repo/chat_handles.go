@@ -0,0 +1,59 @@
+package repo
+
+import "context"
+
+const (
+	sqlSelectChatHandles = `
+		SELECT user_id, COALESCE(chat_handle, '')
+		FROM users
+		WHERE user_id = ANY($1)
+	`
+	sqlUpdateChatHandle = `
+		UPDATE users
+		SET chat_handle = NULLIF($2, '')
+		WHERE user_id = $1
+		RETURNING user_id, COALESCE(chat_handle, '')
+	`
+)
+
+// ChatHandleRow — ник пользователя в мессенджере. Пустой Handle — ник не задан
+type ChatHandleRow struct {
+	UserID string
+	Handle string
+}
+
+// GetChatHandle возвращает ник пользователя или pgx.ErrNoRows
+func (r *UserRepo) GetChatHandle(ctx context.Context, userID string) (ChatHandleRow, error) {
+	var row ChatHandleRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectChatHandles, []string{userID}).Scan(&row.UserID, &row.Handle)
+	return row, err
+}
+
+// UpdateChatHandle задаёт ник пользователя (пустой — снять).
+// Если пользователя нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) UpdateChatHandle(ctx context.Context, row ChatHandleRow) (ChatHandleRow, error) {
+	var out ChatHandleRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlUpdateChatHandle, row.UserID, row.Handle).Scan(&out.UserID, &out.Handle)
+	return out, err
+}
+
+// ListChatHandles возвращает заданные ники переданных пользователей по user_id
+func (r *UserRepo) ListChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectChatHandles, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]string, len(userIDs))
+	for rows.Next() {
+		var row ChatHandleRow
+		if err := rows.Scan(&row.UserID, &row.Handle); err != nil {
+			return nil, err
+		}
+		if row.Handle != "" {
+			out[row.UserID] = row.Handle
+		}
+	}
+	return out, rows.Err()
+}
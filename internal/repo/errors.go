@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotAssigned — ревьювер не назначен на PR
+	ErrNotAssigned = errors.New("reviewer not assigned")
+	// ErrDuplicate — запись с таким ключом уже существует
+	ErrDuplicate = errors.New("duplicate key")
+)
+
+// pgUniqueViolation — SQLSTATE unique_violation
+const pgUniqueViolation = "23505"
+
+// mapUniqueViolation превращает нарушение уникальности в ErrDuplicate с именем
+// ограничения, остальные ошибки возвращает как есть
+func mapUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return fmt.Errorf("%w: %s", ErrDuplicate, pgErr.ConstraintName)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// Привязка с тем же внешним ID, но другим логином устарела: пользователь сменил логин
+	sqlClearIdentityExternalID = `
+		UPDATE external_identities
+		SET external_id = NULL
+		WHERE provider = $1 AND external_id = $2 AND login <> $3
+	`
+	sqlUpsertIdentity = `
+		INSERT INTO external_identities (provider, login, user_id, external_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (provider, login) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    external_id = COALESCE(EXCLUDED.external_id, external_identities.external_id)
+		RETURNING provider, login, user_id, COALESCE(external_id, ''), created_at
+	`
+	sqlSelectIdentitiesByUser = `
+		SELECT provider, login, user_id, COALESCE(external_id, ''), created_at
+		FROM external_identities
+		WHERE user_id = $1
+		ORDER BY provider, login
+	`
+	sqlSelectIdentityUser = `
+		SELECT user_id
+		FROM external_identities
+		WHERE provider = $1 AND login = $2
+	`
+	sqlSelectIdentityUserByExternalID = `
+		SELECT user_id
+		FROM external_identities
+		WHERE provider = $1 AND external_id = $2
+	`
+	sqlDeleteIdentity = `
+		DELETE FROM external_identities
+		WHERE provider = $1 AND login = $2
+		RETURNING user_id
+	`
+)
+
+// IdentityRow — учётная запись пользователя во внешней системе (provider = github, ...).
+// ExternalID — постоянный ID пользователя в этой системе, пустой, если не задан
+type IdentityRow struct {
+	Provider   string
+	Login      string
+	UserID     string
+	ExternalID string
+	CreatedAt  time.Time
+}
+
+// UpsertIdentity привязывает логин к пользователю. Если логин уже привязан
+// к другому пользователю, привязка переносится. Пустой ExternalID сохраняет прежний,
+// а непустой снимается с других логинов провайдера
+func (r *UserRepo) UpsertIdentity(ctx context.Context, row IdentityRow) (IdentityRow, error) {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return IdentityRow{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if row.ExternalID != "" {
+		if _, err := tx.Exec(ctx, sqlClearIdentityExternalID, row.Provider, row.ExternalID, row.Login); err != nil {
+			return IdentityRow{}, err
+		}
+	}
+	var out IdentityRow
+	if err := tx.QueryRow(ctx, sqlUpsertIdentity, row.Provider, row.Login, row.UserID, row.ExternalID).Scan(
+		&out.Provider, &out.Login, &out.UserID, &out.ExternalID, &out.CreatedAt,
+	); err != nil {
+		return IdentityRow{}, err
+	}
+	return out, tx.Commit(ctx)
+}
+
+func (r *UserRepo) ListIdentities(ctx context.Context, userID string) ([]IdentityRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectIdentitiesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []IdentityRow
+	for rows.Next() {
+		var row IdentityRow
+		if err := rows.Scan(&row.Provider, &row.Login, &row.UserID, &row.ExternalID, &row.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ResolveIdentity возвращает user_id по логину. Если привязки нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) ResolveIdentity(ctx context.Context, provider, login string) (string, error) {
+	var userID string
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectIdentityUser, provider, login).Scan(&userID)
+	return userID, err
+}
+
+// ResolveExternalID возвращает user_id по внешнему ID. Если привязки нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) ResolveExternalID(ctx context.Context, provider, externalID string) (string, error) {
+	var userID string
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectIdentityUserByExternalID, provider, externalID).Scan(&userID)
+	return userID, err
+}
+
+// DeleteIdentity удаляет привязку логина. Если её нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) DeleteIdentity(ctx context.Context, provider, login string) error {
+	var userID string
+	return conn(ctx, r.pool).QueryRow(ctx, sqlDeleteIdentity, provider, login).Scan(&userID)
+}
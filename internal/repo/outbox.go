@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Событие берётся для приёмника $1, если он его ещё не брал или время повтора
+	// наступило, и перед ним нет события, которое ждёт повтора в этом же приёмнике:
+	// так приёмник получает события по порядку. Взятые события откладываются до $4,
+	// чтобы их не взял другой экземпляр сервиса; если relay упадёт, они будут
+	// опубликованы снова после этого срока
+	sqlClaimOutbox = `
+		WITH due AS (
+			SELECT o.id
+			FROM outbox o
+			LEFT JOIN outbox_deliveries d ON d.outbox_id = o.id AND d.sink = $1
+			WHERE o.dispatched_at IS NULL
+			  AND (d.outbox_id IS NULL OR (d.status = 'pending' AND d.next_attempt_at <= $2))
+			  AND NOT EXISTS (
+			      SELECT 1
+			      FROM outbox_deliveries w
+			      WHERE w.sink = $1 AND w.status = 'pending'
+			        AND w.next_attempt_at > $2 AND w.outbox_id < o.id
+			  )
+			ORDER BY o.id
+			LIMIT $3
+			FOR UPDATE OF o SKIP LOCKED
+		), claimed AS (
+			INSERT INTO outbox_deliveries (outbox_id, sink, next_attempt_at)
+			SELECT id, $1::varchar, $4::timestamptz
+			FROM due
+			ON CONFLICT (outbox_id, sink) DO UPDATE SET next_attempt_at = EXCLUDED.next_attempt_at
+			RETURNING outbox_id, attempts
+		)
+		SELECT o.id, o.event_type, o.aggregate_id, o.payload, o.created_at, c.attempts
+		FROM claimed c
+		JOIN outbox o ON o.id = c.outbox_id
+		ORDER BY o.id
+	`
+	sqlMarkOutboxDelivered = `
+		UPDATE outbox_deliveries
+		SET attempts = attempts + 1,
+		    status = 'delivered',
+		    last_error = NULL,
+		    delivered_at = $3
+		WHERE sink = $1 AND outbox_id = ANY($2)
+	`
+	sqlRecordOutboxAttempt = `
+		UPDATE outbox_deliveries
+		SET attempts = attempts + 1,
+		    status = $3,
+		    last_error = NULLIF($4, ''),
+		    next_attempt_at = $5,
+		    delivered_at = CASE WHEN $3 = 'delivered' THEN $5 END
+		WHERE outbox_id = $1 AND sink = $2
+	`
+	sqlReleaseOutbox = `
+		UPDATE outbox_deliveries
+		SET next_attempt_at = $3
+		WHERE sink = $1 AND outbox_id = ANY($2) AND status = 'pending'
+	`
+	// Событие опубликовано, когда каждый приёмник из $1 его принял или отказался от него
+	sqlMarkOutboxDispatched = `
+		UPDATE outbox o
+		SET dispatched_at = $2
+		WHERE o.dispatched_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1
+		      FROM unnest($1::text[]) AS s(sink)
+		      WHERE NOT EXISTS (
+		          SELECT 1
+		          FROM outbox_deliveries d
+		          WHERE d.outbox_id = o.id AND d.sink = s.sink AND d.status <> 'pending'
+		      )
+		  )
+	`
+	sqlDeleteDispatchedOutbox = `
+		DELETE FROM outbox
+		WHERE dispatched_at < $1
+	`
+)
+
+// OutboxRow — событие, записанное в outbox вместе с изменением PR.
+// EventType совпадает с типом события в pr_events, AggregateID — pull_request_id.
+// Attempts — число уже сделанных попыток публикации в приёмник, для которого событие взято
+type OutboxRow struct {
+	ID          int64
+	EventType   string
+	AggregateID string
+	Payload     []byte
+	CreatedAt   time.Time
+	Attempts    int
+}
+
+type OutboxRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepo(pool *pgxpool.Pool) *OutboxRepo {
+	return &OutboxRepo{pool: pool}
+}
+
+// ClaimOutbox выбирает для приёмника sink до limit событий в порядке записи,
+// время публикации которых наступило к now, и откладывает их до leaseUntil
+func (r *OutboxRepo) ClaimOutbox(ctx context.Context, sink string, now time.Time, limit int, leaseUntil time.Time) ([]OutboxRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlClaimOutbox, sink, now, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []OutboxRow
+	for rows.Next() {
+		var row OutboxRow
+		if err := rows.Scan(&row.ID, &row.EventType, &row.AggregateID, &row.Payload, &row.CreatedAt, &row.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// MarkOutboxDelivered отмечает, что приёмник sink принял события ids
+func (r *OutboxRepo) MarkOutboxDelivered(ctx context.Context, sink string, ids []int64, at time.Time) error {
+	_, err := conn(ctx, r.pool).Exec(ctx, sqlMarkOutboxDelivered, sink, ids, at)
+	return err
+}
+
+// RecordOutboxAttempt записывает итог попытки публикации события в приёмник
+// и увеличивает счётчик попыток
+func (r *OutboxRepo) RecordOutboxAttempt(ctx context.Context, id int64, sink string, a DeliveryAttempt) error {
+	_, err := conn(ctx, r.pool).Exec(ctx, sqlRecordOutboxAttempt, id, sink, a.Status, a.Error, a.At)
+	return err
+}
+
+// ReleaseOutbox возвращает взятые, но не опубликованные события в очередь приёмника к at
+func (r *OutboxRepo) ReleaseOutbox(ctx context.Context, sink string, ids []int64, at time.Time) error {
+	_, err := conn(ctx, r.pool).Exec(ctx, sqlReleaseOutbox, sink, ids, at)
+	return err
+}
+
+// MarkOutboxDispatched отмечает опубликованными события, которые приняли
+// или окончательно отклонили все приёмники sinks. Возвращает число отмеченных
+func (r *OutboxRepo) MarkOutboxDispatched(ctx context.Context, sinks []string, at time.Time) (int64, error) {
+	tag, err := conn(ctx, r.pool).Exec(ctx, sqlMarkOutboxDispatched, sinks, at)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteDispatchedOutbox удаляет события, опубликованные раньше before, вместе
+// с их попытками. Возвращает число удалённых
+func (r *OutboxRepo) DeleteDispatchedOutbox(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := conn(ctx, r.pool).Exec(ctx, sqlDeleteDispatchedOutbox, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
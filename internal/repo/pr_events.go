@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// Типы событий в журнале pr_events
+const (
+	EventCreated         = "created"
+	EventAssigned        = "assigned"
+	EventUnassigned      = "unassigned"
+	EventReassigned      = "reassigned"
+	EventMerged          = "merged"
+	EventActivityChanged = "activity_changed"
+	EventReviewed        = "reviewed"
+	EventReady           = "ready"
+	EventClosed          = "closed"
+	EventReopened        = "reopened"
+	EventDrafted         = "drafted"
+)
+
+const (
+	// Событие пишется в журнал и в outbox одним запросом. В outbox попадает снимок PR
+	// на момент события: статус и ревьюверы, назначенные к этому моменту транзакции
+	sqlInsertPREvent = `
+		WITH ev AS (
+			INSERT INTO pr_events (pull_request_id, event_type, actor, user_id, from_user_id, to_user_id, reason, verdict)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''))
+			RETURNING event_id, pull_request_id, event_type, actor, user_id, from_user_id, to_user_id, reason, verdict, created_at
+		)
+		INSERT INTO outbox (event_type, aggregate_id, payload, created_at)
+		SELECT ev.event_type, ev.pull_request_id, jsonb_strip_nulls(jsonb_build_object(
+			'event_id', ev.event_id,
+			'type', ev.event_type,
+			'pull_request_id', ev.pull_request_id,
+			'pull_request_name', pr.pull_request_name,
+			'author_id', pr.author_id,
+			'status', pr.status,
+			'assigned_reviewers', COALESCE((
+				SELECT jsonb_agg(r.reviewer_id ORDER BY r.reviewer_id)
+				FROM pr_reviewers r
+				WHERE r.pull_request_id = ev.pull_request_id
+			), '[]'::jsonb),
+			'actor', ev.actor,
+			'user_id', ev.user_id,
+			'from_user_id', ev.from_user_id,
+			'to_user_id', ev.to_user_id,
+			'reason', ev.reason,
+			'verdict', ev.verdict,
+			'occurred_at', ev.created_at
+		)), ev.created_at
+		FROM ev
+		JOIN pull_requests pr ON pr.pull_request_id = ev.pull_request_id
+	`
+	sqlSelectPREvents = `
+		SELECT event_id, pull_request_id, event_type, actor,
+		       COALESCE(user_id, ''), COALESCE(from_user_id, ''), COALESCE(to_user_id, ''),
+		       COALESCE(reason, ''), COALESCE(verdict, ''), created_at
+		FROM pr_events
+		WHERE pull_request_id = $1
+		ORDER BY event_id
+	`
+)
+
+// EventMeta — кто и почему выполняет изменение; попадает в журнал событий
+type EventMeta struct {
+	Actor  string
+	Reason string
+}
+
+// PREventRow — запись журнала событий PR
+type PREventRow struct {
+	ID            int64
+	PullRequestID string
+	Type          string
+	Actor         string
+	UserID        string
+	FromUserID    string
+	ToUserID      string
+	Reason        string
+	Verdict       string
+	CreatedAt     time.Time
+}
+
+// insertEvent пишет событие в журнал и в outbox. Вызывается в транзакции изменения,
+// поэтому событие публикуется тогда и только тогда, когда изменение зафиксировано
+func insertEvent(ctx context.Context, q querier, ev PREventRow) error {
+	_, err := q.Exec(ctx, sqlInsertPREvent,
+		ev.PullRequestID, ev.Type, ev.Actor, ev.UserID, ev.FromUserID, ev.ToUserID, ev.Reason, ev.Verdict,
+	)
+	return err
+}
+
+// ListEvents возвращает журнал событий PR в порядке записи
+func (r *PRRepo) ListEvents(ctx context.Context, prID string) ([]PREventRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectPREvents, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PREventRow
+	for rows.Next() {
+		var ev PREventRow
+		if err := rows.Scan(&ev.ID, &ev.PullRequestID, &ev.Type, &ev.Actor,
+			&ev.UserID, &ev.FromUserID, &ev.ToUserID, &ev.Reason, &ev.Verdict, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
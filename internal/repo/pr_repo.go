@@ -2,42 +2,58 @@ package repo
 
 import (
 	"context"
-	"errors"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
 	sqlSelectPRsByReviewer = `
-		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status,
+		       COALESCE(r.verdict, ''), r.verdict_at
 		FROM pull_requests pr
 		JOIN pr_reviewers r ON r.pull_request_id = pr.pull_request_id
 		WHERE r.reviewer_id = $1
 		ORDER BY pr.pull_request_id
 	`
 	sqlInsertPR = `
-		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers)
-		VALUES ($1, $2, $3, 'OPEN', $4)
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, need_more_reviewers, capacity_exhausted)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	sqlSelectTeamActiveCandidatesExcluding = `
 		SELECT u.user_id
 		FROM users u
 		WHERE u.team_name = $1 AND u.is_active = true AND u.user_id <> $2
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_unavailability ua
+		      WHERE ua.user_id = u.user_id AND ua.starts_at <= NOW() AND ua.ends_at > NOW()
+		  )
 		ORDER BY u.user_id
 	`
 	sqlInsertReviewer = `
-		INSERT INTO pr_reviewers (pull_request_id, reviewer_id)
-		VALUES ($1, $2)
+		INSERT INTO pr_reviewers (pull_request_id, reviewer_id, source_team)
+		VALUES ($1, $2, $3)
 	`
 	sqlSelectPRByID = `
-		SELECT pull_request_id, pull_request_name, author_id, status, need_more_reviewers, created_at, merged_at
+		SELECT pull_request_id, pull_request_name, author_id, status, need_more_reviewers, capacity_exhausted,
+		       created_at, merged_at, closed_at
 		FROM pull_requests
 		WHERE pull_request_id = $1
 	`
+	sqlLockPR = `
+		SELECT pull_request_id
+		FROM pull_requests
+		WHERE pull_request_id = $1
+		FOR UPDATE
+	`
+	sqlLockOpenPR = `
+		SELECT pull_request_id
+		FROM pull_requests
+		WHERE pull_request_id = $1 AND status = 'OPEN'
+		FOR UPDATE
+	`
 	sqlSelectReviewersByPR = `
-		SELECT reviewer_id
+		SELECT reviewer_id, source_team, assigned_at, COALESCE(verdict, ''), verdict_at
 		FROM pr_reviewers
 		WHERE pull_request_id = $1
 		ORDER BY reviewer_id
@@ -46,16 +62,45 @@ const (
 		UPDATE pull_requests
 		SET status = 'MERGED',
 		    merged_at = COALESCE(merged_at, NOW())
-		WHERE pull_request_id = $1
+		WHERE pull_request_id = $1 AND status = 'OPEN'
+		RETURNING pull_request_id
+	`
+	sqlUpdatePRStatus = `
+		UPDATE pull_requests
+		SET status = $3,
+		    closed_at = CASE WHEN $3 = 'CLOSED' THEN NOW() END
+		WHERE pull_request_id = $1 AND status = ANY($2)
 		RETURNING pull_request_id
 	`
 	sqlReplaceReviewer = `
 		DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND reviewer_id = $2;
 	`
+	// Блокировка PR FOR SHARE упорядочивает решение с merge, который проверяет политику под FOR UPDATE
+	sqlUpdateReviewerVerdict = `
+		UPDATE pr_reviewers
+		SET verdict = $3,
+		    verdict_at = NOW()
+		WHERE pull_request_id = $1 AND reviewer_id = $2
+		  AND EXISTS (
+		      SELECT 1 FROM pull_requests
+		      WHERE pull_request_id = $1 AND status = 'OPEN'
+		      FOR SHARE
+		  )
+	`
+	sqlUpdatePRNeedMore = `
+		UPDATE pull_requests
+		SET need_more_reviewers = $2,
+		    capacity_exhausted = $3
+		WHERE pull_request_id = $1
+	`
 	sqlSelectTeamActiveCandidates = `
 		SELECT u.user_id
 		FROM users u
 		WHERE u.team_name = $1 AND u.is_active = true
+		  AND NOT EXISTS (
+		      SELECT 1 FROM user_unavailability ua
+		      WHERE ua.user_id = u.user_id AND ua.starts_at <= NOW() AND ua.ends_at > NOW()
+		  )
 		ORDER BY u.user_id
 	`
 	sqlSelectUserTeamByID = `
@@ -63,19 +108,41 @@ const (
 		FROM users
 		WHERE user_id = $1
 	`
-	sqlSelectReviewerStats = `
-		SELECT r.reviewer_id, COUNT(*) AS total_assigned
+	sqlSelectCapacityExhausted = `
+		SELECT pull_request_id
+		FROM pull_requests
+		WHERE status = 'OPEN' AND capacity_exhausted
+		ORDER BY created_at, pull_request_id
+	`
+	sqlSelectOpenUnderReviewed = `
+		SELECT pr.pull_request_id
+		FROM pull_requests pr
+		JOIN users a ON a.user_id = pr.author_id
+		LEFT JOIN team_settings ts ON ts.team_name = a.team_name
+		WHERE pr.status = 'OPEN'
+		  AND (pr.need_more_reviewers
+		       OR (SELECT COUNT(*) FROM pr_reviewers r WHERE r.pull_request_id = pr.pull_request_id)
+		          < COALESCE(ts.min_reviewers, $1))
+		ORDER BY pr.created_at, pr.pull_request_id
+	`
+	sqlSelectOpenReviewCounts = `
+		SELECT r.reviewer_id, COUNT(*) AS open_reviews
 		FROM pr_reviewers r
+		JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+		WHERE pr.status = 'OPEN' AND r.reviewer_id = ANY($1)
 		GROUP BY r.reviewer_id
-		ORDER BY r.reviewer_id
 	`
 )
 
+// PRShortRow — PR, на который назначен ревьювер, и его решение по нему.
+// Пустой Verdict означает, что ревью ещё не отправлено
 type PRShortRow struct {
-	ID       string
-	Name     string
-	AuthorID string
-	Status   string
+	ID        string
+	Name      string
+	AuthorID  string
+	Status    string
+	Verdict   string
+	VerdictAt *time.Time
 }
 
 type PRRepo struct {
@@ -87,7 +154,7 @@ func NewPRRepo(pool *pgxpool.Pool) *PRRepo {
 }
 
 func (r *PRRepo) GetShortByReviewer(ctx context.Context, userID string) ([]PRShortRow, error) {
-	rows, err := r.pool.Query(ctx, sqlSelectPRsByReviewer, userID)
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectPRsByReviewer, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +162,7 @@ func (r *PRRepo) GetShortByReviewer(ctx context.Context, userID string) ([]PRSho
 	var out []PRShortRow
 	for rows.Next() {
 		var row PRShortRow
-		if err := rows.Scan(&row.ID, &row.Name, &row.AuthorID, &row.Status); err != nil {
+		if err := rows.Scan(&row.ID, &row.Name, &row.AuthorID, &row.Status, &row.Verdict, &row.VerdictAt); err != nil {
 			return nil, err
 		}
 		out = append(out, row)
@@ -109,68 +176,207 @@ type PRFull struct {
 	AuthorID          string
 	Status            string
 	NeedMoreReviewers bool
+	CapacityExhausted bool
 	Assigned          []string
+	Reviewers         []Assignment
 	CreatedAt         *time.Time
 	MergedAt          *time.Time
+	ClosedAt          *time.Time
+}
+
+// Coverage — флаги недобора ревьюверов на PR. CapacityExhausted ставится вместе
+// с NeedMore, когда недобор вызван лимитами max_open_reviews кандидатов
+type Coverage struct {
+	NeedMore          bool
+	CapacityExhausted bool
+}
+
+// Assignment — назначение ревьювера с командой, из которой он был взят,
+// временем назначения и его решением по PR (пустой Verdict — ревью ещё не отправлено)
+type Assignment struct {
+	ReviewerID string
+	SourceTeam string
+	AssignedAt *time.Time
+	Verdict    string
+	VerdictAt  *time.Time
 }
 
 // CreatePROpenWithAssigned создаёт PR и назначает переданных ревьюеров
-func (r *PRRepo) CreatePROpenWithAssigned(ctx context.Context, id, name, author string, needMore bool, reviewers []string) error {
-	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+func (r *PRRepo) CreatePROpenWithAssigned(ctx context.Context, id, name, author string, cov Coverage, reviewers []Assignment, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	if _, err := tx.Exec(ctx, sqlInsertPR, id, name, author, needMore); err != nil {
+	if _, err := tx.Exec(ctx, sqlInsertPR, id, name, author, "OPEN", cov.NeedMore, cov.CapacityExhausted); err != nil {
+		return mapUniqueViolation(err)
+	}
+	if err := insertEvent(ctx, tx, PREventRow{PullRequestID: id, Type: EventCreated, Actor: meta.Actor, UserID: author, Reason: meta.Reason}); err != nil {
 		return err
 	}
+	if err := assignReviewers(ctx, tx, id, reviewers, meta); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CreatePRDraft создаёт PR в статусе DRAFT без ревьюверов
+func (r *PRRepo) CreatePRDraft(ctx context.Context, id, name, author string, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, sqlInsertPR, id, name, author, "DRAFT", false, false); err != nil {
+		return mapUniqueViolation(err)
+	}
+	if err := insertEvent(ctx, tx, PREventRow{PullRequestID: id, Type: EventCreated, Actor: meta.Actor, UserID: author, Reason: meta.Reason}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// assignReviewers вставляет назначения и пишет по событию assigned на каждое
+func assignReviewers(ctx context.Context, q querier, prID string, reviewers []Assignment, meta EventMeta) error {
 	for _, rv := range reviewers {
-		if _, err := tx.Exec(ctx, sqlInsertReviewer, id, rv); err != nil {
+		if _, err := q.Exec(ctx, sqlInsertReviewer, prID, rv.ReviewerID, rv.SourceTeam); err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, q, PREventRow{PullRequestID: prID, Type: EventAssigned, Actor: meta.Actor, UserID: rv.ReviewerID, Reason: meta.Reason}); err != nil {
 			return err
 		}
 	}
-	return tx.Commit(ctx)
+	return nil
 }
 
 func (r *PRRepo) GetPR(ctx context.Context, id string) (PRFull, error) {
 	var pr PRFull
-	if err := r.pool.QueryRow(ctx, sqlSelectPRByID, id).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.NeedMoreReviewers, &pr.CreatedAt, &pr.MergedAt); err != nil {
+	if err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectPRByID, id).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &pr.NeedMoreReviewers, &pr.CapacityExhausted, &pr.CreatedAt, &pr.MergedAt, &pr.ClosedAt); err != nil {
 		return PRFull{}, err
 	}
-	rows, err := r.pool.Query(ctx, sqlSelectReviewersByPR, id)
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectReviewersByPR, id)
 	if err != nil {
 		return PRFull{}, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var rv string
-		if err := rows.Scan(&rv); err != nil {
+		var rv Assignment
+		if err := rows.Scan(&rv.ReviewerID, &rv.SourceTeam, &rv.AssignedAt, &rv.Verdict, &rv.VerdictAt); err != nil {
 			return PRFull{}, err
 		}
-		pr.Assigned = append(pr.Assigned, rv)
+		pr.Assigned = append(pr.Assigned, rv.ReviewerID)
+		pr.Reviewers = append(pr.Reviewers, rv)
 	}
 	return pr, rows.Err()
 }
 
-func (r *PRRepo) MarkMerged(ctx context.Context, id string) error {
+// GetPRForUpdate блокирует строку PR до конца транзакции (TxManager.WithinTx) и
+// возвращает PR; вне транзакции блокировка снимается сразу. Если PR нет, возвращает pgx.ErrNoRows
+func (r *PRRepo) GetPRForUpdate(ctx context.Context, id string) (PRFull, error) {
 	var got string
-	if err := r.pool.QueryRow(ctx, sqlUpdatePRMerged, id).Scan(&got); err != nil {
+	if err := conn(ctx, r.pool).QueryRow(ctx, sqlLockPR, id).Scan(&got); err != nil {
+		return PRFull{}, err
+	}
+	return r.GetPR(ctx, id)
+}
+
+// MarkMerged переводит OPEN PR в MERGED. Если PR не OPEN, возвращает pgx.ErrNoRows
+func (r *PRRepo) MarkMerged(ctx context.Context, id string, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var got string
+	if err := tx.QueryRow(ctx, sqlUpdatePRMerged, id).Scan(&got); err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, PREventRow{PullRequestID: id, Type: EventMerged, Actor: meta.Actor, Reason: meta.Reason}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkReady переводит DRAFT PR в OPEN и назначает ревьюверов
+func (r *PRRepo) MarkReady(ctx context.Context, id string, reviewers []Assignment, cov Coverage, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := updateStatus(ctx, tx, id, []string{"DRAFT"}, "OPEN", PREventRow{Type: EventReady, Actor: meta.Actor, Reason: meta.Reason}); err != nil {
+		return err
+	}
+	if err := assignReviewers(ctx, tx, id, reviewers, meta); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, sqlUpdatePRNeedMore, id, cov.NeedMore, cov.CapacityExhausted); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MarkClosed закрывает DRAFT или OPEN PR без merge. Назначения сохраняются
+func (r *PRRepo) MarkClosed(ctx context.Context, id string, meta EventMeta) error {
+	return r.transition(ctx, id, []string{"DRAFT", "OPEN"}, "CLOSED", PREventRow{Type: EventClosed, Actor: meta.Actor, Reason: meta.Reason})
+}
+
+// Reopen возвращает CLOSED PR в OPEN
+func (r *PRRepo) Reopen(ctx context.Context, id string, meta EventMeta) error {
+	return r.transition(ctx, id, []string{"CLOSED"}, "OPEN", PREventRow{Type: EventReopened, Actor: meta.Actor, Reason: meta.Reason})
+}
+
+// ReopenDraft возвращает CLOSED PR в черновик
+func (r *PRRepo) ReopenDraft(ctx context.Context, id string, meta EventMeta) error {
+	return r.transition(ctx, id, []string{"CLOSED"}, "DRAFT", PREventRow{Type: EventReopened, Actor: meta.Actor, Reason: meta.Reason})
+}
+
+// MarkDraft возвращает OPEN PR в черновик. Назначения сохраняются,
+// но черновик не считается нагрузкой ревьюверов
+func (r *PRRepo) MarkDraft(ctx context.Context, id string, meta EventMeta) error {
+	return r.transition(ctx, id, []string{"OPEN"}, "DRAFT", PREventRow{Type: EventDrafted, Actor: meta.Actor, Reason: meta.Reason})
+}
+
+func (r *PRRepo) transition(ctx context.Context, id string, from []string, to string, ev PREventRow) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := updateStatus(ctx, tx, id, from, to, ev); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// updateStatus меняет статус PR, если текущий статус входит в from, и пишет событие ev.
+// Если PR нет или его статус другой, возвращает pgx.ErrNoRows
+func updateStatus(ctx context.Context, q querier, id string, from []string, to string, ev PREventRow) error {
+	var got string
+	if err := q.QueryRow(ctx, sqlUpdatePRStatus, id, from, to).Scan(&got); err != nil {
+		return err
+	}
+	ev.PullRequestID = id
+	return insertEvent(ctx, q, ev)
 }
 
 func (r *PRRepo) GetUserTeam(ctx context.Context, userID string) (string, error) {
 	var team string
-	if err := r.pool.QueryRow(ctx, sqlSelectUserTeamByID, userID).Scan(&team); err != nil {
+	if err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectUserTeamByID, userID).Scan(&team); err != nil {
 		return "", err
 	}
 	return team, nil
 }
 
+// GetActiveCandidatesFromTeamExcluding возвращает активных участников команды, кроме
+// excludeUserID, у которых сейчас нет периода недоступности
 func (r *PRRepo) GetActiveCandidatesFromTeamExcluding(ctx context.Context, teamName, excludeUserID string) ([]string, error) {
-	rows, err := r.pool.Query(ctx, sqlSelectTeamActiveCandidatesExcluding, teamName, excludeUserID)
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectTeamActiveCandidatesExcluding, teamName, excludeUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -186,8 +392,9 @@ func (r *PRRepo) GetActiveCandidatesFromTeamExcluding(ctx context.Context, teamN
 	return ids, rows.Err()
 }
 
-func (r *PRRepo) ReplaceReviewer(ctx context.Context, prID, oldReviewer, newReviewer string) error {
-	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+// ReplaceReviewer заменяет ревьювера и обновляет флаги недобора
+func (r *PRRepo) ReplaceReviewer(ctx context.Context, prID, oldReviewer string, newReviewer Assignment, cov Coverage, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -198,35 +405,160 @@ func (r *PRRepo) ReplaceReviewer(ctx context.Context, prID, oldReviewer, newRevi
 		return err
 	}
 	if ct.RowsAffected() == 0 {
-		return errors.New("not assigned")
+		return ErrNotAssigned
+	}
+	if _, err := tx.Exec(ctx, sqlInsertReviewer, prID, newReviewer.ReviewerID, newReviewer.SourceTeam); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, sqlUpdatePRNeedMore, prID, cov.NeedMore, cov.CapacityExhausted); err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, PREventRow{
+		PullRequestID: prID,
+		Type:          EventReassigned,
+		Actor:         meta.Actor,
+		FromUserID:    oldReviewer,
+		ToUserID:      newReviewer.ReviewerID,
+		Reason:        meta.Reason,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetVerdict сохраняет решение ревьювера по OPEN PR. Повторный вызов перезаписывает
+// предыдущее решение и его время. Если ревьювер не назначен или PR уже не OPEN,
+// возвращает ErrNotAssigned
+func (r *PRRepo) SetVerdict(ctx context.Context, prID, reviewerID, verdict string, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, sqlUpdateReviewerVerdict, prID, reviewerID, verdict)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotAssigned
+	}
+	if err := insertEvent(ctx, tx, PREventRow{
+		PullRequestID: prID,
+		Type:          EventReviewed,
+		Actor:         meta.Actor,
+		UserID:        reviewerID,
+		Reason:        meta.Reason,
+		Verdict:       verdict,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveReviewer снимает ревьювера с PR без замены и обновляет флаги недобора
+func (r *PRRepo) RemoveReviewer(ctx context.Context, prID, reviewerID string, cov Coverage, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, sqlReplaceReviewer, prID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrNotAssigned
+	}
+	if _, err := tx.Exec(ctx, sqlUpdatePRNeedMore, prID, cov.NeedMore, cov.CapacityExhausted); err != nil {
+		return err
+	}
+	if err := insertEvent(ctx, tx, PREventRow{PullRequestID: prID, Type: EventUnassigned, Actor: meta.Actor, UserID: reviewerID, Reason: meta.Reason}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AddReviewers назначает дополнительных ревьюверов и обновляет флаги недобора.
+// Если PR не OPEN, возвращает pgx.ErrNoRows и ничего не меняет
+func (r *PRRepo) AddReviewers(ctx context.Context, prID string, reviewers []Assignment, cov Coverage, meta EventMeta) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var got string
+	if err := tx.QueryRow(ctx, sqlLockOpenPR, prID).Scan(&got); err != nil {
+		return err
+	}
+
+	if err := assignReviewers(ctx, tx, prID, reviewers, meta); err != nil {
+		return err
 	}
-	if _, err := tx.Exec(ctx, sqlInsertReviewer, prID, newReviewer); err != nil {
+	if _, err := tx.Exec(ctx, sqlUpdatePRNeedMore, prID, cov.NeedMore, cov.CapacityExhausted); err != nil {
 		return err
 	}
 	return tx.Commit(ctx)
 }
 
-// ReviewerStatRow описывает простую статистику назначений ревьюеров.
-type ReviewerStatRow struct {
-	ReviewerID    string
-	TotalAssigned int64
+// ListOpenUnderReviewed возвращает OPEN PR с флагом need_more_reviewers или с числом
+// ревьюверов меньше min_reviewers команды автора (defaultMin — для команд без настроек)
+func (r *PRRepo) ListOpenUnderReviewed(ctx context.Context, defaultMin int) ([]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectOpenUnderReviewed, defaultMin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListCapacityExhausted возвращает OPEN PR с флагом capacity_exhausted
+func (r *PRRepo) ListCapacityExhausted(ctx context.Context) ([]string, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectCapacityExhausted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
-// GetReviewerStats возвращает статистику назначений по ревьюверам.
-func (r *PRRepo) GetReviewerStats(ctx context.Context) ([]ReviewerStatRow, error) {
-	rows, err := r.pool.Query(ctx, sqlSelectReviewerStats)
+// GetOpenReviewCounts возвращает число OPEN PR, на которые назначен каждый из
+// переданных пользователей. Пользователи без открытых ревью в результат не попадают
+func (r *PRRepo) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int64, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectOpenReviewCounts, userIDs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var stats []ReviewerStatRow
+	counts := make(map[string]int64, len(userIDs))
 	for rows.Next() {
-		var s ReviewerStatRow
-		if err := rows.Scan(&s.ReviewerID, &s.TotalAssigned); err != nil {
+		var id string
+		var n int64
+		if err := rows.Scan(&id, &n); err != nil {
 			return nil, err
 		}
-		stats = append(stats, s)
+		counts[id] = n
 	}
-	return stats, rows.Err()
-}
\ No newline at end of file
+	return counts, rows.Err()
+}
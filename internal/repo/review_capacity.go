@@ -0,0 +1,73 @@
+package repo
+
+import "context"
+
+const (
+	sqlSelectReviewCapacity = `
+		SELECT u.user_id, u.max_open_reviews,
+		       COALESCE(u.max_open_reviews, ts.default_max_open_reviews),
+		       (SELECT COUNT(*)
+		        FROM pr_reviewers r
+		        JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+		        WHERE r.reviewer_id = u.user_id AND pr.status = 'OPEN')
+		FROM users u
+		LEFT JOIN team_settings ts ON ts.team_name = u.team_name
+		WHERE u.user_id = ANY($1)
+	`
+	sqlUpdateMaxOpenReviews = `
+		UPDATE users
+		SET max_open_reviews = $2
+		WHERE user_id = $1
+		RETURNING user_id
+	`
+)
+
+// ReviewCapacityRow — лимит одновременных ревью пользователя и его текущая загрузка.
+// MaxOpenReviews — собственный лимит пользователя, Limit — действующий с учётом
+// default_max_open_reviews его команды. nil — без ограничения
+type ReviewCapacityRow struct {
+	UserID         string
+	MaxOpenReviews *int
+	Limit          *int
+	OpenReviews    int
+}
+
+// AtCapacity сообщает, достиг ли пользователь своего лимита
+func (r ReviewCapacityRow) AtCapacity() bool {
+	return r.Limit != nil && r.OpenReviews >= *r.Limit
+}
+
+// ListReviewCapacity возвращает лимиты и число OPEN PR на ревью у переданных пользователей
+func (r *PRRepo) ListReviewCapacity(ctx context.Context, userIDs []string) (map[string]ReviewCapacityRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectReviewCapacity, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]ReviewCapacityRow, len(userIDs))
+	for rows.Next() {
+		var row ReviewCapacityRow
+		if err := rows.Scan(&row.UserID, &row.MaxOpenReviews, &row.Limit, &row.OpenReviews); err != nil {
+			return nil, err
+		}
+		out[row.UserID] = row
+	}
+	return out, rows.Err()
+}
+
+// GetReviewCapacity возвращает лимит и загрузку пользователя или pgx.ErrNoRows
+func (r *UserRepo) GetReviewCapacity(ctx context.Context, userID string) (ReviewCapacityRow, error) {
+	var row ReviewCapacityRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectReviewCapacity, []string{userID}).Scan(
+		&row.UserID, &row.MaxOpenReviews, &row.Limit, &row.OpenReviews,
+	)
+	return row, err
+}
+
+// UpdateMaxOpenReviews задаёт собственный лимит пользователя (nil — снять).
+// Если пользователя нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) UpdateMaxOpenReviews(ctx context.Context, userID string, limit *int) error {
+	var got string
+	return conn(ctx, r.pool).QueryRow(ctx, sqlUpdateMaxOpenReviews, userID, limit).Scan(&got)
+}
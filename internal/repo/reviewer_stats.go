@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// Группировка статистики ревьюверов
+const (
+	StatsGroupByReviewer = "reviewer"
+	StatsGroupByTeam     = "team"
+	StatsGroupByWeek     = "week"
+)
+
+// Назначения попадают в период по assigned_at, уходы с PR при переназначении —
+// по времени события reassigned. Команда — текущая команда ревьювера,
+// неделя — понедельник (UTC) в формате YYYY-MM-DD
+const sqlSelectReviewerStats = `
+	WITH assignments AS (
+		SELECT r.reviewer_id, COALESCE(u.team_name, '') AS team_name, r.assigned_at AS at,
+		       1 AS assigned,
+		       (pr.status = 'OPEN')::int AS open,
+		       (pr.status = 'MERGED')::int AS merged,
+		       0 AS away,
+		       CASE WHEN pr.status = 'MERGED'
+		            THEN EXTRACT(EPOCH FROM pr.merged_at - r.assigned_at)::double precision
+		       END AS merge_secs
+		FROM pr_reviewers r
+		JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+		LEFT JOIN users u ON u.user_id = r.reviewer_id
+		WHERE ($1::timestamptz IS NULL OR r.assigned_at >= $1)
+		  AND ($2::timestamptz IS NULL OR r.assigned_at < $2)
+		  AND ($3::text = '' OR u.team_name = $3)
+		  AND ($4::text = '' OR pr.status = $4)
+	),
+	reassigned_away AS (
+		SELECT e.from_user_id, COALESCE(u.team_name, ''), e.created_at,
+		       0, 0, 0, 1, NULL::double precision
+		FROM pr_events e
+		JOIN pull_requests pr ON pr.pull_request_id = e.pull_request_id
+		LEFT JOIN users u ON u.user_id = e.from_user_id
+		WHERE e.event_type = 'reassigned'
+		  AND ($1::timestamptz IS NULL OR e.created_at >= $1)
+		  AND ($2::timestamptz IS NULL OR e.created_at < $2)
+		  AND ($3::text = '' OR u.team_name = $3)
+		  AND ($4::text = '' OR pr.status = $4)
+	),
+	keyed AS (
+		SELECT CASE $5::text
+		           WHEN 'team' THEN team_name
+		           WHEN 'week' THEN to_char(date_trunc('week', at AT TIME ZONE 'UTC'), 'YYYY-MM-DD')
+		           ELSE reviewer_id
+		       END AS group_key,
+		       assigned, open, merged, away, merge_secs
+		FROM (SELECT * FROM assignments UNION ALL SELECT * FROM reassigned_away) s
+	)
+	SELECT group_key, SUM(assigned), SUM(open), SUM(merged), SUM(away),
+	       percentile_cont(0.5) WITHIN GROUP (ORDER BY merge_secs)
+	FROM keyed
+	GROUP BY group_key
+	ORDER BY group_key
+`
+
+// StatsFilter ограничивает статистику ревьюверов. Пустые поля не ограничивают
+// выборку; период — полуинтервал [From, To)
+type StatsFilter struct {
+	From     *time.Time
+	To       *time.Time
+	TeamName string
+	Status   string
+	GroupBy  string
+}
+
+// ReviewerStatRow — статистика одной группы. Key — reviewer_id, имя команды или
+// начало недели, в зависимости от StatsFilter.GroupBy. MedianTimeToMerge — медиана
+// времени от назначения до merge, nil — смерженных PR в группе нет
+type ReviewerStatRow struct {
+	Key               string
+	TotalAssigned     int64
+	OpenReviews       int64
+	Merged            int64
+	ReassignedAway    int64
+	MedianTimeToMerge *time.Duration
+}
+
+// GetReviewerStats возвращает статистику назначений ревьюверов по фильтру
+func (r *PRRepo) GetReviewerStats(ctx context.Context, f StatsFilter) ([]ReviewerStatRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectReviewerStats, f.From, f.To, f.TeamName, f.Status, f.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ReviewerStatRow
+	for rows.Next() {
+		var s ReviewerStatRow
+		var medianSecs *float64
+		if err := rows.Scan(&s.Key, &s.TotalAssigned, &s.OpenReviews, &s.Merged, &s.ReassignedAway, &medianSecs); err != nil {
+			return nil, err
+		}
+		if medianSecs != nil {
+			d := time.Duration(*medianSecs * float64(time.Second)).Round(time.Second)
+			s.MedianTimeToMerge = &d
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
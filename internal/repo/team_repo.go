@@ -34,6 +34,32 @@ const (
 		WHERE team_name = $1
 		ORDER BY user_id
 	`
+	sqlSelectTeamSettings = `
+		SELECT team_name, min_reviewers, max_reviewers, default_max_open_reviews
+		FROM team_settings
+		WHERE team_name = $1
+	`
+	sqlUpsertTeamSettings = `
+		INSERT INTO team_settings (team_name, min_reviewers, max_reviewers, default_max_open_reviews)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (team_name) DO UPDATE
+		SET min_reviewers = EXCLUDED.min_reviewers,
+		    max_reviewers = EXCLUDED.max_reviewers,
+		    default_max_open_reviews = EXCLUDED.default_max_open_reviews
+	`
+	sqlSelectTeamFallbacks = `
+		SELECT fallback_team
+		FROM team_fallbacks
+		WHERE team_name = $1
+		ORDER BY position
+	`
+	sqlDeleteTeamFallbacks = `
+		DELETE FROM team_fallbacks WHERE team_name = $1
+	`
+	sqlInsertTeamFallback = `
+		INSERT INTO team_fallbacks (team_name, fallback_team, position)
+		VALUES ($1, $2, $3)
+	`
 )
 
 type TeamRepo struct {
@@ -49,7 +75,7 @@ func (r *TeamRepo) CreateTeamWithMembers(ctx context.Context, teamName string, m
 	Username string
 	IsActive bool
 }) error {
-	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := conn(ctx, r.pool).Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -58,7 +84,7 @@ func (r *TeamRepo) CreateTeamWithMembers(ctx context.Context, teamName string, m
 	// Создаем команду
 	_, err = tx.Exec(ctx, sqlInsertTeam, teamName)
 	if err != nil {
-		return err
+		return mapUniqueViolation(err)
 	}
 	
 	for _, m := range members {
@@ -78,7 +104,7 @@ func (r *TeamRepo) CreateTeamWithMembers(ctx context.Context, teamName string, m
 
 func (r *TeamRepo) TeamExists(ctx context.Context, teamName string) (bool, error) {
 	var name string
-	err := r.pool.QueryRow(ctx, sqlSelectTeamByName, teamName).Scan(&name)
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectTeamByName, teamName).Scan(&name)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return false, nil
@@ -95,7 +121,7 @@ type TeamMemberRow struct {
 }
 
 func (r *TeamRepo) GetTeamWithMembers(ctx context.Context, teamName string) ([]TeamMemberRow, error) {
-	rows, err := r.pool.Query(ctx, sqlSelectMembersByTeam, teamName)
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectMembersByTeam, teamName)
 	if err != nil {
 		return nil, err
 	}
@@ -110,4 +136,59 @@ func (r *TeamRepo) GetTeamWithMembers(ctx context.Context, teamName string) ([]T
 		result = append(result, row)
 	}
 	return result, rows.Err()
-}
\ No newline at end of file
+}
+
+type TeamSettingsRow struct {
+	TeamName              string
+	MinReviewers          int
+	MaxReviewers          int
+	FallbackTeams         []string // в порядке приоритета
+	DefaultMaxOpenReviews *int     // nil — без ограничения
+}
+
+// GetSettings возвращает настройки команды или pgx.ErrNoRows, если они не заданы
+func (r *TeamRepo) GetSettings(ctx context.Context, teamName string) (TeamSettingsRow, error) {
+	var row TeamSettingsRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectTeamSettings, teamName).Scan(
+		&row.TeamName, &row.MinReviewers, &row.MaxReviewers, &row.DefaultMaxOpenReviews,
+	)
+	if err != nil {
+		return TeamSettingsRow{}, err
+	}
+
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectTeamFallbacks, teamName)
+	if err != nil {
+		return TeamSettingsRow{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var fb string
+		if err := rows.Scan(&fb); err != nil {
+			return TeamSettingsRow{}, err
+		}
+		row.FallbackTeams = append(row.FallbackTeams, fb)
+	}
+	return row, rows.Err()
+}
+
+// UpsertSettings сохраняет настройки и полностью заменяет список резервных команд
+func (r *TeamRepo) UpsertSettings(ctx context.Context, row TeamSettingsRow) error {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, sqlUpsertTeamSettings, row.TeamName, row.MinReviewers, row.MaxReviewers, row.DefaultMaxOpenReviews); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, sqlDeleteTeamFallbacks, row.TeamName); err != nil {
+		return err
+	}
+	for i, fb := range row.FallbackTeams {
+		if _, err := tx.Exec(ctx, sqlInsertTeamFallback, row.TeamName, fb, i+1); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	sqlInsertUnavailability = `
+		INSERT INTO user_unavailability (user_id, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING id, user_id, starts_at, ends_at, COALESCE(reason, ''), COALESCE(source, ''), COALESCE(external_uid, ''), created_at
+	`
+	sqlUpsertExternalUnavailability = `
+		INSERT INTO user_unavailability (user_id, starts_at, ends_at, reason, source, external_uid)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+		ON CONFLICT (user_id, source, external_uid) WHERE external_uid IS NOT NULL
+		DO UPDATE SET starts_at = EXCLUDED.starts_at,
+		              ends_at = EXCLUDED.ends_at,
+		              reason = EXCLUDED.reason
+		WHERE (user_unavailability.starts_at, user_unavailability.ends_at, user_unavailability.reason)
+		      IS DISTINCT FROM (EXCLUDED.starts_at, EXCLUDED.ends_at, EXCLUDED.reason)
+		RETURNING (xmax = 0) AS inserted
+	`
+	sqlSelectUnavailability = `
+		SELECT id, user_id, starts_at, ends_at, COALESCE(reason, ''), COALESCE(source, ''), COALESCE(external_uid, ''), created_at
+		FROM user_unavailability
+		WHERE user_id = $1 AND ends_at > $2
+		ORDER BY starts_at, id
+	`
+	sqlDeleteUnavailability = `
+		DELETE FROM user_unavailability
+		WHERE id = $1 AND user_id = $2
+		RETURNING id
+	`
+	sqlSelectAllUsers = `
+		SELECT user_id, username, team_name, is_active
+		FROM users
+		ORDER BY user_id
+	`
+)
+
+// UnavailabilityRow — период [StartsAt, EndsAt), когда пользователь не назначается ревьювером.
+// Source и ExternalUID заполнены у периодов, импортированных из календаря
+type UnavailabilityRow struct {
+	ID          int64
+	UserID      string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Reason      string
+	Source      string
+	ExternalUID string
+	CreatedAt   time.Time
+}
+
+// UpsertResult — что сделал UpsertExternalUnavailability
+type UpsertResult int
+
+const (
+	UpsertUnchanged UpsertResult = iota
+	UpsertCreated
+	UpsertUpdated
+)
+
+func (r *UserRepo) AddUnavailability(ctx context.Context, row UnavailabilityRow) (UnavailabilityRow, error) {
+	var out UnavailabilityRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlInsertUnavailability, row.UserID, row.StartsAt, row.EndsAt, row.Reason).Scan(
+		&out.ID, &out.UserID, &out.StartsAt, &out.EndsAt, &out.Reason, &out.Source, &out.ExternalUID, &out.CreatedAt,
+	)
+	return out, err
+}
+
+// UpsertExternalUnavailability создаёт или обновляет период, импортированный
+// из внешнего источника; ключ — (user_id, source, external_uid)
+func (r *UserRepo) UpsertExternalUnavailability(ctx context.Context, row UnavailabilityRow) (UpsertResult, error) {
+	var inserted bool
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlUpsertExternalUnavailability,
+		row.UserID, row.StartsAt, row.EndsAt, row.Reason, row.Source, row.ExternalUID,
+	).Scan(&inserted)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Конфликт без изменений: DO UPDATE ... WHERE не вернул строку
+		return UpsertUnchanged, nil
+	case err != nil:
+		return UpsertUnchanged, err
+	case inserted:
+		return UpsertCreated, nil
+	default:
+		return UpsertUpdated, nil
+	}
+}
+
+// ListUsers возвращает всех пользователей
+func (r *UserRepo) ListUsers(ctx context.Context) ([]UserRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectAllUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UserRow
+	for rows.Next() {
+		var row UserRow
+		if err := rows.Scan(&row.UserID, &row.Username, &row.TeamName, &row.IsActive); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// ListUnavailability возвращает периоды пользователя, которые заканчиваются позже since
+func (r *UserRepo) ListUnavailability(ctx context.Context, userID string, since time.Time) ([]UnavailabilityRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectUnavailability, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []UnavailabilityRow
+	for rows.Next() {
+		var row UnavailabilityRow
+		if err := rows.Scan(&row.ID, &row.UserID, &row.StartsAt, &row.EndsAt, &row.Reason, &row.Source, &row.ExternalUID, &row.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// DeleteUnavailability удаляет период пользователя. Если его нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) DeleteUnavailability(ctx context.Context, userID string, id int64) error {
+	var got int64
+	return conn(ctx, r.pool).QueryRow(ctx, sqlDeleteUnavailability, id, userID).Scan(&got)
+}
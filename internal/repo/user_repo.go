@@ -13,6 +13,19 @@ const (
 		WHERE user_id = $1
 		RETURNING user_id, username, team_name, is_active
 	`
+	sqlSelectUserIsActiveForUpdate = `
+		SELECT is_active
+		FROM users
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+	sqlSelectOpenReviewsOfUser = `
+		SELECT r.pull_request_id
+		FROM pr_reviewers r
+		JOIN pull_requests pr ON pr.pull_request_id = r.pull_request_id
+		WHERE r.reviewer_id = $1 AND pr.status = 'OPEN'
+		ORDER BY r.pull_request_id
+	`
 	sqlSelectUserByID = `
 		SELECT user_id, username, team_name, is_active
 		FROM users
@@ -36,17 +49,67 @@ func NewUserRepo(pool *pgxpool.Pool) *UserRepo {
 	return &UserRepo{pool: pool}
 }
 
-func (r *UserRepo) UpdateIsActive(ctx context.Context, userID string, isActive bool) (UserRow, error) {
+// UpdateIsActive меняет активность пользователя. Если значение изменилось, на всех
+// OPEN PR, где он ревьювер, в журнал и outbox пишется событие activity_changed
+func (r *UserRepo) UpdateIsActive(ctx context.Context, userID string, isActive bool, meta EventMeta) (UserRow, error) {
+	tx, err := conn(ctx, r.pool).Begin(ctx)
+	if err != nil {
+		return UserRow{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var wasActive bool
+	if err := tx.QueryRow(ctx, sqlSelectUserIsActiveForUpdate, userID).Scan(&wasActive); err != nil {
+		return UserRow{}, err
+	}
 	var row UserRow
-	err := r.pool.QueryRow(ctx, sqlUpdateUserIsActive, userID, isActive).Scan(
+	if err := tx.QueryRow(ctx, sqlUpdateUserIsActive, userID, isActive).Scan(
 		&row.UserID, &row.Username, &row.TeamName, &row.IsActive,
-	)
-	return row, err
+	); err != nil {
+		return UserRow{}, err
+	}
+	if wasActive != isActive {
+		prIDs, err := openReviewsOf(ctx, tx, userID)
+		if err != nil {
+			return UserRow{}, err
+		}
+		for _, prID := range prIDs {
+			if err := insertEvent(ctx, tx, PREventRow{
+				PullRequestID: prID,
+				Type:          EventActivityChanged,
+				Actor:         meta.Actor,
+				UserID:        userID,
+				Reason:        meta.Reason,
+			}); err != nil {
+				return UserRow{}, err
+			}
+		}
+	}
+	return row, tx.Commit(ctx)
+}
+
+// openReviewsOf возвращает OPEN PR, где пользователь назначен ревьювером
+func openReviewsOf(ctx context.Context, q querier, userID string) ([]string, error) {
+	rows, err := q.Query(ctx, sqlSelectOpenReviewsOfUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 func (r *UserRepo) GetByID(ctx context.Context, userID string) (UserRow, error) {
 	var row UserRow
-	err := r.pool.QueryRow(ctx, sqlSelectUserByID, userID).Scan(
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectUserByID, userID).Scan(
 		&row.UserID, &row.Username, &row.TeamName, &row.IsActive,
 	)
 	return row, err
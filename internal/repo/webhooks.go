@@ -0,0 +1,232 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Статусы доставки исходящего вебхука
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+const (
+	sqlInsertSubscription = `
+		INSERT INTO webhook_subscriptions (url, secret, events)
+		VALUES ($1, $2, $3)
+		RETURNING id, url, secret, events, created_at
+	`
+	sqlSelectSubscriptions = `
+		SELECT id, url, secret, events, created_at
+		FROM webhook_subscriptions
+		ORDER BY id
+	`
+	sqlDeleteSubscription = `
+		DELETE FROM webhook_subscriptions
+		WHERE id = $1
+		RETURNING id
+	`
+	// Доставка создаётся для каждой подписки, в фильтре которой есть тип события.
+	// Повторная постановка того же события подписке ничего не меняет
+	sqlEnqueueDeliveries = `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1::varchar, $2::varchar, $3::jsonb
+		FROM webhook_subscriptions
+		WHERE $2::varchar = ANY(events)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	// Выбранные доставки откладываются до $3, чтобы их не взял другой экземпляр
+	// сервиса. Если отправитель упадёт, доставка будет повторена после этого срока
+	sqlClaimDeliveries = `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $3
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, s.url, s.secret, d.event_id, d.event_type, d.payload, d.attempts
+	`
+	sqlRecordAttempt = `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+		    status = $2,
+		    last_status_code = NULLIF($3, 0),
+		    last_error = NULLIF($4, ''),
+		    next_attempt_at = $5,
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN $5 END
+		WHERE id = $1
+	`
+	sqlSelectDeliveries = `
+		SELECT id, subscription_id, event_id, event_type, payload, status, attempts,
+		       next_attempt_at, COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE ($1::bigint = 0 OR subscription_id = $1)
+		  AND ($2::varchar = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+)
+
+// SubscriptionRow — подписка на события: POST на URL с подписью секретом
+// для событий из Events
+type SubscriptionRow struct {
+	ID        int64
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// DeliveryRow — доставка одного события одной подписке и итог последней попытки
+type DeliveryRow struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// DueDelivery — доставка, выбранная для отправки, вместе с адресом и секретом подписки
+type DueDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	URL            string
+	Secret         string
+	EventID        string
+	EventType      string
+	Payload        []byte
+	// Attempts — число уже сделанных попыток
+	Attempts int
+}
+
+// DeliveryAttempt — итог попытки доставки. At — время следующей попытки для
+// pending и время доставки для delivered
+type DeliveryAttempt struct {
+	Status     string
+	StatusCode int
+	Error      string
+	At         time.Time
+}
+
+// DeliveryFilter — фильтр журнала доставок; нулевые поля не ограничивают выборку
+type DeliveryFilter struct {
+	SubscriptionID int64
+	Status         string
+	Limit          int
+}
+
+type WebhookRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepo(pool *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{pool: pool}
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, row SubscriptionRow) (SubscriptionRow, error) {
+	var out SubscriptionRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlInsertSubscription, row.URL, row.Secret, row.Events).Scan(
+		&out.ID, &out.URL, &out.Secret, &out.Events, &out.CreatedAt,
+	)
+	return out, err
+}
+
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) ([]SubscriptionRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SubscriptionRow
+	for rows.Next() {
+		var row SubscriptionRow
+		if err := rows.Scan(&row.ID, &row.URL, &row.Secret, &row.Events, &row.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSubscription удаляет подписку вместе с её журналом доставок.
+// Если подписки нет, возвращает pgx.ErrNoRows
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id int64) error {
+	var deleted int64
+	return conn(ctx, r.pool).QueryRow(ctx, sqlDeleteSubscription, id).Scan(&deleted)
+}
+
+// EnqueueDeliveries ставит событие в очередь всем подписанным на eventType.
+// Возвращает число созданных доставок; уже поставленные ранее не считаются
+func (r *WebhookRepo) EnqueueDeliveries(ctx context.Context, eventID, eventType string, payload []byte) (int, error) {
+	tag, err := conn(ctx, r.pool).Exec(ctx, sqlEnqueueDeliveries, eventID, eventType, payload)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDueDeliveries выбирает до limit доставок, время попытки которых наступило к now,
+// и откладывает их до leaseUntil
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]DueDelivery, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlClaimDeliveries, now, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventID, &d.EventType, &d.Payload, &d.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RecordAttempt записывает итог попытки доставки и увеличивает счётчик попыток
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, id int64, a DeliveryAttempt) error {
+	_, err := conn(ctx, r.pool).Exec(ctx, sqlRecordAttempt, id, a.Status, a.StatusCode, a.Error, a.At)
+	return err
+}
+
+// ListDeliveries возвращает журнал доставок, новые первыми
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, f DeliveryFilter) ([]DeliveryRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectDeliveries, f.SubscriptionID, f.Status, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DeliveryRow
+	for rows.Next() {
+		var d DeliveryRow
+		if err := rows.Scan(
+			&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,67 @@
+package repo
+
+import "context"
+
+const (
+	sqlSelectWorkingHours = `
+		SELECT user_id, time_zone,
+		       COALESCE(to_char(work_start, 'HH24:MI'), ''), COALESCE(to_char(work_end, 'HH24:MI'), '')
+		FROM users
+		WHERE user_id = ANY($1)
+	`
+	sqlUpdateWorkingHours = `
+		UPDATE users
+		SET time_zone = $2,
+		    work_start = NULLIF($3, '')::time,
+		    work_end = NULLIF($4, '')::time
+		WHERE user_id = $1
+		RETURNING user_id, time_zone,
+		          COALESCE(to_char(work_start, 'HH24:MI'), ''), COALESCE(to_char(work_end, 'HH24:MI'), '')
+	`
+)
+
+// WorkingHoursRow — часовой пояс и рабочие часы пользователя ("HH:MM").
+// Пустые WorkStart и WorkEnd означают, что рабочие часы не заданы
+type WorkingHoursRow struct {
+	UserID    string
+	TimeZone  string
+	WorkStart string
+	WorkEnd   string
+}
+
+// GetWorkingHours возвращает рабочие часы пользователя или pgx.ErrNoRows
+func (r *UserRepo) GetWorkingHours(ctx context.Context, userID string) (WorkingHoursRow, error) {
+	var row WorkingHoursRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlSelectWorkingHours, []string{userID}).Scan(
+		&row.UserID, &row.TimeZone, &row.WorkStart, &row.WorkEnd,
+	)
+	return row, err
+}
+
+// UpdateWorkingHours сохраняет рабочие часы пользователя. Если его нет, возвращает pgx.ErrNoRows
+func (r *UserRepo) UpdateWorkingHours(ctx context.Context, row WorkingHoursRow) (WorkingHoursRow, error) {
+	var out WorkingHoursRow
+	err := conn(ctx, r.pool).QueryRow(ctx, sqlUpdateWorkingHours, row.UserID, row.TimeZone, row.WorkStart, row.WorkEnd).Scan(
+		&out.UserID, &out.TimeZone, &out.WorkStart, &out.WorkEnd,
+	)
+	return out, err
+}
+
+// ListWorkingHours возвращает рабочие часы переданных пользователей по user_id
+func (r *PRRepo) ListWorkingHours(ctx context.Context, userIDs []string) (map[string]WorkingHoursRow, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, sqlSelectWorkingHours, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]WorkingHoursRow, len(userIDs))
+	for rows.Next() {
+		var row WorkingHoursRow
+		if err := rows.Scan(&row.UserID, &row.TimeZone, &row.WorkStart, &row.WorkEnd); err != nil {
+			return nil, err
+		}
+		out[row.UserID] = row
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,30 @@
+package service
+
+import "context"
+
+// SystemActor — автор изменений, которые сервис выполняет сам (фоновый добор и т.п.)
+const SystemActor = "system"
+
+// Причины изменений, которые сервис записывает в журнал событий PR
+const (
+	ReasonUserDeactivated = "user_deactivated"
+	ReasonUserActivated   = "user_activated"
+	ReasonTopUp           = "top_up"
+	ReasonForcedMerge     = "forced"
+	ReasonExternalMerge   = "external"
+)
+
+type actorCtxKey struct{}
+
+// WithActor сохраняет в ctx автора изменений для журнала событий
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext возвращает автора изменений или SystemActor, если он не задан
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorCtxKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
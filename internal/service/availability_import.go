@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/quasttyy/pr-reviewer/internal/ical"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// DefaultICalSource — имя источника для импорта без явно заданного календаря
+const DefaultICalSource = "ical"
+
+// Причины, по которым событие календаря не стало периодом недоступности
+const (
+	SkipNoUser    = "no matching user"
+	SkipRecurring = "recurring events are not supported"
+	SkipEnded     = "event has already ended"
+	SkipEmpty     = "event has no duration"
+	SkipNoUID     = "event has no UID"
+	SkipUnknownTZ = "event uses an unknown time zone"
+)
+
+// SkippedEvent — событие календаря, которое не было импортировано
+type SkippedEvent struct {
+	UID     string
+	Summary string
+	Reason  string
+}
+
+// ImportReport — итог импорта календаря. Повторный импорт того же файла
+// даёт только Unchanged
+type ImportReport struct {
+	Created   int
+	Updated   int
+	Unchanged int
+	Skipped   []SkippedEvent
+}
+
+// ImportICal превращает события (VEVENT) календаря в периоды недоступности.
+// Пользователь определяется по участникам события (ATTENDEE: CN или локальная
+// часть mailto-адреса), а если ни один не найден — по началу SUMMARY
+// («u2: отпуск», «Daniil - vacation»). Совпадение ищется по user_id и username
+// без учёта регистра. Период с тем же source и UID события обновляется, а не дублируется
+func (s *AvailabilityService) ImportICal(ctx context.Context, source string, r io.Reader) (_ ImportReport, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.ImportICal")
+	defer endSpan(span, &err)
+
+	events, err := ical.Parse(r)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	if source == "" {
+		source = DefaultICalSource
+	}
+	users, err := s.store.ListUsers(ctx)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	index := newUserIndex(users)
+	now := s.now()
+
+	var report ImportReport
+	for _, ev := range events {
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, SkippedEvent{UID: ev.UID, Summary: ev.Summary, Reason: reason})
+		}
+		switch {
+		case ev.UID == "":
+			skip(SkipNoUID)
+			continue
+		case ev.Recurring:
+			skip(SkipRecurring)
+			continue
+		case ev.UnknownTZID != "":
+			// Время прочитано как UTC и может быть сдвинуто на несколько часов
+			skip(SkipUnknownTZ)
+			continue
+		case !ev.End.After(ev.Start):
+			skip(SkipEmpty)
+			continue
+		case !ev.End.After(now):
+			skip(SkipEnded)
+			continue
+		}
+		userIDs := index.match(ev)
+		if len(userIDs) == 0 {
+			skip(SkipNoUser)
+			continue
+		}
+		for _, userID := range userIDs {
+			res, err := s.store.UpsertExternalUnavailability(ctx, repo.UnavailabilityRow{
+				UserID:      userID,
+				StartsAt:    ev.Start,
+				EndsAt:      ev.End,
+				Reason:      ev.Summary,
+				Source:      source,
+				ExternalUID: ev.UID,
+			})
+			if err != nil {
+				return report, err
+			}
+			switch res {
+			case repo.UpsertCreated:
+				report.Created++
+			case repo.UpsertUpdated:
+				report.Updated++
+			default:
+				report.Unchanged++
+			}
+		}
+	}
+	return report, nil
+}
+
+// userIndex ищет user_id по user_id или username без учёта регистра
+type userIndex map[string]string
+
+func newUserIndex(users []repo.UserRow) userIndex {
+	idx := make(userIndex, 2*len(users))
+	for _, u := range users {
+		idx[strings.ToLower(u.Username)] = u.UserID
+	}
+	// user_id важнее username, если они совпали у разных пользователей
+	for _, u := range users {
+		idx[strings.ToLower(u.UserID)] = u.UserID
+	}
+	return idx
+}
+
+func (idx userIndex) lookup(key string) (string, bool) {
+	id, ok := idx[strings.ToLower(strings.TrimSpace(key))]
+	return id, ok && key != ""
+}
+
+// match возвращает пользователей события без повторов
+func (idx userIndex) match(ev ical.Event) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	for _, a := range ev.Attendees {
+		if id, ok := idx.lookup(a.Name); ok {
+			add(id)
+			continue
+		}
+		local, _, _ := strings.Cut(a.Email, "@")
+		if id, ok := idx.lookup(local); ok {
+			add(id)
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	if id, ok := idx.lookup(summaryKey(ev.Summary)); ok {
+		add(id)
+	}
+	return out
+}
+
+// summaryKey возвращает часть SUMMARY до «:» или « - », либо весь SUMMARY
+func summaryKey(summary string) string {
+	for _, sep := range []string{":", " - "} {
+		if head, _, ok := strings.Cut(summary, sep); ok {
+			return head
+		}
+	}
+	return summary
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// AvailabilityStore хранит периоды недоступности пользователей
+type AvailabilityStore interface {
+	GetByID(ctx context.Context, userID string) (repo.UserRow, error)
+	AddUnavailability(ctx context.Context, row repo.UnavailabilityRow) (repo.UnavailabilityRow, error)
+	ListUnavailability(ctx context.Context, userID string, since time.Time) ([]repo.UnavailabilityRow, error)
+	DeleteUnavailability(ctx context.Context, userID string, id int64) error
+	ListUsers(ctx context.Context) ([]repo.UserRow, error)
+	UpsertExternalUnavailability(ctx context.Context, row repo.UnavailabilityRow) (repo.UpsertResult, error)
+	GetWorkingHours(ctx context.Context, userID string) (repo.WorkingHoursRow, error)
+	UpdateWorkingHours(ctx context.Context, row repo.WorkingHoursRow) (repo.WorkingHoursRow, error)
+	GetReviewCapacity(ctx context.Context, userID string) (repo.ReviewCapacityRow, error)
+	UpdateMaxOpenReviews(ctx context.Context, userID string, limit *int) error
+}
+
+// AvailabilityService управляет периодами, когда пользователь не назначается ревьювером.
+// Во время периода пользователь не попадает в кандидаты, после его окончания —
+// снова попадает, без изменения is_active
+type AvailabilityService struct {
+	store AvailabilityStore
+	now   func() time.Time
+}
+
+func NewAvailabilityService(store AvailabilityStore) *AvailabilityService {
+	return &AvailabilityService{store: store, now: time.Now}
+}
+
+// Add регистрирует период [startsAt, endsAt). Период должен быть непустым
+// и ещё не закончившимся
+func (s *AvailabilityService) Add(ctx context.Context, userID string, startsAt, endsAt time.Time, reason string) (_ repo.UnavailabilityRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.Add")
+	defer endSpan(span, &err)
+
+	if !endsAt.After(startsAt) || !endsAt.After(s.now()) {
+		return repo.UnavailabilityRow{}, ErrInvalidWindow
+	}
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return repo.UnavailabilityRow{}, err
+	}
+	return s.store.AddUnavailability(ctx, repo.UnavailabilityRow{
+		UserID:   userID,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Reason:   reason,
+	})
+}
+
+// List возвращает текущие и будущие периоды пользователя
+func (s *AvailabilityService) List(ctx context.Context, userID string) (_ []repo.UnavailabilityRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.List")
+	defer endSpan(span, &err)
+
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.store.ListUnavailability(ctx, userID, s.now())
+}
+
+func (s *AvailabilityService) Delete(ctx context.Context, userID string, id int64) (err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.Delete")
+	defer endSpan(span, &err)
+
+	if err := s.store.DeleteUnavailability(ctx, userID, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrWindowNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *AvailabilityService) GetWorkingHours(ctx context.Context, userID string) (_ repo.WorkingHoursRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.GetWorkingHours")
+	defer endSpan(span, &err)
+
+	row, err := s.store.GetWorkingHours(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return repo.WorkingHoursRow{}, ErrUserNotFound
+	}
+	return row, err
+}
+
+// SetWorkingHours задаёт часовой пояс (IANA, например Europe/Moscow) и рабочие
+// часы пользователя "HH:MM". Пустые start и end снимают ограничение по часам
+func (s *AvailabilityService) SetWorkingHours(ctx context.Context, row repo.WorkingHoursRow) (_ repo.WorkingHoursRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.SetWorkingHours")
+	defer endSpan(span, &err)
+
+	if err := validateWorkingHours(row); err != nil {
+		return repo.WorkingHoursRow{}, err
+	}
+	out, err := s.store.UpdateWorkingHours(ctx, row)
+	if err == pgx.ErrNoRows {
+		return repo.WorkingHoursRow{}, ErrUserNotFound
+	}
+	return out, err
+}
+
+// GetCapacity возвращает лимит одновременных ревью пользователя и его загрузку
+func (s *AvailabilityService) GetCapacity(ctx context.Context, userID string) (_ repo.ReviewCapacityRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.GetCapacity")
+	defer endSpan(span, &err)
+
+	row, err := s.store.GetReviewCapacity(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return repo.ReviewCapacityRow{}, ErrUserNotFound
+	}
+	return row, err
+}
+
+// SetCapacity задаёт собственный max_open_reviews пользователя. nil снимает его,
+// и тогда действует лимит по умолчанию команды
+func (s *AvailabilityService) SetCapacity(ctx context.Context, userID string, limit *int) (_ repo.ReviewCapacityRow, err error) {
+	ctx, span := startSpan(ctx, "AvailabilityService.SetCapacity")
+	defer endSpan(span, &err)
+
+	if limit != nil && *limit < 1 {
+		return repo.ReviewCapacityRow{}, ErrInvalidLimit
+	}
+	if err := s.store.UpdateMaxOpenReviews(ctx, userID, limit); err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.ReviewCapacityRow{}, ErrUserNotFound
+		}
+		return repo.ReviewCapacityRow{}, err
+	}
+	return s.GetCapacity(ctx, userID)
+}
+
+func (s *AvailabilityService) ensureUser(ctx context.Context, userID string) error {
+	if _, err := s.store.GetByID(ctx, userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
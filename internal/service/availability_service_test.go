@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// fakeAvailabilityStore — in-memory реализация AvailabilityStore
+type fakeAvailabilityStore struct {
+	users   map[string]string // user_id -> username
+	periods []repo.UnavailabilityRow
+	hours   map[string]repo.WorkingHoursRow
+	limits  map[string]int // user_id -> max_open_reviews
+}
+
+func (f *fakeAvailabilityStore) GetByID(ctx context.Context, userID string) (repo.UserRow, error) {
+	if _, ok := f.users[userID]; !ok {
+		return repo.UserRow{}, pgx.ErrNoRows
+	}
+	return repo.UserRow{UserID: userID}, nil
+}
+
+func (f *fakeAvailabilityStore) AddUnavailability(ctx context.Context, row repo.UnavailabilityRow) (repo.UnavailabilityRow, error) {
+	row.ID = int64(len(f.periods) + 1)
+	f.periods = append(f.periods, row)
+	return row, nil
+}
+
+func (f *fakeAvailabilityStore) ListUnavailability(ctx context.Context, userID string, since time.Time) ([]repo.UnavailabilityRow, error) {
+	var out []repo.UnavailabilityRow
+	for _, p := range f.periods {
+		if p.UserID == userID && p.EndsAt.After(since) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAvailabilityStore) DeleteUnavailability(ctx context.Context, userID string, id int64) error {
+	for i, p := range f.periods {
+		if p.ID == id && p.UserID == userID {
+			f.periods = append(f.periods[:i], f.periods[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (f *fakeAvailabilityStore) ListUsers(ctx context.Context) ([]repo.UserRow, error) {
+	var out []repo.UserRow
+	for id, name := range f.users {
+		out = append(out, repo.UserRow{UserID: id, Username: name})
+	}
+	return out, nil
+}
+
+func (f *fakeAvailabilityStore) UpsertExternalUnavailability(ctx context.Context, row repo.UnavailabilityRow) (repo.UpsertResult, error) {
+	for i, p := range f.periods {
+		if p.UserID != row.UserID || p.Source != row.Source || p.ExternalUID != row.ExternalUID {
+			continue
+		}
+		if p.StartsAt.Equal(row.StartsAt) && p.EndsAt.Equal(row.EndsAt) && p.Reason == row.Reason {
+			return repo.UpsertUnchanged, nil
+		}
+		row.ID = p.ID
+		f.periods[i] = row
+		return repo.UpsertUpdated, nil
+	}
+	row.ID = int64(len(f.periods) + 1)
+	f.periods = append(f.periods, row)
+	return repo.UpsertCreated, nil
+}
+
+func (f *fakeAvailabilityStore) GetWorkingHours(ctx context.Context, userID string) (repo.WorkingHoursRow, error) {
+	if _, ok := f.users[userID]; !ok {
+		return repo.WorkingHoursRow{}, pgx.ErrNoRows
+	}
+	if row, ok := f.hours[userID]; ok {
+		return row, nil
+	}
+	return repo.WorkingHoursRow{UserID: userID, TimeZone: "UTC"}, nil
+}
+
+func (f *fakeAvailabilityStore) UpdateWorkingHours(ctx context.Context, row repo.WorkingHoursRow) (repo.WorkingHoursRow, error) {
+	if _, ok := f.users[row.UserID]; !ok {
+		return repo.WorkingHoursRow{}, pgx.ErrNoRows
+	}
+	if f.hours == nil {
+		f.hours = make(map[string]repo.WorkingHoursRow)
+	}
+	f.hours[row.UserID] = row
+	return row, nil
+}
+
+func (f *fakeAvailabilityStore) GetReviewCapacity(ctx context.Context, userID string) (repo.ReviewCapacityRow, error) {
+	if _, ok := f.users[userID]; !ok {
+		return repo.ReviewCapacityRow{}, pgx.ErrNoRows
+	}
+	row := repo.ReviewCapacityRow{UserID: userID}
+	if n, ok := f.limits[userID]; ok {
+		row.MaxOpenReviews = &n
+		row.Limit = &n
+	}
+	return row, nil
+}
+
+func (f *fakeAvailabilityStore) UpdateMaxOpenReviews(ctx context.Context, userID string, limit *int) error {
+	if _, ok := f.users[userID]; !ok {
+		return pgx.ErrNoRows
+	}
+	if f.limits == nil {
+		f.limits = make(map[string]int)
+	}
+	delete(f.limits, userID)
+	if limit != nil {
+		f.limits[userID] = *limit
+	}
+	return nil
+}
+
+func newTestAvailabilityService(now time.Time) (*AvailabilityService, *fakeAvailabilityStore) {
+	store := &fakeAvailabilityStore{users: map[string]string{
+		"u1": "Zakhar",
+		"u2": "Daniil",
+		"u3": "Konstantin",
+		"u4": "Nikita",
+	}}
+	svc := NewAvailabilityService(store)
+	svc.now = func() time.Time { return now }
+	return svc, store
+}
+
+func TestAvailability_AddListDelete(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	svc, store := newTestAvailabilityService(now)
+	ctx := context.Background()
+
+	// Закончившийся период в хранилище не попадает в список
+	store.periods = append(store.periods, repo.UnavailabilityRow{ID: 100, UserID: "u1", StartsAt: now.AddDate(0, 0, -10), EndsAt: now.AddDate(0, 0, -3)})
+
+	row, err := svc.Add(ctx, "u1", now.AddDate(0, 0, -1), now.AddDate(0, 0, 7), "vacation")
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	list, err := svc.List(ctx, "u1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != row.ID || list[0].Reason != "vacation" {
+		t.Fatalf("unexpected periods: %+v", list)
+	}
+
+	if err := svc.Delete(ctx, "u2", row.ID); !errors.Is(err, ErrWindowNotFound) {
+		t.Fatalf("other user's period: expected ErrWindowNotFound, got %v", err)
+	}
+	if err := svc.Delete(ctx, "u1", row.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if list, _ := svc.List(ctx, "u1"); len(list) != 0 {
+		t.Fatalf("period must be deleted, got %+v", list)
+	}
+}
+
+func TestAvailability_AddValidation(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	svc, _ := newTestAvailabilityService(now)
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		user     string
+		from, to time.Time
+		want     error
+	}{
+		{"empty period", "u1", now.Add(time.Hour), now.Add(time.Hour), ErrInvalidWindow},
+		{"reversed period", "u1", now.Add(2 * time.Hour), now.Add(time.Hour), ErrInvalidWindow},
+		{"period in the past", "u1", now.AddDate(0, 0, -5), now.AddDate(0, 0, -1), ErrInvalidWindow},
+		{"unknown user", "nobody", now, now.Add(time.Hour), ErrUserNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := svc.Add(ctx, tc.user, tc.from, tc.to, ""); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestImportICal_MapsUsersAndIsIdempotent(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	svc, store := newTestAvailabilityService(now)
+	ctx := context.Background()
+
+	data, err := os.ReadFile("../ical/testdata/team.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := svc.ImportICal(ctx, "backend", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	// ooo-1 — по CN участника (username), ooo-2 — по SUMMARY (user_id),
+	// one-day — по SUMMARY (username); ooo-3 закончился, standup повторяется
+	if report.Created != 3 || report.Updated != 0 || report.Unchanged != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	skipped := map[string]string{}
+	for _, sk := range report.Skipped {
+		skipped[sk.UID] = sk.Reason
+	}
+	wantSkipped := map[string]string{"ooo-3@backend": SkipEnded, "standup@backend": SkipRecurring}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Fatalf("skipped = %v, want %v", skipped, wantSkipped)
+	}
+	var got []string
+	for _, p := range store.periods {
+		if p.Source != "backend" {
+			t.Fatalf("period must keep source, got %+v", p)
+		}
+		got = append(got, p.UserID+"/"+p.ExternalUID)
+	}
+	sort.Strings(got)
+	want := []string{"u2/ooo-1@backend", "u3/ooo-2@backend", "u4/one-day@backend"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("periods = %v, want %v", got, want)
+	}
+
+	// Повторный импорт ничего не меняет
+	report, err = svc.ImportICal(ctx, "backend", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if report.Created != 0 || report.Updated != 0 || report.Unchanged != 3 || len(store.periods) != 3 {
+		t.Fatalf("re-import must be idempotent, got %+v with %d periods", report, len(store.periods))
+	}
+
+	// Изменённое событие обновляет существующий период
+	moved := strings.Replace(string(data), "DTEND;VALUE=DATE:20260715", "DTEND;VALUE=DATE:20260720", 1)
+	report, err = svc.ImportICal(ctx, "backend", strings.NewReader(moved))
+	if err != nil {
+		t.Fatalf("import moved: %v", err)
+	}
+	if report.Updated != 1 || len(store.periods) != 3 {
+		t.Fatalf("moved event must update period, got %+v with %d periods", report, len(store.periods))
+	}
+}
+
+func TestImportICal_MatchesAttendeeEmailAndSkipsUnknown(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc, store := newTestAvailabilityService(now)
+	cal := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:a\r\nSUMMARY:Team offsite\r\nDTSTART;VALUE=DATE:20260210\r\nDTEND;VALUE=DATE:20260212\r\n" +
+		"ATTENDEE;CN=Someone Else:mailto:u1@example.com\r\nATTENDEE:mailto:nikita@example.com\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:b\r\nSUMMARY:Stranger: vacation\r\nDTSTART;VALUE=DATE:20260210\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:c\r\nSUMMARY:u1: vacation\r\nDTSTART;TZID=Mars/Olympus:20260210T090000\r\nDURATION:P2D\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	report, err := svc.ImportICal(context.Background(), "", strings.NewReader(cal))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	skipped := map[string]string{}
+	for _, sk := range report.Skipped {
+		skipped[sk.UID] = sk.Reason
+	}
+	if report.Created != 2 || !reflect.DeepEqual(skipped, map[string]string{"b": SkipNoUser, "c": SkipUnknownTZ}) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, p := range store.periods {
+		if p.Source != DefaultICalSource {
+			t.Fatalf("empty source must default to %q, got %q", DefaultICalSource, p.Source)
+		}
+	}
+}
+
+func TestSetWorkingHours_Validation(t *testing.T) {
+	svc, _ := newTestAvailabilityService(time.Now())
+	ctx := context.Background()
+
+	valid := []repo.WorkingHoursRow{
+		{UserID: "u1", TimeZone: "Europe/Moscow", WorkStart: "09:00", WorkEnd: "18:00"},
+		{UserID: "u1", TimeZone: "Asia/Tokyo", WorkStart: "22:00", WorkEnd: "06:00"},
+		{UserID: "u1", TimeZone: "UTC"},
+	}
+	for _, row := range valid {
+		if _, err := svc.SetWorkingHours(ctx, row); err != nil {
+			t.Fatalf("SetWorkingHours(%+v): %v", row, err)
+		}
+	}
+	got, err := svc.GetWorkingHours(ctx, "u1")
+	if err != nil || got.TimeZone != "UTC" || got.WorkStart != "" {
+		t.Fatalf("last update must win, got %+v, %v", got, err)
+	}
+
+	invalid := []repo.WorkingHoursRow{
+		{UserID: "u1", TimeZone: "Mars/Olympus", WorkStart: "09:00", WorkEnd: "18:00"},
+		{UserID: "u1", TimeZone: "Local"},
+		{UserID: "u1", TimeZone: "UTC", WorkStart: "09:00"},
+		{UserID: "u1", TimeZone: "UTC", WorkStart: "9am", WorkEnd: "18:00"},
+		{UserID: "u1", TimeZone: "UTC", WorkStart: "10:00", WorkEnd: "10:00"},
+	}
+	for _, row := range invalid {
+		if _, err := svc.SetWorkingHours(ctx, row); !errors.Is(err, ErrInvalidWorkingHours) {
+			t.Fatalf("SetWorkingHours(%+v): expected ErrInvalidWorkingHours, got %v", row, err)
+		}
+	}
+	if _, err := svc.SetWorkingHours(ctx, repo.WorkingHoursRow{UserID: "nobody", TimeZone: "UTC"}); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestAvailability_SetCapacity(t *testing.T) {
+	svc, _ := newTestAvailabilityService(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	ctx := context.Background()
+
+	if _, err := svc.SetCapacity(ctx, "u1", new(int)); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("want ErrInvalidLimit, got %v", err)
+	}
+	if _, err := svc.SetCapacity(ctx, "ghost", nil); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("want ErrUserNotFound, got %v", err)
+	}
+	three := 3
+	row, err := svc.SetCapacity(ctx, "u1", &three)
+	if err != nil || row.MaxOpenReviews == nil || *row.MaxOpenReviews != 3 {
+		t.Fatalf("set: %+v, %v", row, err)
+	}
+	row, err = svc.SetCapacity(ctx, "u1", nil)
+	if err != nil || row.MaxOpenReviews != nil {
+		t.Fatalf("reset: %+v, %v", row, err)
+	}
+}
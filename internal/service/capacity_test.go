@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// newCapacityFixture: команда A — автор u1 и ревьюверы u2, u3, u4.
+// На u2 уже назначен открытый PR busy-1
+func newCapacityFixture(t *testing.T) (*fakePRRepo, *PRService) {
+	t.Helper()
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	_ = r.CreatePROpenWithAssigned(context.Background(), "busy-1", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u2", SourceTeam: "A"}}, repo.EventMeta{})
+	return r, newTestPRService(t, r)
+}
+
+func TestCapacity_CreateSkipsSaturated(t *testing.T) {
+	r, svc := newCapacityFixture(t)
+	r.limits["u2"] = 1
+
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	got := append([]string(nil), pr.Assigned...)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"u3", "u4"}) {
+		t.Fatalf("want u3 and u4, got %v", got)
+	}
+	if pr.NeedMoreReviewers || pr.CapacityExhausted {
+		t.Fatalf("quorum met, got need_more=%v capacity_exhausted=%v", pr.NeedMoreReviewers, pr.CapacityExhausted)
+	}
+}
+
+func TestCapacity_TeamDefaultAndOverride(t *testing.T) {
+	r, svc := newCapacityFixture(t)
+	one := 1
+	r.settings["A"] = repo.TeamSettingsRow{TeamName: "A", MinReviewers: 2, MaxReviewers: 2, DefaultMaxOpenReviews: &one}
+	_ = r.CreatePROpenWithAssigned(context.Background(), "busy-2", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}}, repo.EventMeta{})
+	// Собственный лимит u2 выше лимита команды
+	r.limits["u2"] = 5
+
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	got := append([]string(nil), pr.Assigned...)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"u2", "u4"}) {
+		t.Fatalf("want u2 and u4, got %v", got)
+	}
+}
+
+func TestCapacity_AllSaturatedFlagsPR(t *testing.T) {
+	r, svc := newCapacityFixture(t)
+	r.limits["u2"] = 1
+	r.limits["u3"] = 1
+	_ = r.CreatePROpenWithAssigned(context.Background(), "busy-2", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}}, repo.EventMeta{})
+
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !reflect.DeepEqual(pr.Assigned, []string{"u4"}) {
+		t.Fatalf("want only u4, got %v", pr.Assigned)
+	}
+	if !pr.NeedMoreReviewers || !pr.CapacityExhausted {
+		t.Fatalf("want need_more and capacity_exhausted, got %v/%v", pr.NeedMoreReviewers, pr.CapacityExhausted)
+	}
+	ids, _ := svc.CapacityExhausted(context.Background())
+	if !reflect.DeepEqual(ids, []string{"pr-1"}) {
+		t.Fatalf("CapacityExhausted = %v", ids)
+	}
+
+	// Когда лимит подняли, добор назначает u2 и снимает оба флага
+	r.limits["u2"] = 3
+	res, err := svc.TopUp(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("top up: %v", err)
+	}
+	if !reflect.DeepEqual(res.Added, []string{"u2"}) || res.PR.NeedMoreReviewers || res.PR.CapacityExhausted {
+		t.Fatalf("unexpected top up result: added=%v need_more=%v capacity_exhausted=%v", res.Added, res.PR.NeedMoreReviewers, res.PR.CapacityExhausted)
+	}
+}
+
+func TestCapacity_NotFlaggedWithoutLimits(t *testing.T) {
+	r := newFakePRRepo()
+	r.usersTeam["u1"] = "A"
+	r.usersTeam["u2"] = "A"
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true}
+	svc := newTestPRService(t, r)
+
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !pr.NeedMoreReviewers || pr.CapacityExhausted {
+		t.Fatalf("want need_more without capacity_exhausted, got %v/%v", pr.NeedMoreReviewers, pr.CapacityExhausted)
+	}
+}
+
+func TestCapacity_ReassignSkipsSaturated(t *testing.T) {
+	r, svc := newCapacityFixture(t)
+	_ = r.CreatePROpenWithAssigned(context.Background(), "pr-1", "T", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}}, repo.EventMeta{})
+	r.limits["u2"] = 1
+
+	_, replacedBy, err := svc.Reassign(context.Background(), "pr-1", "u3", "")
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if replacedBy != "u4" {
+		t.Fatalf("want u4, got %s", replacedBy)
+	}
+
+	r.limits["u3"] = 1
+	_ = r.CreatePROpenWithAssigned(context.Background(), "busy-2", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}}, repo.EventMeta{})
+	_, _, err = svc.Reassign(context.Background(), "pr-1", "u4", "")
+	if !errors.Is(err, ErrCapacityExhausted) || !errors.Is(err, ErrNoCandidate) {
+		t.Fatalf("want ErrCapacityExhausted wrapping ErrNoCandidate, got %v", err)
+	}
+}
+
+func TestCapacity_DeactivationFlagsPR(t *testing.T) {
+	r, svc := newCapacityFixture(t)
+	r.limits["u2"] = 1
+	_ = r.CreatePROpenWithAssigned(context.Background(), "pr-1", "T", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}, {ReviewerID: "u4", SourceTeam: "A"}}, repo.EventMeta{})
+	users := newFakeUserStore()
+	users.users["u3"] = repo.UserRow{UserID: "u3", TeamName: "A", IsActive: true}
+	userSvc := NewUserService(users, r, &fakeTransactor{}, svc)
+
+	// Единственный свободный по составу кандидат u2 упёрся в лимит
+	_, report, err := userSvc.SetIsActiveAdmin(context.Background(), "u3", false)
+	if err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if !reflect.DeepEqual(report.Uncovered, []string{"pr-1"}) {
+		t.Fatalf("Uncovered = %v, want [pr-1]", report.Uncovered)
+	}
+	pr, _ := r.GetPR(context.Background(), "pr-1")
+	if !reflect.DeepEqual(pr.Assigned, []string{"u4"}) || !pr.NeedMoreReviewers || !pr.CapacityExhausted {
+		t.Fatalf("want u4 with need_more and capacity_exhausted, got %v %v/%v", pr.Assigned, pr.NeedMoreReviewers, pr.CapacityExhausted)
+	}
+}
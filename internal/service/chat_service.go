@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// maxChatHandle — длина столбца users.chat_handle
+const maxChatHandle = 100
+
+// Шаблоны уведомлений по умолчанию. Ссылка по умолчанию не строится
+const (
+	DefaultChatAssignedTemplate   = `{{.Reviewer.Mention}}, you were assigned to review {{template "pr" .}} by {{.Author.Mention}}`
+	DefaultChatReassignedTemplate = `{{.Reviewer.Mention}}, you were assigned to review {{template "pr" .}} by {{.Author.Mention}} instead of {{.OldReviewer.Mention}}`
+)
+
+// chatPRTemplate — PR ссылкой в разметке Slack, если задан шаблон ссылки
+const chatPRTemplate = `{{define "pr"}}{{if .Link}}<{{.Link}}|{{.PullRequestName}}>{{else}}{{.PullRequestName}} ({{.PullRequestID}}){{end}}{{end}}`
+
+// chatFuncs — функции, доступные в шаблонах уведомлений
+var chatFuncs = template.FuncMap{
+	"replace": strings.ReplaceAll,
+}
+
+// ChatStore хранит ники пользователей в мессенджере
+type ChatStore interface {
+	GetChatHandle(ctx context.Context, userID string) (repo.ChatHandleRow, error)
+	UpdateChatHandle(ctx context.Context, row repo.ChatHandleRow) (repo.ChatHandleRow, error)
+	ListChatHandles(ctx context.Context, userIDs []string) (map[string]string, error)
+}
+
+// ChatService управляет никами пользователей, которыми их упоминают в уведомлениях
+type ChatService struct {
+	store ChatStore
+}
+
+func NewChatService(store ChatStore) *ChatService {
+	return &ChatService{store: store}
+}
+
+func (s *ChatService) GetHandle(ctx context.Context, userID string) (_ repo.ChatHandleRow, err error) {
+	ctx, span := startSpan(ctx, "ChatService.GetHandle")
+	defer endSpan(span, &err)
+
+	row, err := s.store.GetChatHandle(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return repo.ChatHandleRow{}, ErrUserNotFound
+	}
+	return row, err
+}
+
+// SetHandle задаёт ник пользователя: ID участника Slack (U024BE7LH) или имя
+// без пробелов для других мессенджеров, см. ChatUser.Mention. Пустой handle снимает ник
+func (s *ChatService) SetHandle(ctx context.Context, userID, handle string) (_ repo.ChatHandleRow, err error) {
+	ctx, span := startSpan(ctx, "ChatService.SetHandle")
+	defer endSpan(span, &err)
+
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if len(handle) > maxChatHandle || strings.ContainsFunc(handle, unicode.IsSpace) {
+		return repo.ChatHandleRow{}, ErrInvalidChatHandle
+	}
+	row, err := s.store.UpdateChatHandle(ctx, repo.ChatHandleRow{UserID: userID, Handle: handle})
+	if err == pgx.ErrNoRows {
+		return repo.ChatHandleRow{}, ErrUserNotFound
+	}
+	return row, err
+}
+
+// ChatTemplates — шаблоны text/template уведомлений. Link строит ссылку на PR,
+// Assigned и Reassigned — текст сообщений; пустой шаблон заменяется шаблоном
+// по умолчанию, а пустой Link — отсутствием ссылки. Данные шаблонов — ChatMessage
+type ChatTemplates struct {
+	Link       string
+	Assigned   string
+	Reassigned string
+}
+
+// ChatUser — участник уведомления
+type ChatUser struct {
+	ID     string
+	Handle string
+}
+
+// slackMemberID — ID участника Slack; только его Slack раскрывает в упоминание <@...>
+var slackMemberID = regexp.MustCompile(`^[UW][A-Z0-9]{2,}$`)
+
+// Mention возвращает упоминание: <@ID> для ID участника Slack, @имя для остальных
+// ников (так упоминают в Mattermost и Rocket.Chat) или user_id, если ник не задан
+func (u ChatUser) Mention() string {
+	switch {
+	case u.Handle == "":
+		return u.ID
+	case slackMemberID.MatchString(u.Handle):
+		return "<@" + u.Handle + ">"
+	default:
+		return "@" + u.Handle
+	}
+}
+
+// ChatMessage — данные шаблонов уведомления. Link пуст при выполнении
+// шаблона ссылки, OldReviewer заполнен только у reviewer.reassigned
+type ChatMessage struct {
+	Event           string
+	PullRequestID   string
+	PullRequestName string
+	Link            string
+	Author          ChatUser
+	Reviewer        ChatUser
+	OldReviewer     ChatUser
+	Reason          string
+	Actor           string
+}
+
+// ChatNotifier — приёмник outbox, который пишет в чат о назначении и переназначении
+// ревьюверов: POST {"text": ...} во входящий вебхук Slack или совместимого мессенджера.
+// Ошибку мессенджера повторяет Relay с задержкой, не задерживая другие приёмники;
+// доставка at-least-once: после сбоя сообщение может прийти повторно
+type ChatNotifier struct {
+	handles    ChatStore
+	sender     WebhookSender
+	url        string
+	link       *template.Template
+	assigned   *template.Template
+	reassigned *template.Template
+}
+
+// NewChatNotifier разбирает шаблоны и пробует выполнить их, чтобы ошибка
+// в шаблоне была видна при запуске, а не при первом назначении
+func NewChatNotifier(handles ChatStore, sender WebhookSender, url string, t ChatTemplates) (*ChatNotifier, error) {
+	if t.Assigned == "" {
+		t.Assigned = DefaultChatAssignedTemplate
+	}
+	if t.Reassigned == "" {
+		t.Reassigned = DefaultChatReassignedTemplate
+	}
+	n := &ChatNotifier{handles: handles, sender: sender, url: url}
+	for _, p := range []struct {
+		dst  **template.Template
+		name string
+		text string
+	}{
+		{&n.link, "link", t.Link},
+		{&n.assigned, "assigned", t.Assigned},
+		{&n.reassigned, "reassigned", t.Reassigned},
+	} {
+		tmpl, err := template.New(p.name).Funcs(chatFuncs).Parse(chatPRTemplate)
+		if err == nil {
+			tmpl, err = tmpl.Parse(p.text)
+		}
+		if err == nil {
+			_, err = render(tmpl, ChatMessage{})
+		}
+		if err != nil {
+			return nil, err
+		}
+		*p.dst = tmpl
+	}
+	return n, nil
+}
+
+func (n *ChatNotifier) Name() string { return "chat" }
+
+func (n *ChatNotifier) Publish(ctx context.Context, m outbox.Message) error {
+	ev, ok, err := EventFromOutbox(m)
+	if err != nil || !ok {
+		return err
+	}
+	var tmpl *template.Template
+	switch ev.Type {
+	case EventReviewerAssigned:
+		tmpl = n.assigned
+	case EventReviewerReassigned:
+		tmpl = n.reassigned
+	default:
+		return nil
+	}
+
+	msg, err := n.message(ctx, ev)
+	if err != nil {
+		return err
+	}
+	// Шаблоны проверены при запуске; если выполнение всё же упало, повтор не поможет
+	link, err := render(n.link, msg)
+	var text string
+	if err == nil {
+		msg.Link = strings.TrimSpace(link)
+		text, err = render(tmpl, msg)
+	}
+	if err != nil {
+		logger.Warn("chat notification template failed", "outbox_id", m.ID, "event", ev.Type, "error", err)
+		return nil
+	}
+	// Шаблон может ничего не выводить, например для части событий
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+	}{text})
+	if err != nil {
+		return err
+	}
+
+	status, err := n.sender.Send(ctx, outbound.Message{
+		URL:        n.url,
+		EventType:  ev.Type,
+		DeliveryID: strconv.FormatInt(m.ID, 10),
+		Body:       body,
+	})
+	// 4xx не исправится повтором, а повторы задержали бы следующие уведомления
+	if err != nil && status >= 400 && status < 500 {
+		logger.Warn("chat notification rejected",
+			"outbox_id", m.ID,
+			"event", ev.Type,
+			"status_code", status,
+			"error", err,
+		)
+		return nil
+	}
+	return err
+}
+
+// message собирает данные шаблона с никами участников, без ссылки
+func (n *ChatNotifier) message(ctx context.Context, ev Event) (ChatMessage, error) {
+	d := ev.Data
+	handles, err := n.handles.ListChatHandles(ctx, []string{d.AuthorID, d.ReviewerID, d.OldReviewerID})
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	user := func(id string) ChatUser {
+		if id == "" {
+			return ChatUser{}
+		}
+		return ChatUser{ID: id, Handle: handles[id]}
+	}
+	return ChatMessage{
+		Event:           ev.Type,
+		PullRequestID:   d.PullRequestID,
+		PullRequestName: d.PullRequestName,
+		Author:          user(d.AuthorID),
+		Reviewer:        user(d.ReviewerID),
+		OldReviewer:     user(d.OldReviewerID),
+		Reason:          d.Reason,
+		Actor:           d.Actor,
+	}, nil
+}
+
+func render(tmpl *template.Template, data ChatMessage) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// fakeChatStore — ники пользователей в памяти
+type fakeChatStore map[string]string
+
+func (f fakeChatStore) GetChatHandle(ctx context.Context, userID string) (repo.ChatHandleRow, error) {
+	handle, ok := f[userID]
+	if !ok {
+		return repo.ChatHandleRow{}, pgx.ErrNoRows
+	}
+	return repo.ChatHandleRow{UserID: userID, Handle: handle}, nil
+}
+
+func (f fakeChatStore) UpdateChatHandle(ctx context.Context, row repo.ChatHandleRow) (repo.ChatHandleRow, error) {
+	if _, ok := f[row.UserID]; !ok {
+		return repo.ChatHandleRow{}, pgx.ErrNoRows
+	}
+	f[row.UserID] = row.Handle
+	return row, nil
+}
+
+func (f fakeChatStore) ListChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, id := range userIDs {
+		if f[id] != "" {
+			out[id] = f[id]
+		}
+	}
+	return out, nil
+}
+
+// chatText достаёт текст сообщения из тела запроса к входящему вебхуку
+func chatText(t *testing.T, body []byte) string {
+	t.Helper()
+	var msg struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return msg.Text
+}
+
+func TestChatService_SetHandle(t *testing.T) {
+	svc := NewChatService(fakeChatStore{"u1": ""})
+	ctx := context.Background()
+
+	row, err := svc.SetHandle(ctx, "u1", " @alice ")
+	if err != nil || row.Handle != "alice" {
+		t.Fatalf("SetHandle = %+v, %v; want alice", row, err)
+	}
+	if _, err := svc.SetHandle(ctx, "u1", "alice smith"); !errors.Is(err, ErrInvalidChatHandle) {
+		t.Fatalf("handle with space: err = %v, want ErrInvalidChatHandle", err)
+	}
+	if _, err := svc.SetHandle(ctx, "ghost", "bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+	if row, err := svc.SetHandle(ctx, "u1", ""); err != nil || row.Handle != "" {
+		t.Fatalf("clear: %+v, %v", row, err)
+	}
+}
+
+func TestChatNotifier_PostsAssignments(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	handles := fakeChatStore{"u1": "U0AUTHOR", "u2": "bob", "u3": ""}
+	n, err := NewChatNotifier(handles, outbound.NewSender(time.Second), srv.URL, ChatTemplates{
+		Link: `https://github.com/{{replace .PullRequestID "#" "/pull/"}}`,
+	})
+	if err != nil {
+		t.Fatalf("notifier: %v", err)
+	}
+	ctx := context.Background()
+	const pr = `"pull_request_id":"acme/api#7","pull_request_name":"Add search","author_id":"u1","status":"OPEN","actor":"system"`
+
+	for _, m := range []struct {
+		id      int64
+		typ     string
+		payload string
+	}{
+		{1, repo.EventCreated, `{` + pr + `}`},
+		{2, repo.EventAssigned, `{` + pr + `,"user_id":"u2"}`},
+		{3, repo.EventReviewed, `{` + pr + `,"user_id":"u2","verdict":"APPROVED"}`},
+		{4, repo.EventReassigned, `{` + pr + `,"from_user_id":"u2","to_user_id":"u3","reason":"manual"}`},
+	} {
+		if err := n.Publish(ctx, outboxMessage(m.id, m.typ, m.payload)); err != nil {
+			t.Fatalf("publish %d: %v", m.id, err)
+		}
+	}
+
+	want := []string{
+		"@bob, you were assigned to review <https://github.com/acme/api/pull/7|Add search> by <@U0AUTHOR>",
+		"u3, you were assigned to review <https://github.com/acme/api/pull/7|Add search> by <@U0AUTHOR> instead of @bob",
+	}
+	if rc.count() != len(want) {
+		t.Fatalf("got %d messages, want %d", rc.count(), len(want))
+	}
+	for i, w := range want {
+		if got := chatText(t, rc.bodies[i]); got != w {
+			t.Errorf("message %d = %q, want %q", i, got, w)
+		}
+	}
+	if ev := rc.headers[0].Get(outbound.HeaderEvent); ev != EventReviewerAssigned {
+		t.Errorf("event header = %q", ev)
+	}
+}
+
+func TestChatNotifier_RetriesOnlyServerErrors(t *testing.T) {
+	logger.Init("test")
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusNotFound}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	n, err := NewChatNotifier(fakeChatStore{}, outbound.NewSender(time.Second), srv.URL, ChatTemplates{
+		Assigned: `{{.Reviewer.ID}} -> {{.PullRequestName}}{{if .Link}} {{.Link}}{{end}}`,
+	})
+	if err != nil {
+		t.Fatalf("notifier: %v", err)
+	}
+	m := outboxMessage(1, repo.EventAssigned, `{"pull_request_id":"pr-1","pull_request_name":"Fix","author_id":"u1","user_id":"u2"}`)
+
+	// 500 — событие останется в outbox и будет опубликовано снова
+	if err := n.Publish(context.Background(), m); err == nil {
+		t.Fatal("5xx must be retried")
+	}
+	// 404 повтором не исправить — уведомление пропускается
+	if err := n.Publish(context.Background(), m); err != nil {
+		t.Fatalf("4xx must not be retried: %v", err)
+	}
+	if got := chatText(t, rc.bodies[1]); got != "u2 -> Fix" {
+		t.Fatalf("text = %q", got)
+	}
+}
+
+func TestNewChatNotifier_RejectsBrokenTemplates(t *testing.T) {
+	for _, tmpl := range []ChatTemplates{
+		{Link: "{{.PullRequestID"},
+		{Assigned: "{{.Reviewer.Nickname}}"},
+		{Reassigned: `{{template "missing" .}}`},
+	} {
+		if _, err := NewChatNotifier(fakeChatStore{}, nil, "http://chat.local", tmpl); err == nil {
+			t.Errorf("%+v: want error", tmpl)
+		}
+	}
+}
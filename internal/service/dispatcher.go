@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+const (
+	// dispatchBatch — сколько доставок выбирается из очереди за раз
+	dispatchBatch = 100
+	// dispatchLease — на сколько откладывается выбранная доставка: если экземпляр
+	// упадёт посреди отправки, её повторит другой. Должен превышать таймаут отправки
+	dispatchLease = 5 * time.Minute
+)
+
+// DeliveryQueue — очередь доставок исходящих вебхуков
+type DeliveryQueue interface {
+	ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]repo.DueDelivery, error)
+	RecordAttempt(ctx context.Context, id int64, a repo.DeliveryAttempt) error
+}
+
+// WebhookSender отправляет одну доставку и возвращает код ответа получателя
+type WebhookSender interface {
+	Send(ctx context.Context, m outbound.Message) (int, error)
+}
+
+// RetryPolicy — повторы неудачных доставок. После n-й неудачной попытки следующая
+// откладывается на BackoffBase * 2^(n-1), но не больше BackoffMax. После MaxAttempts
+// попыток доставка помечается failed
+type RetryPolicy struct {
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// ErrInvalidRetryPolicy — в политике повторов не разрешена ни одна попытка
+var ErrInvalidRetryPolicy = errors.New("max_attempts must be >= 1")
+
+// Validate проверяет политику при запуске. Бесконечных повторов нет: Relay
+// и Dispatcher одинаково сдаются после MaxAttempts попыток
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("%w, got %d", ErrInvalidRetryPolicy, p.MaxAttempts)
+	}
+	return nil
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempts неудачных
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	d := p.BackoffBase
+	for i := 1; i < attempts && d < p.BackoffMax; i++ {
+		d *= 2
+	}
+	if d > p.BackoffMax {
+		d = p.BackoffMax
+	}
+	return d
+}
+
+// Dispatcher периодически отправляет доставки, время которых наступило,
+// и записывает итог каждой попытки в журнал
+type Dispatcher struct {
+	queue    DeliveryQueue
+	sender   WebhookSender
+	retry    RetryPolicy
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewDispatcher(queue DeliveryQueue, sender WebhookSender, retry RetryPolicy, interval time.Duration) *Dispatcher {
+	return &Dispatcher{queue: queue, sender: sender, retry: retry, interval: interval, now: time.Now}
+}
+
+// Run отправляет доставки раз в interval, пока не отменён ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce отправляет все доставки, время которых наступило
+func (d *Dispatcher) RunOnce(ctx context.Context) {
+	for {
+		now := d.now()
+		due, err := d.queue.ClaimDueDeliveries(ctx, now, dispatchBatch, now.Add(dispatchLease))
+		if err != nil {
+			logger.Error("webhook deliveries claim failed", "error", err)
+			return
+		}
+		for _, del := range due {
+			d.deliver(ctx, del)
+		}
+		if len(due) < dispatchBatch {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, del repo.DueDelivery) {
+	code, err := d.sender.Send(ctx, outbound.Message{
+		URL:        del.URL,
+		Secret:     del.Secret,
+		EventType:  del.EventType,
+		DeliveryID: strconv.FormatInt(del.ID, 10),
+		Body:       del.Payload,
+	})
+	attempts := del.Attempts + 1
+	now := d.now()
+	a := repo.DeliveryAttempt{StatusCode: code}
+	switch {
+	case err == nil:
+		a.Status, a.At = repo.DeliveryDelivered, now
+	case attempts >= d.retry.MaxAttempts:
+		a.Status, a.Error, a.At = repo.DeliveryFailed, err.Error(), now
+	default:
+		a.Status, a.Error, a.At = repo.DeliveryPending, err.Error(), now.Add(d.retry.Backoff(attempts))
+	}
+	if err != nil {
+		logger.Warn("webhook delivery failed",
+			"delivery_id", del.ID,
+			"subscription_id", del.SubscriptionID,
+			"event", del.EventType,
+			"attempts", attempts,
+			"status", a.Status,
+			"error", err,
+		)
+	}
+	if err := d.queue.RecordAttempt(ctx, del.ID, a); err != nil {
+		logger.Error("webhook delivery attempt not recorded", "delivery_id", del.ID, "error", err)
+	}
+}
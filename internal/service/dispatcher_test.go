@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbound"
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// receiver — локальный получатель вебхуков: отвечает кодами из statuses по очереди
+// (потом 200) и запоминает принятые запросы
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.bodies = append(rc.bodies, body)
+	rc.headers = append(rc.headers, r.Header.Clone())
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BackoffBase: time.Second, BackoffMax: 10 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestRetryPolicy_Validate(t *testing.T) {
+	if err := (RetryPolicy{MaxAttempts: 1}).Validate(); err != nil {
+		t.Fatalf("one attempt must be allowed: %v", err)
+	}
+	for _, n := range []int{0, -1} {
+		if err := (RetryPolicy{MaxAttempts: n}).Validate(); !errors.Is(err, ErrInvalidRetryPolicy) {
+			t.Fatalf("MaxAttempts = %d: err = %v, want ErrInvalidRetryPolicy", n, err)
+		}
+	}
+}
+
+// TestDispatcher_DeliversAssignmentEvents проходит путь от события outbox
+// до подписанного запроса получателю: первая попытка получает 500 и повторяется после задержки
+func TestDispatcher_DeliversAssignmentEvents(t *testing.T) {
+	logger.Init("test")
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{http.StatusInternalServerError}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	store := &fakeWebhookStore{}
+	subs := NewSubscriptionService(store)
+	if _, err := subs.Create(ctx, srv.URL, "s3cret", []string{EventReviewerAssigned}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	// События создания PR в том виде, в каком их пишет в outbox repo
+	sink := NewWebhookSink(subs)
+	for _, m := range []outbox.Message{
+		outboxMessage(1, repo.EventCreated, `{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1","status":"OPEN","assigned_reviewers":[],"actor":"system","user_id":"u1"}`),
+		outboxMessage(2, repo.EventAssigned, `{"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1","status":"OPEN","assigned_reviewers":["u2"],"actor":"system","user_id":"u2"}`),
+	} {
+		if err := sink.Publish(ctx, m); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(store, outbound.NewSender(time.Second), RetryPolicy{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Hour}, time.Second)
+	d.now = func() time.Time { return now }
+
+	d.RunOnce(ctx)
+	first := store.delivery(1)
+	if first.Status != repo.DeliveryPending || first.Attempts != 1 || first.LastStatusCode != http.StatusInternalServerError {
+		t.Fatalf("after failed attempt: %+v", first)
+	}
+	if !first.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("next attempt at %v, want %v", first.NextAttemptAt, now.Add(time.Minute))
+	}
+
+	// До истечения задержки повторов нет
+	d.RunOnce(ctx)
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests before backoff elapsed, want 1", rc.count())
+	}
+
+	now = now.Add(time.Minute)
+	d.RunOnce(ctx)
+	done := store.delivery(1)
+	if done.Status != repo.DeliveryDelivered || done.Attempts != 2 || done.DeliveredAt == nil {
+		t.Fatalf("after retry: %+v", done)
+	}
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", rc.count())
+	}
+
+	h, body := rc.headers[1], rc.bodies[1]
+	if !outbound.Verify("s3cret", body, h.Get(outbound.HeaderSignature)) {
+		t.Fatalf("bad signature %q", h.Get(outbound.HeaderSignature))
+	}
+	if h.Get(outbound.HeaderEvent) != EventReviewerAssigned || h.Get(outbound.HeaderDelivery) != "1" {
+		t.Fatalf("headers = %v", h)
+	}
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if ev.ID != "2" || ev.Data.PullRequestID != "pr-1" || ev.Data.ReviewerID != "u2" || ev.Data.AuthorID != "u1" {
+		t.Fatalf("event = %+v", ev)
+	}
+	// Повторная доставка несёт то же событие
+	if string(rc.bodies[0]) != string(body) {
+		t.Fatalf("retry must resend the same payload")
+	}
+}
+
+func TestDispatcher_FailsAfterMaxAttempts(t *testing.T) {
+	logger.Init("test")
+	ctx := context.Background()
+	rc := &receiver{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	store := &fakeWebhookStore{}
+	subs := NewSubscriptionService(store)
+	if _, err := subs.Create(ctx, srv.URL, "s", []string{EventPRMerged}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := subs.Publish(ctx, Event{ID: "ev-1", Type: EventPRMerged}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := NewDispatcher(store, outbound.NewSender(time.Second), RetryPolicy{MaxAttempts: 2, BackoffBase: time.Second, BackoffMax: time.Second}, time.Second)
+	d.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		d.RunOnce(ctx)
+		now = now.Add(time.Second)
+	}
+	got := store.delivery(1)
+	if got.Status != repo.DeliveryFailed || got.Attempts != 2 || got.LastError == "" {
+		t.Fatalf("delivery = %+v, want failed after 2 attempts", got)
+	}
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", rc.count())
+	}
+}
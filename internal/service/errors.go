@@ -0,0 +1,83 @@
+package service
+
+// Kind — класс ошибки сервиса; по нему HTTP-слой выбирает статус ответа
+type Kind int
+
+const (
+	// KindInternal — непредвиденная ошибка (500)
+	KindInternal Kind = iota
+	// KindInvalid — некорректные входные данные (400)
+	KindInvalid
+	// KindNotFound — ресурс не найден (404)
+	KindNotFound
+	// KindConflict — операция невозможна в текущем состоянии (409)
+	KindConflict
+)
+
+// Error — ошибка из каталога: стабильный код для клиентов, класс и описание.
+// Ошибки каталога — значения-синглтоны, сравниваются через errors.Is; контекст
+// добавляется обёрткой fmt.Errorf("%w: ...")
+type Error struct {
+	Code    string
+	Kind    Kind
+	Message string
+	// cause — более общая ошибка каталога, которую уточняет эта
+	cause error
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.cause }
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Code: code, Kind: kind, Message: message}
+}
+
+// Каталог ошибок сервиса
+var (
+	// Команды
+	ErrTeamExists       = newError(KindConflict, "TEAM_EXISTS", "team_name already exists")
+	ErrNotFound         = newError(KindNotFound, "NOT_FOUND", "team not found")
+	ErrFallbackNotFound = newError(KindNotFound, "NOT_FOUND", "fallback team not found")
+	ErrInvalidSettings  = newError(KindInvalid, "INVALID_SETTINGS", "require 0 <= min_reviewers <= max_reviewers, max_reviewers >= 1, default_max_open_reviews >= 1 or null and distinct fallback_teams other than the team itself")
+
+	// Пользователи
+	ErrUserNotFound        = newError(KindNotFound, "NOT_FOUND", "user not found")
+	ErrNotFoundUser        = ErrUserNotFound
+	ErrInvalidWindow       = newError(KindInvalid, "INVALID_WINDOW", "period must end after it starts and not be in the past")
+	ErrWindowNotFound      = newError(KindNotFound, "NOT_FOUND", "unavailability period not found")
+	ErrInvalidWorkingHours = newError(KindInvalid, "INVALID_WORKING_HOURS", "time_zone must be an IANA zone, work_start and work_end must be different HH:MM values given together")
+	ErrInvalidLimit        = newError(KindInvalid, "INVALID_LIMIT", "max_open_reviews must be >= 1 or null")
+	ErrInvalidChatHandle   = newError(KindInvalid, "INVALID_CHAT_HANDLE", "chat_handle must be at most 100 characters without spaces")
+
+	// Привязки логинов внешних систем
+	ErrInvalidIdentity  = newError(KindInvalid, "INVALID_IDENTITY", "provider must be github or gitlab, login must not be empty and external_id must be at most 100 characters")
+	ErrIdentityNotFound = newError(KindNotFound, "NOT_FOUND", "identity not found")
+	ErrUnknownIdentity  = newError(KindInvalid, "UNKNOWN_IDENTITY", "login is not linked to any user")
+
+	// Подписки на исходящие вебхуки
+	ErrInvalidSubscription  = newError(KindInvalid, "INVALID_SUBSCRIPTION", "url must be an absolute http or https URL, secret must not be empty and events must list known event types")
+	ErrSubscriptionNotFound = newError(KindNotFound, "NOT_FOUND", "subscription not found")
+
+	// Pull Request'ы
+	ErrPRExists          = newError(KindConflict, "PR_EXISTS", "PR id already exists")
+	ErrNotFoundPR        = newError(KindNotFound, "NOT_FOUND", "PR not found")
+	ErrPRMerged          = newError(KindConflict, "PR_MERGED", "PR is already merged")
+	ErrPRNotOpen         = newError(KindConflict, "PR_NOT_OPEN", "PR is not open")
+	ErrInvalidTransition = newError(KindConflict, "INVALID_TRANSITION", "invalid PR status transition")
+	ErrMergeBlocked      = newError(KindConflict, "MERGE_BLOCKED", "merge policy is not satisfied")
+	ErrInvalidVerdict    = newError(KindInvalid, "INVALID_VERDICT", "verdict must be APPROVED, CHANGES_REQUESTED or COMMENTED")
+	ErrNotAssigned       = newError(KindConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+	ErrNoCandidate       = newError(KindConflict, "NO_CANDIDATE", "no active replacement candidate in team")
+	// ErrCapacityExhausted — кандидаты есть, но все достигли max_open_reviews.
+	// Уточняет ErrNoCandidate: errors.Is(ErrCapacityExhausted, ErrNoCandidate) == true
+	ErrCapacityExhausted = &Error{
+		Code:    "CAPACITY_EXHAUSTED",
+		Kind:    KindConflict,
+		Message: "all replacement candidates reached max_open_reviews",
+		cause:   ErrNoCandidate,
+	}
+
+	// Статистика
+	ErrInvalidStatsFilter = newError(KindInvalid, "INVALID_STATS_FILTER", "group_by must be reviewer, team or week, status must be DRAFT, OPEN, MERGED or CLOSED, from must be before to")
+)
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// Типы доменных событий, на которые можно подписаться
+const (
+	EventPRCreated          = "pr.created"
+	EventReviewerAssigned   = "reviewer.assigned"
+	EventReviewerReassigned = "reviewer.reassigned"
+	EventPRMerged           = "pr.merged"
+)
+
+var eventTypes = map[string]bool{
+	EventPRCreated:          true,
+	EventReviewerAssigned:   true,
+	EventReviewerReassigned: true,
+	EventPRMerged:           true,
+}
+
+// webhookEvents — события журнала PR, которые рассылаются подписчикам, и их типы
+var webhookEvents = map[string]string{
+	repo.EventCreated:    EventPRCreated,
+	repo.EventAssigned:   EventReviewerAssigned,
+	repo.EventReassigned: EventReviewerReassigned,
+	repo.EventMerged:     EventPRMerged,
+}
+
+// Event — доменное событие для подписчиков. ID уникален для события и одинаков
+// во всех его доставках, по нему получатель отбрасывает повторы
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       EventData `json:"data"`
+}
+
+// EventData — PR на момент события. ReviewerID заполнен у reviewer.assigned
+// и reviewer.reassigned (новый ревьювер), OldReviewerID — у reviewer.reassigned
+type EventData struct {
+	PullRequestID     string   `json:"pull_request_id"`
+	PullRequestName   string   `json:"pull_request_name"`
+	AuthorID          string   `json:"author_id"`
+	Status            string   `json:"status"`
+	AssignedReviewers []string `json:"assigned_reviewers"`
+	ReviewerID        string   `json:"reviewer_id,omitempty"`
+	OldReviewerID     string   `json:"old_reviewer_id,omitempty"`
+	Reason            string   `json:"reason,omitempty"`
+	Actor             string   `json:"actor"`
+}
+
+// EventPublisher получает доменные события, например чтобы разослать их подписчикам
+type EventPublisher interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+// outboxPayload — снимок события журнала PR, который repo пишет в outbox
+type outboxPayload struct {
+	PullRequestID     string    `json:"pull_request_id"`
+	PullRequestName   string    `json:"pull_request_name"`
+	AuthorID          string    `json:"author_id"`
+	Status            string    `json:"status"`
+	AssignedReviewers []string  `json:"assigned_reviewers"`
+	Actor             string    `json:"actor"`
+	UserID            string    `json:"user_id"`
+	FromUserID        string    `json:"from_user_id"`
+	ToUserID          string    `json:"to_user_id"`
+	Reason            string    `json:"reason"`
+	OccurredAt        time.Time `json:"occurred_at"`
+}
+
+// EventFromOutbox превращает событие outbox в событие для подписчиков.
+// ok = false, если событие этого типа подписчикам не рассылается
+func EventFromOutbox(m outbox.Message) (_ Event, ok bool, err error) {
+	eventType, ok := webhookEvents[m.Type]
+	if !ok {
+		return Event{}, false, nil
+	}
+	var p outboxPayload
+	if err := json.Unmarshal(m.Payload, &p); err != nil {
+		return Event{}, false, err
+	}
+	data := EventData{
+		PullRequestID:     p.PullRequestID,
+		PullRequestName:   p.PullRequestName,
+		AuthorID:          p.AuthorID,
+		Status:            p.Status,
+		AssignedReviewers: append([]string{}, p.AssignedReviewers...),
+		Reason:            p.Reason,
+		Actor:             p.Actor,
+	}
+	switch eventType {
+	case EventReviewerAssigned:
+		data.ReviewerID = p.UserID
+	case EventReviewerReassigned:
+		data.ReviewerID, data.OldReviewerID = p.ToUserID, p.FromUserID
+	}
+	return Event{
+		ID:         strconv.FormatInt(m.ID, 10),
+		Type:       eventType,
+		OccurredAt: p.OccurredAt.UTC(),
+		Data:       data,
+	}, true, nil
+}
+
+// WebhookSink — приёмник outbox, который ставит события в очередь доставки подписчикам.
+// Отправка идёт отдельно, в Dispatcher; повтор события не дублирует доставку
+type WebhookSink struct {
+	events EventPublisher
+}
+
+func NewWebhookSink(events EventPublisher) *WebhookSink {
+	return &WebhookSink{events: events}
+}
+
+func (s *WebhookSink) Name() string { return "webhooks" }
+
+func (s *WebhookSink) Publish(ctx context.Context, m outbox.Message) error {
+	ev, ok, err := EventFromOutbox(m)
+	if err != nil || !ok {
+		return err
+	}
+	return s.events.Publish(ctx, ev)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// Внешние системы, логины которых можно привязать к пользователю
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// maxExternalID — длина столбца external_identities.external_id
+const maxExternalID = 100
+
+var identityProviders = map[string]bool{
+	ProviderGitHub: true,
+	ProviderGitLab: true,
+}
+
+// IdentityStore хранит привязки логинов внешних систем к пользователям
+type IdentityStore interface {
+	GetByID(ctx context.Context, userID string) (repo.UserRow, error)
+	UpsertIdentity(ctx context.Context, row repo.IdentityRow) (repo.IdentityRow, error)
+	ListIdentities(ctx context.Context, userID string) ([]repo.IdentityRow, error)
+	ResolveIdentity(ctx context.Context, provider, login string) (string, error)
+	ResolveExternalID(ctx context.Context, provider, externalID string) (string, error)
+	DeleteIdentity(ctx context.Context, provider, login string) error
+}
+
+// IdentityService сопоставляет логины внешних систем (GitHub, GitLab) с user_id.
+// Логины регистронезависимы и хранятся в нижнем регистре
+type IdentityService struct {
+	store IdentityStore
+}
+
+func NewIdentityService(store IdentityStore) *IdentityService {
+	return &IdentityService{store: store}
+}
+
+// Link привязывает логин к пользователю, перенося привязку, если логин уже занят.
+// externalID — постоянный ID пользователя во внешней системе (для GitLab — числовой
+// ID, по которому вебхук находит автора MR); пустой externalID сохраняет прежний
+func (s *IdentityService) Link(ctx context.Context, provider, login, userID, externalID string) (_ repo.IdentityRow, err error) {
+	ctx, span := startSpan(ctx, "IdentityService.Link")
+	defer endSpan(span, &err)
+
+	provider, login, err = normalizeIdentity(provider, login)
+	if err != nil {
+		return repo.IdentityRow{}, err
+	}
+	externalID = strings.TrimSpace(externalID)
+	if len(externalID) > maxExternalID {
+		return repo.IdentityRow{}, ErrInvalidIdentity
+	}
+	if _, err := s.store.GetByID(ctx, userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.IdentityRow{}, ErrUserNotFound
+		}
+		return repo.IdentityRow{}, err
+	}
+	return s.store.UpsertIdentity(ctx, repo.IdentityRow{Provider: provider, Login: login, UserID: userID, ExternalID: externalID})
+}
+
+// List возвращает привязки пользователя
+func (s *IdentityService) List(ctx context.Context, userID string) (_ []repo.IdentityRow, err error) {
+	ctx, span := startSpan(ctx, "IdentityService.List")
+	defer endSpan(span, &err)
+
+	if _, err := s.store.GetByID(ctx, userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return s.store.ListIdentities(ctx, userID)
+}
+
+func (s *IdentityService) Unlink(ctx context.Context, provider, login string) (err error) {
+	ctx, span := startSpan(ctx, "IdentityService.Unlink")
+	defer endSpan(span, &err)
+
+	provider, login, err = normalizeIdentity(provider, login)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteIdentity(ctx, provider, login); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Resolve возвращает user_id, к которому привязан логин, или ErrUnknownIdentity
+func (s *IdentityService) Resolve(ctx context.Context, provider, login string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "IdentityService.Resolve")
+	defer endSpan(span, &err)
+
+	provider, login, err = normalizeIdentity(provider, login)
+	if err != nil {
+		return "", err
+	}
+	userID, err := s.store.ResolveIdentity(ctx, provider, login)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("%w: %s %s", ErrUnknownIdentity, provider, login)
+	}
+	return userID, err
+}
+
+// ResolveExternalID возвращает user_id, к которому привязан внешний ID, или ErrUnknownIdentity
+func (s *IdentityService) ResolveExternalID(ctx context.Context, provider, externalID string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "IdentityService.ResolveExternalID")
+	defer endSpan(span, &err)
+
+	userID, err := s.store.ResolveExternalID(ctx, provider, externalID)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("%w: %s id %s", ErrUnknownIdentity, provider, externalID)
+	}
+	return userID, err
+}
+
+func normalizeIdentity(provider, login string) (string, string, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	login = strings.ToLower(strings.TrimSpace(login))
+	if !identityProviders[provider] || login == "" {
+		return "", "", ErrInvalidIdentity
+	}
+	return provider, login, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// fakeIdentityStore — in-memory реализация IdentityStore
+type fakeIdentityStore struct {
+	users       map[string]bool
+	identities  map[[2]string]string // (provider, login) -> user_id
+	externalIDs map[[2]string]string // (provider, external_id) -> user_id
+}
+
+func newFakeIdentityStore(users ...string) *fakeIdentityStore {
+	f := &fakeIdentityStore{users: map[string]bool{}, identities: map[[2]string]string{}, externalIDs: map[[2]string]string{}}
+	for _, u := range users {
+		f.users[u] = true
+	}
+	return f
+}
+
+func (f *fakeIdentityStore) GetByID(ctx context.Context, userID string) (repo.UserRow, error) {
+	if !f.users[userID] {
+		return repo.UserRow{}, pgx.ErrNoRows
+	}
+	return repo.UserRow{UserID: userID}, nil
+}
+
+func (f *fakeIdentityStore) UpsertIdentity(ctx context.Context, row repo.IdentityRow) (repo.IdentityRow, error) {
+	f.identities[[2]string{row.Provider, row.Login}] = row.UserID
+	if row.ExternalID != "" {
+		f.externalIDs[[2]string{row.Provider, row.ExternalID}] = row.UserID
+	}
+	return row, nil
+}
+
+func (f *fakeIdentityStore) ListIdentities(ctx context.Context, userID string) ([]repo.IdentityRow, error) {
+	var out []repo.IdentityRow
+	for k, u := range f.identities {
+		if u == userID {
+			out = append(out, repo.IdentityRow{Provider: k[0], Login: k[1], UserID: u})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIdentityStore) ResolveIdentity(ctx context.Context, provider, login string) (string, error) {
+	u, ok := f.identities[[2]string{provider, login}]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return u, nil
+}
+
+func (f *fakeIdentityStore) ResolveExternalID(ctx context.Context, provider, externalID string) (string, error) {
+	u, ok := f.externalIDs[[2]string{provider, externalID}]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return u, nil
+}
+
+func (f *fakeIdentityStore) DeleteIdentity(ctx context.Context, provider, login string) error {
+	k := [2]string{provider, login}
+	if _, ok := f.identities[k]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(f.identities, k)
+	return nil
+}
+
+func TestIdentityService_LinkAndResolveIgnoreCase(t *testing.T) {
+	store := newFakeIdentityStore("u1", "u2")
+	svc := NewIdentityService(store)
+	ctx := context.Background()
+
+	row, err := svc.Link(ctx, "GitHub", " Zakhar-Dev ", "u1", "")
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if row.Provider != ProviderGitHub || row.Login != "zakhar-dev" {
+		t.Fatalf("identity must be stored normalized, got %+v", row)
+	}
+	if got, err := svc.Resolve(ctx, ProviderGitHub, "ZAKHAR-DEV"); err != nil || got != "u1" {
+		t.Fatalf("resolve = %q, %v; want u1", got, err)
+	}
+
+	// Занятый логин перепривязывается
+	if _, err := svc.Link(ctx, ProviderGitHub, "zakhar-dev", "u2", ""); err != nil {
+		t.Fatalf("relink: %v", err)
+	}
+	if got, _ := svc.Resolve(ctx, ProviderGitHub, "zakhar-dev"); got != "u2" {
+		t.Fatalf("resolve after relink = %q, want u2", got)
+	}
+
+	// Внешний ID GitLab
+	if _, err := svc.Link(ctx, ProviderGitLab, "zakhar", "u1", " 51 "); err != nil {
+		t.Fatalf("link with external id: %v", err)
+	}
+	if got, err := svc.ResolveExternalID(ctx, ProviderGitLab, "51"); err != nil || got != "u1" {
+		t.Fatalf("resolve external id = %q, %v; want u1", got, err)
+	}
+	if _, err := svc.ResolveExternalID(ctx, ProviderGitLab, "52"); !errors.Is(err, ErrUnknownIdentity) {
+		t.Fatalf("unlinked external id: want ErrUnknownIdentity, got %v", err)
+	}
+}
+
+func TestIdentityService_Errors(t *testing.T) {
+	svc := NewIdentityService(newFakeIdentityStore("u1"))
+	ctx := context.Background()
+
+	if _, err := svc.Link(ctx, "bitbucket", "someone", "u1", ""); !errors.Is(err, ErrInvalidIdentity) {
+		t.Fatalf("unknown provider: want ErrInvalidIdentity, got %v", err)
+	}
+	if _, err := svc.Link(ctx, ProviderGitHub, "  ", "u1", ""); !errors.Is(err, ErrInvalidIdentity) {
+		t.Fatalf("empty login: want ErrInvalidIdentity, got %v", err)
+	}
+	if _, err := svc.Link(ctx, ProviderGitHub, "ghost", "u9", ""); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("missing user: want ErrUserNotFound, got %v", err)
+	}
+	if _, err := svc.Link(ctx, ProviderGitLab, "ghost", "u1", strings.Repeat("9", 101)); !errors.Is(err, ErrInvalidIdentity) {
+		t.Fatalf("long external_id: want ErrInvalidIdentity, got %v", err)
+	}
+	if _, err := svc.Resolve(ctx, ProviderGitHub, "ghost"); !errors.Is(err, ErrUnknownIdentity) {
+		t.Fatalf("unlinked login: want ErrUnknownIdentity, got %v", err)
+	}
+	if err := svc.Unlink(ctx, ProviderGitHub, "ghost"); !errors.Is(err, ErrIdentityNotFound) {
+		t.Fatalf("unlink missing: want ErrIdentityNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quasttyy/pr-reviewer/internal/domain"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// MergePolicy — условия, которые должны выполняться для merge PR.
+// Нулевое значение ничего не проверяет
+type MergePolicy struct {
+	// RequiredApprovals — сколько назначенных ревьюверов должны ответить APPROVED
+	RequiredApprovals int
+	// BlockOnChangesRequested запрещает merge, пока у кого-то из ревьюверов CHANGES_REQUESTED
+	BlockOnChangesRequested bool
+	// BlockWhenNeedMoreReviewers запрещает merge PR с need_more_reviewers
+	BlockWhenNeedMoreReviewers bool
+}
+
+// Коды невыполненных условий merge
+const (
+	ConditionRequiredApprovals = "required_approvals"
+	ConditionChangesRequested  = "changes_requested"
+	ConditionNeedMoreReviewers = "need_more_reviewers"
+)
+
+// UnmetCondition — условие политики merge, которое PR не выполняет
+type UnmetCondition struct {
+	Code    string
+	Message string
+}
+
+// MergeBlockedError перечисляет невыполненные условия. errors.Is(err, ErrMergeBlocked) == true
+type MergeBlockedError struct {
+	Unmet []UnmetCondition
+}
+
+func (e *MergeBlockedError) Error() string {
+	codes := make([]string, 0, len(e.Unmet))
+	for _, c := range e.Unmet {
+		codes = append(codes, c.Code)
+	}
+	return ErrMergeBlocked.Error() + ": " + strings.Join(codes, ", ")
+}
+
+func (e *MergeBlockedError) Unwrap() error { return ErrMergeBlocked }
+
+// Check возвращает условия политики, которые PR не выполняет
+func (p MergePolicy) Check(pr repo.PRFull) []UnmetCondition {
+	var approvals int
+	var changesRequested []string
+	for _, rv := range pr.Reviewers {
+		switch domain.ReviewVerdict(rv.Verdict) {
+		case domain.VerdictApproved:
+			approvals++
+		case domain.VerdictChangesRequested:
+			changesRequested = append(changesRequested, rv.ReviewerID)
+		}
+	}
+
+	var unmet []UnmetCondition
+	if approvals < p.RequiredApprovals {
+		unmet = append(unmet, UnmetCondition{
+			Code:    ConditionRequiredApprovals,
+			Message: fmt.Sprintf("%d of %d required approvals", approvals, p.RequiredApprovals),
+		})
+	}
+	if p.BlockOnChangesRequested && len(changesRequested) > 0 {
+		unmet = append(unmet, UnmetCondition{
+			Code:    ConditionChangesRequested,
+			Message: "changes requested by " + strings.Join(changesRequested, ", "),
+		})
+	}
+	if p.BlockWhenNeedMoreReviewers && pr.NeedMoreReviewers {
+		unmet = append(unmet, UnmetCondition{
+			Code:    ConditionNeedMoreReviewers,
+			Message: "PR needs more reviewers",
+		})
+	}
+	return unmet
+}
@@ -0,0 +1,26 @@
+package service
+
+// Metrics получает доменные события PRService и UserService, например для экспорта в Prometheus
+type Metrics interface {
+	PRCreated()
+	PRMerged()
+	ReviewerReassigned()
+	NoCandidate()
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) PRCreated()          {}
+func (noopMetrics) PRMerged()           {}
+func (noopMetrics) ReviewerReassigned() {}
+func (noopMetrics) NoCandidate()        {}
+
+// SetMetrics подключает сбор доменных метрик. По умолчанию события никуда не пишутся
+func (s *PRService) SetMetrics(m Metrics) {
+	s.metrics = m
+}
+
+// SetMetrics подключает сбор метрик переназначений при деактивации пользователя
+func (s *UserService) SetMetrics(m Metrics) {
+	s.metrics = m
+}
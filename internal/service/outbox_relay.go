@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+const (
+	// relayBatch — сколько событий outbox приёмник берёт за раз
+	relayBatch = 100
+	// relayLease — на сколько взятые события скрываются от других экземпляров сервиса
+	relayLease = 5 * time.Minute
+)
+
+// OutboxStore — таблица outbox и попытки публикации её событий в приёмники
+type OutboxStore interface {
+	ClaimOutbox(ctx context.Context, sink string, now time.Time, limit int, leaseUntil time.Time) ([]repo.OutboxRow, error)
+	MarkOutboxDelivered(ctx context.Context, sink string, ids []int64, at time.Time) error
+	RecordOutboxAttempt(ctx context.Context, id int64, sink string, a repo.DeliveryAttempt) error
+	ReleaseOutbox(ctx context.Context, sink string, ids []int64, at time.Time) error
+	MarkOutboxDispatched(ctx context.Context, sinks []string, at time.Time) (int64, error)
+	DeleteDispatchedOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Relay периодически публикует события из outbox в приёмники. Каждый приёмник
+// получает события по порядку записи и независимо от остальных: если он отклонил
+// событие, оно и следующие за ним ждут повтора по RetryPolicy только в этом приёмнике,
+// а после MaxAttempts попыток приёмник пропускает событие. События берутся из БД
+// короткой операцией, публикация идёт вне транзакции. Доставка at-least-once: после
+// падения сервиса приёмник может получить событие повторно. Событие считается
+// опубликованным, когда его обработали все приёмники; опубликованные хранятся
+// retention и затем удаляются
+type Relay struct {
+	store     OutboxStore
+	sinks     []outbox.Sink
+	retry     RetryPolicy
+	interval  time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRelay создаёт relay; retention = 0 хранит опубликованные события бессрочно
+func NewRelay(store OutboxStore, sinks []outbox.Sink, retry RetryPolicy, interval, retention time.Duration) *Relay {
+	return &Relay{store: store, sinks: sinks, retry: retry, interval: interval, retention: retention, now: time.Now}
+}
+
+// Run публикует события раз в interval, пока не отменён ctx
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce публикует накопившиеся события во все приёмники параллельно,
+// отмечает опубликованные и удаляет устаревшие
+func (r *Relay) RunOnce(ctx context.Context) {
+	var wg sync.WaitGroup
+	names := make([]string, 0, len(r.sinks))
+	for _, sink := range r.sinks {
+		names = append(names, sink.Name())
+		wg.Go(func() { r.relaySink(ctx, sink) })
+	}
+	wg.Wait()
+
+	if _, err := r.store.MarkOutboxDispatched(ctx, names, r.now()); err != nil {
+		logger.Error("outbox dispatch marking failed", "error", err)
+	}
+	if r.retention > 0 {
+		deleted, err := r.store.DeleteDispatchedOutbox(ctx, r.now().Add(-r.retention))
+		if err != nil {
+			logger.Error("outbox cleanup failed", "error", err)
+		} else if deleted > 0 {
+			logger.Info("outbox cleaned up", "deleted", deleted)
+		}
+	}
+}
+
+func (r *Relay) relaySink(ctx context.Context, sink outbox.Sink) {
+	for {
+		more, err := r.relayBatch(ctx, sink)
+		if err != nil {
+			logger.Error("outbox relay failed", "sink", sink.Name(), "error", err)
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+// relayBatch публикует в приёмник одну пачку событий. more — пачка опубликована
+// целиком и за ней могут быть ещё события
+func (r *Relay) relayBatch(ctx context.Context, sink outbox.Sink) (more bool, err error) {
+	now := r.now()
+	rows, err := r.store.ClaimOutbox(ctx, sink.Name(), now, relayBatch, now.Add(relayLease))
+	if err != nil {
+		return false, err
+	}
+	var delivered []int64
+	for i, row := range rows {
+		perr := sink.Publish(ctx, outbox.Message{
+			ID:          row.ID,
+			Type:        row.EventType,
+			AggregateID: row.AggregateID,
+			Payload:     row.Payload,
+			CreatedAt:   row.CreatedAt,
+		})
+		if perr == nil {
+			delivered = append(delivered, row.ID)
+			continue
+		}
+		retry, err := r.recordFailure(ctx, sink.Name(), row, perr)
+		if err != nil {
+			return false, err
+		}
+		if retry {
+			// Следующие события ждут повтора этого, чтобы сохранить порядок
+			rest := make([]int64, 0, len(rows)-i-1)
+			for _, next := range rows[i+1:] {
+				rest = append(rest, next.ID)
+			}
+			if len(rest) > 0 {
+				if err := r.store.ReleaseOutbox(ctx, sink.Name(), rest, r.now()); err != nil {
+					return false, err
+				}
+			}
+			rows = rows[:i+1]
+			break
+		}
+	}
+	if len(delivered) > 0 {
+		if err := r.store.MarkOutboxDelivered(ctx, sink.Name(), delivered, r.now()); err != nil {
+			return false, err
+		}
+	}
+	return len(rows) == relayBatch, nil
+}
+
+// recordFailure записывает неудачную попытку. retry — событие будет опубликовано
+// снова после задержки; false — попытки исчерпаны и приёмник пропускает событие
+func (r *Relay) recordFailure(ctx context.Context, sink string, row repo.OutboxRow, perr error) (retry bool, err error) {
+	attempts := row.Attempts + 1
+	now := r.now()
+	a := repo.DeliveryAttempt{Status: repo.DeliveryPending, Error: perr.Error(), At: now.Add(r.retry.Backoff(attempts))}
+	if attempts >= r.retry.MaxAttempts {
+		a.Status, a.At = repo.DeliveryFailed, now
+	}
+	logger.Warn("outbox publish failed",
+		"sink", sink,
+		"outbox_id", row.ID,
+		"type", row.EventType,
+		"attempts", attempts,
+		"status", a.Status,
+		"error", perr,
+	)
+	if err := r.store.RecordOutboxAttempt(ctx, row.ID, sink, a); err != nil {
+		return false, err
+	}
+	return a.Status == repo.DeliveryPending, nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/outbox"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+func outboxMessage(id int64, eventType, payload string) outbox.Message {
+	return outbox.Message{ID: id, Type: eventType, AggregateID: "pr-1", Payload: json.RawMessage(payload)}
+}
+
+// fakeOutboxStore — in-memory таблица outbox и попытки публикации по приёмникам
+type fakeOutboxStore struct {
+	mu         sync.Mutex
+	rows       []repo.OutboxRow
+	deliveries map[string]map[int64]*repo.DeliveryAttempt
+	attempts   map[string]map[int64]int
+	dispatched map[int64]time.Time
+}
+
+func newFakeOutboxStore(types ...string) *fakeOutboxStore {
+	f := &fakeOutboxStore{
+		deliveries: map[string]map[int64]*repo.DeliveryAttempt{},
+		attempts:   map[string]map[int64]int{},
+		dispatched: map[int64]time.Time{},
+	}
+	for i, typ := range types {
+		f.rows = append(f.rows, repo.OutboxRow{ID: int64(i + 1), EventType: typ, AggregateID: "pr-1", Payload: []byte(`{}`)})
+	}
+	return f
+}
+
+func (f *fakeOutboxStore) sink(name string) (map[int64]*repo.DeliveryAttempt, map[int64]int) {
+	if f.deliveries[name] == nil {
+		f.deliveries[name] = map[int64]*repo.DeliveryAttempt{}
+		f.attempts[name] = map[int64]int{}
+	}
+	return f.deliveries[name], f.attempts[name]
+}
+
+func (f *fakeOutboxStore) ClaimOutbox(ctx context.Context, sink string, now time.Time, limit int, leaseUntil time.Time) ([]repo.OutboxRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dels, attempts := f.sink(sink)
+	var out []repo.OutboxRow
+	for _, row := range f.rows {
+		if _, ok := f.dispatched[row.ID]; ok || len(out) == limit {
+			continue
+		}
+		d := dels[row.ID]
+		if d != nil && d.Status != repo.DeliveryPending {
+			continue
+		}
+		// Событие ждёт повтора: оно и следующие за ним не берутся
+		if d != nil && d.At.After(now) {
+			break
+		}
+		dels[row.ID] = &repo.DeliveryAttempt{Status: repo.DeliveryPending, At: leaseUntil}
+		row.Attempts = attempts[row.ID]
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func (f *fakeOutboxStore) MarkOutboxDelivered(ctx context.Context, sink string, ids []int64, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dels, attempts := f.sink(sink)
+	for _, id := range ids {
+		dels[id] = &repo.DeliveryAttempt{Status: repo.DeliveryDelivered, At: at}
+		attempts[id]++
+	}
+	return nil
+}
+
+func (f *fakeOutboxStore) RecordOutboxAttempt(ctx context.Context, id int64, sink string, a repo.DeliveryAttempt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dels, attempts := f.sink(sink)
+	dels[id] = &a
+	attempts[id]++
+	return nil
+}
+
+func (f *fakeOutboxStore) ReleaseOutbox(ctx context.Context, sink string, ids []int64, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dels, _ := f.sink(sink)
+	for _, id := range ids {
+		dels[id].At = at
+	}
+	return nil
+}
+
+func (f *fakeOutboxStore) MarkOutboxDispatched(ctx context.Context, sinks []string, at time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for _, row := range f.rows {
+		if _, ok := f.dispatched[row.ID]; ok {
+			continue
+		}
+		done := true
+		for _, name := range sinks {
+			dels, _ := f.sink(name)
+			if d := dels[row.ID]; d == nil || d.Status == repo.DeliveryPending {
+				done = false
+			}
+		}
+		if done {
+			f.dispatched[row.ID] = at
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeOutboxStore) DeleteDispatchedOutbox(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []repo.OutboxRow
+	var n int64
+	for _, row := range f.rows {
+		if at, ok := f.dispatched[row.ID]; ok && at.Before(before) {
+			n++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	f.rows = kept
+	return n, nil
+}
+
+// recordingSink запоминает принятые события; failOn — id события, которое он отклоняет
+type recordingSink struct {
+	name   string
+	got    []int64
+	failOn int64
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Publish(ctx context.Context, m outbox.Message) error {
+	if m.ID == s.failOn {
+		return errors.New("sink unavailable")
+	}
+	s.got = append(s.got, m.ID)
+	return nil
+}
+
+var testRelayRetry = RetryPolicy{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Hour}
+
+func TestRelay_SinkFailureDoesNotBlockOtherSinks(t *testing.T) {
+	logger.Init("test")
+	ctx := context.Background()
+	store := newFakeOutboxStore(repo.EventCreated, repo.EventAssigned, repo.EventAssigned)
+	first, second := &recordingSink{name: "first"}, &recordingSink{name: "second", failOn: 2}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	relay := NewRelay(store, []outbox.Sink{first, second}, testRelayRetry, time.Second, 0)
+	relay.now = func() time.Time { return now }
+
+	// Второй приёмник отклоняет событие 2: в нём событие 2 и следующее ждут повтора,
+	// первый приёмник получает все события
+	relay.RunOnce(ctx)
+	if !reflect.DeepEqual(first.got, []int64{1, 2, 3}) || !reflect.DeepEqual(second.got, []int64{1}) {
+		t.Fatalf("first = %v, second = %v", first.got, second.got)
+	}
+	if len(store.dispatched) != 1 {
+		t.Fatalf("dispatched = %v, want only event 1", store.dispatched)
+	}
+
+	// До истечения задержки повтора нет, первый приёмник не получает события снова
+	second.failOn = 0
+	now = now.Add(30 * time.Second)
+	relay.RunOnce(ctx)
+	if !reflect.DeepEqual(first.got, []int64{1, 2, 3}) || !reflect.DeepEqual(second.got, []int64{1}) {
+		t.Fatalf("before backoff: first = %v, second = %v", first.got, second.got)
+	}
+
+	now = now.Add(time.Minute)
+	relay.RunOnce(ctx)
+	if !reflect.DeepEqual(first.got, []int64{1, 2, 3}) || !reflect.DeepEqual(second.got, []int64{1, 2, 3}) {
+		t.Fatalf("after backoff: first = %v, second = %v", first.got, second.got)
+	}
+	if len(store.dispatched) != 3 {
+		t.Fatalf("dispatched = %v, want all events", store.dispatched)
+	}
+}
+
+func TestRelay_SkipsEventAfterMaxAttempts(t *testing.T) {
+	logger.Init("test")
+	ctx := context.Background()
+	store := newFakeOutboxStore(repo.EventCreated, repo.EventMerged)
+	sink := &recordingSink{name: "sink", failOn: 1}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	relay := NewRelay(store, []outbox.Sink{sink}, testRelayRetry, time.Second, 0)
+	relay.now = func() time.Time { return now }
+
+	for i := 0; i < testRelayRetry.MaxAttempts; i++ {
+		relay.RunOnce(ctx)
+		now = now.Add(time.Hour)
+	}
+	if !reflect.DeepEqual(sink.got, []int64{2}) {
+		t.Fatalf("got = %v, want event 2 after event 1 is given up", sink.got)
+	}
+	if d := store.deliveries["sink"][1]; d.Status != repo.DeliveryFailed || store.attempts["sink"][1] != testRelayRetry.MaxAttempts {
+		t.Fatalf("event 1: %+v after %d attempts", d, store.attempts["sink"][1])
+	}
+	if len(store.dispatched) != 2 {
+		t.Fatalf("dispatched = %v, want both events", store.dispatched)
+	}
+}
+
+func TestRelay_DeletesDispatchedAfterRetention(t *testing.T) {
+	logger.Init("test")
+	ctx := context.Background()
+	store := newFakeOutboxStore(repo.EventCreated, repo.EventMerged)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	relay := NewRelay(store, []outbox.Sink{&recordingSink{name: "sink"}}, testRelayRetry, time.Second, time.Hour)
+	relay.now = func() time.Time { return now }
+
+	relay.RunOnce(ctx)
+	if len(store.rows) != 2 {
+		t.Fatalf("dispatched events must be retained, have %d rows", len(store.rows))
+	}
+	now = now.Add(time.Hour + time.Second)
+	relay.RunOnce(ctx)
+	if len(store.rows) != 0 {
+		t.Fatalf("events older than retention must be deleted, have %d rows", len(store.rows))
+	}
+}
+
+func TestEventFromOutbox(t *testing.T) {
+	const snapshot = `"pull_request_id":"pr-1","pull_request_name":"Add search","author_id":"u1","status":"OPEN","assigned_reviewers":["u3"],"actor":"admin","occurred_at":"2025-01-01T12:00:00Z"`
+	cases := []struct {
+		name     string
+		msg      outbox.Message
+		ok       bool
+		typ      string
+		reviewer string
+		old      string
+	}{
+		{"created", outboxMessage(1, repo.EventCreated, `{`+snapshot+`,"user_id":"u1"}`), true, EventPRCreated, "", ""},
+		{"assigned", outboxMessage(2, repo.EventAssigned, `{`+snapshot+`,"user_id":"u3"}`), true, EventReviewerAssigned, "u3", ""},
+		{"reassigned", outboxMessage(3, repo.EventReassigned, `{`+snapshot+`,"from_user_id":"u2","to_user_id":"u3","reason":"vacation"}`), true, EventReviewerReassigned, "u3", "u2"},
+		{"merged", outboxMessage(4, repo.EventMerged, `{`+snapshot+`,"reason":"forced"}`), true, EventPRMerged, "", ""},
+		{"not for subscribers", outboxMessage(5, repo.EventReviewed, `{`+snapshot+`}`), false, "", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ev, ok, err := EventFromOutbox(tc.msg)
+			if err != nil || ok != tc.ok {
+				t.Fatalf("ok = %v, err = %v; want ok = %v", ok, err, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			if ev.Type != tc.typ || ev.Data.ReviewerID != tc.reviewer || ev.Data.OldReviewerID != tc.old {
+				t.Fatalf("event = %+v", ev)
+			}
+			if ev.ID != strconv.FormatInt(tc.msg.ID, 10) {
+				t.Fatalf("event id = %q, want outbox id", ev.ID)
+			}
+			d := ev.Data
+			if d.PullRequestName != "Add search" || d.AuthorID != "u1" || d.Actor != "admin" || !reflect.DeepEqual(d.AssignedReviewers, []string{"u3"}) {
+				t.Fatalf("data = %+v", d)
+			}
+			if !ev.OccurredAt.Equal(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)) {
+				t.Fatalf("occurred_at = %v", ev.OccurredAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/domain"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// prTransitions — допустимые переходы жизненного цикла PR:
+//
+//	DRAFT  -> OPEN (ready), CLOSED
+//	OPEN   -> MERGED, CLOSED, DRAFT (draft)
+//	CLOSED -> OPEN (reopen), DRAFT (reopen черновика)
+//
+// MERGED — конечное состояние
+var prTransitions = map[domain.PRStatus][]domain.PRStatus{
+	domain.PRStatusDraft:  {domain.PRStatusOpen, domain.PRStatusClosed},
+	domain.PRStatusOpen:   {domain.PRStatusMerged, domain.PRStatusClosed, domain.PRStatusDraft},
+	domain.PRStatusClosed: {domain.PRStatusOpen, domain.PRStatusDraft},
+}
+
+// TransitionError — недопустимый переход статуса PR. errors.Is(err, ErrInvalidTransition) == true.
+// From == To означает, что PR уже в нужном статусе
+type TransitionError struct {
+	From string
+	To   domain.PRStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidTransition.Error(), e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error { return ErrInvalidTransition }
+
+// checkTransition возвращает TransitionError, если из from нельзя перейти в to
+func checkTransition(from string, to domain.PRStatus) error {
+	for _, allowed := range prTransitions[domain.PRStatus(from)] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to}
+}
+
+// CreateDraft создаёт PR в статусе DRAFT. Ревьюверы не назначаются,
+// пока PR не будет переведён в OPEN через Ready
+func (s *PRService) CreateDraft(ctx context.Context, prID, prName, authorID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.CreateDraft")
+	defer endSpan(span, &err)
+
+	if _, err := s.prs.GetUserTeam(ctx, authorID); err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.PRFull{}, ErrNotFoundUser
+		}
+		return repo.PRFull{}, err
+	}
+	if err := s.prs.CreatePRDraft(ctx, prID, prName, authorID, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			return repo.PRFull{}, ErrPRExists
+		}
+		return repo.PRFull{}, err
+	}
+	s.metrics.PRCreated()
+	return s.prs.GetPR(ctx, prID)
+}
+
+// Ready переводит DRAFT PR в OPEN и назначает ревьюверов по правилам Create.
+// Если PR уже ревьюили до возврата в черновик, назначения сохраняются, а
+// недостающие ревьюверы добираются по правилам TopUp в той же транзакции
+func (s *PRService) Ready(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Ready")
+	defer endSpan(span, &err)
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.getForTransition(ctx, prID, domain.PRStatusOpen)
+		if err != nil {
+			return err
+		}
+		if pr.Status != string(domain.PRStatusDraft) {
+			return &TransitionError{From: pr.Status, To: domain.PRStatusOpen}
+		}
+		if len(pr.Assigned) > 0 {
+			return s.readyAgain(ctx, pr)
+		}
+		reviewers, cov, err := s.initialReviewers(ctx, pr.AuthorID)
+		if err != nil {
+			return err
+		}
+		meta := repo.EventMeta{Actor: ActorFromContext(ctx)}
+		if err := s.prs.MarkReady(ctx, prID, reviewers, cov, meta); err != nil {
+			return transitionErr(err, pr.Status, domain.PRStatusOpen)
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// readyAgain вызывается в транзакции Ready
+func (s *PRService) readyAgain(ctx context.Context, pr repo.PRFull) error {
+	cov := repo.Coverage{NeedMore: pr.NeedMoreReviewers, CapacityExhausted: pr.CapacityExhausted}
+	if err := s.prs.MarkReady(ctx, pr.ID, nil, cov, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+		return transitionErr(err, pr.Status, domain.PRStatusOpen)
+	}
+	_, err := s.TopUp(ctx, pr.ID)
+	return err
+}
+
+// MarkDraft возвращает OPEN PR в черновик. Назначения и решения сохраняются,
+// но, пока PR черновик, он не считается нагрузкой ревьюверов и не добирается
+func (s *PRService) MarkDraft(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.MarkDraft")
+	defer endSpan(span, &err)
+
+	pr, err := s.getForTransition(ctx, prID, domain.PRStatusDraft)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	if pr.Status != string(domain.PRStatusOpen) {
+		return repo.PRFull{}, &TransitionError{From: pr.Status, To: domain.PRStatusDraft}
+	}
+	if err := s.prs.MarkDraft(ctx, prID, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+		return repo.PRFull{}, transitionErr(err, pr.Status, domain.PRStatusDraft)
+	}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// Close закрывает DRAFT или OPEN PR без merge. Назначения сохраняются,
+// но закрытый PR не считается нагрузкой ревьюверов
+func (s *PRService) Close(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Close")
+	defer endSpan(span, &err)
+
+	pr, err := s.getForTransition(ctx, prID, domain.PRStatusClosed)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	if err := s.prs.MarkClosed(ctx, prID, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+		return repo.PRFull{}, transitionErr(err, pr.Status, domain.PRStatusClosed)
+	}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// Reopen возвращает CLOSED PR в OPEN. Если ревьюверов меньше min_reviewers
+// (например, PR закрыли черновиком), они добираются по правилам TopUp
+// в той же транзакции
+func (s *PRService) Reopen(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Reopen")
+	defer endSpan(span, &err)
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.getForTransition(ctx, prID, domain.PRStatusOpen)
+		if err != nil {
+			return err
+		}
+		if pr.Status != string(domain.PRStatusClosed) {
+			return &TransitionError{From: pr.Status, To: domain.PRStatusOpen}
+		}
+		if err := s.prs.Reopen(ctx, prID, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+			return transitionErr(err, pr.Status, domain.PRStatusOpen)
+		}
+		_, err = s.TopUp(ctx, prID)
+		return err
+	})
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// ReopenDraft возвращает CLOSED PR в черновик, как при reopen черновика во внешней
+// системе. Ревьюверы не добираются, пока PR не будет переведён в OPEN через Ready
+func (s *PRService) ReopenDraft(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.ReopenDraft")
+	defer endSpan(span, &err)
+
+	pr, err := s.getForTransition(ctx, prID, domain.PRStatusDraft)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	if pr.Status != string(domain.PRStatusClosed) {
+		return repo.PRFull{}, &TransitionError{From: pr.Status, To: domain.PRStatusDraft}
+	}
+	if err := s.prs.ReopenDraft(ctx, prID, repo.EventMeta{Actor: ActorFromContext(ctx)}); err != nil {
+		return repo.PRFull{}, transitionErr(err, pr.Status, domain.PRStatusDraft)
+	}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// getForTransition загружает PR и проверяет, что из его статуса можно перейти в to.
+// В транзакции PR остаётся заблокированным до её конца
+func (s *PRService) getForTransition(ctx context.Context, prID string, to domain.PRStatus) (repo.PRFull, error) {
+	pr, err := s.prs.GetPRForUpdate(ctx, prID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.PRFull{}, ErrNotFoundPR
+		}
+		return repo.PRFull{}, err
+	}
+	if err := checkTransition(pr.Status, to); err != nil {
+		return repo.PRFull{}, err
+	}
+	return pr, nil
+}
+
+// transitionErr: репозиторий возвращает pgx.ErrNoRows, если статус PR
+// изменился между проверкой и обновлением
+func transitionErr(err error, from string, to domain.PRStatus) error {
+	if err == pgx.ErrNoRows {
+		return &TransitionError{From: from, To: to}
+	}
+	return err
+}
@@ -3,82 +3,210 @@ package service
 import (
 	"context"
 	"errors"
-	"math/rand"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/domain"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
-)
-
-var (
-	ErrPRExists     = errors.New("pr exists")
-	ErrPRMerged     = errors.New("pr merged")
-	ErrNotAssigned  = errors.New("not assigned")
-	ErrNoCandidate  = errors.New("no candidate")
-	ErrNotFoundPR   = errors.New("pr not found")
-	ErrNotFoundUser = errors.New("user not found")
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
 )
 
 type PRService struct {
-	prs PRStore
+	prs       PRStore
+	teams     TeamSettingsStore
+	selectors *SelectorRegistry
+	policy    MergePolicy
+	hours     WorkingHoursPolicy
+	metrics   Metrics
+	tx        Transactor
+	now       func() time.Time
 }
 
 type PRStore interface {
 	GetUserTeam(ctx context.Context, userID string) (string, error)
 	GetActiveCandidatesFromTeamExcluding(ctx context.Context, teamName, excludeUserID string) ([]string, error)
-	CreatePROpenWithAssigned(ctx context.Context, id, name, author string, needMore bool, reviewers []string) error
+	CreatePROpenWithAssigned(ctx context.Context, id, name, author string, cov repo.Coverage, reviewers []repo.Assignment, meta repo.EventMeta) error
+	CreatePRDraft(ctx context.Context, id, name, author string, meta repo.EventMeta) error
 	GetPR(ctx context.Context, id string) (repo.PRFull, error)
-	MarkMerged(ctx context.Context, id string) error
-	ReplaceReviewer(ctx context.Context, prID, oldReviewer, newReviewer string) error
-	GetReviewerStats(ctx context.Context) ([]repo.ReviewerStatRow, error)
+	GetPRForUpdate(ctx context.Context, id string) (repo.PRFull, error)
+	MarkMerged(ctx context.Context, id string, meta repo.EventMeta) error
+	MarkReady(ctx context.Context, id string, reviewers []repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error
+	MarkClosed(ctx context.Context, id string, meta repo.EventMeta) error
+	MarkDraft(ctx context.Context, id string, meta repo.EventMeta) error
+	Reopen(ctx context.Context, id string, meta repo.EventMeta) error
+	ReopenDraft(ctx context.Context, id string, meta repo.EventMeta) error
+	ReplaceReviewer(ctx context.Context, prID, oldReviewer string, newReviewer repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error
+	RemoveReviewer(ctx context.Context, prID, reviewerID string, cov repo.Coverage, meta repo.EventMeta) error
+	AddReviewers(ctx context.Context, prID string, reviewers []repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error
+	SetVerdict(ctx context.Context, prID, reviewerID, verdict string, meta repo.EventMeta) error
+	ListEvents(ctx context.Context, prID string) ([]repo.PREventRow, error)
+	ListOpenUnderReviewed(ctx context.Context, defaultMin int) ([]string, error)
+	ListWorkingHours(ctx context.Context, userIDs []string) (map[string]repo.WorkingHoursRow, error)
+	ListReviewCapacity(ctx context.Context, userIDs []string) (map[string]repo.ReviewCapacityRow, error)
+	GetReviewerStats(ctx context.Context, f repo.StatsFilter) ([]repo.ReviewerStatRow, error)
+	ListCapacityExhausted(ctx context.Context) ([]string, error)
 }
 
-func NewPRService(prs PRStore) *PRService {
-	return &PRService{prs: prs}
+func NewPRService(prs PRStore, teams TeamSettingsStore, selectors *SelectorRegistry, policy MergePolicy, hours WorkingHoursPolicy, tx Transactor) *PRService {
+	return &PRService{prs: prs, teams: teams, selectors: selectors, policy: policy, hours: hours, metrics: noopMetrics{}, tx: tx, now: time.Now}
 }
 
-// Create назначает до двух активных ревьюеров из команды автора (кроме автора)
-func (s *PRService) Create(ctx context.Context, prID, prName, authorID string) (repo.PRFull, error) {
-	// найдём команду автора
-	team, err := s.prs.GetUserTeam(ctx, authorID)
+// Create назначает до max_reviewers активных ревьюеров из команды автора (кроме автора)
+// с помощью стратегии выбора, настроенной для этой команды. Недостающие места
+// добираются из резервных команд по порядку. Кандидаты, достигшие max_open_reviews,
+// пропускаются. Если назначено меньше min_reviewers, PR помечается need_more_reviewers,
+// а если из-за лимитов — ещё и capacity_exhausted
+func (s *PRService) Create(ctx context.Context, prID, prName, authorID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Create")
+	defer endSpan(span, &err)
+
+	reviewers, cov, err := s.initialReviewers(ctx, authorID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return repo.PRFull{}, ErrNotFoundUser
-		}
 		return repo.PRFull{}, err
 	}
-	candidates, err := s.prs.GetActiveCandidatesFromTeamExcluding(ctx, team, authorID)
-	if err != nil {
+	meta := repo.EventMeta{Actor: ActorFromContext(ctx)}
+	if err := s.prs.CreatePROpenWithAssigned(ctx, prID, prName, authorID, cov, reviewers, meta); err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			return repo.PRFull{}, ErrPRExists
+		}
 		return repo.PRFull{}, err
 	}
-	reviewers := chooseUpToTwoRandom(candidates)
-	needMore := len(reviewers) < 2
-	if err := s.prs.CreatePROpenWithAssigned(ctx, prID, prName, authorID, needMore, reviewers); err != nil {
-		return repo.PRFull{}, ErrPRExists
-	}
+	s.metrics.PRCreated()
 	return s.prs.GetPR(ctx, prID)
 }
 
-// Merge идемпотентно помечает PR как MERGED
-func (s *PRService) Merge(ctx context.Context, prID string) (repo.PRFull, error) {
-	pr, err := s.prs.GetPR(ctx, prID)
+// initialReviewers выбирает ревьюверов для нового (или готового к ревью) PR автора
+// и вычисляет флаги недобора
+func (s *PRService) initialReviewers(ctx context.Context, authorID string) ([]repo.Assignment, repo.Coverage, error) {
+	// найдём команду автора
+	team, err := s.userTeam(ctx, authorID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return repo.PRFull{}, ErrNotFoundPR
-		}
-		return repo.PRFull{}, err
+		return nil, repo.Coverage{}, err
 	}
-	if pr.Status == "MERGED" {
-		return pr, nil
+	settings, err := loadTeamSettings(ctx, s.teams, team)
+	if err != nil {
+		return nil, repo.Coverage{}, err
 	}
-	if err := s.prs.MarkMerged(ctx, prID); err != nil {
+	exclude := map[string]struct{}{authorID: {}}
+	reviewers, saturated, err := s.pickReviewers(ctx, teamChain(team, settings.FallbackTeams), exclude, settings.MaxReviewers)
+	if err != nil {
+		return nil, repo.Coverage{}, err
+	}
+	return reviewers, coverage(len(reviewers), settings.MinReviewers, saturated), nil
+}
+
+// userTeam возвращает команду пользователя или ErrNotFoundUser, если его нет
+func (s *PRService) userTeam(ctx context.Context, userID string) (string, error) {
+	team, err := s.prs.GetUserTeam(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return "", ErrNotFoundUser
+	}
+	return team, err
+}
+
+// coverage вычисляет флаги недобора для PR с assigned ревьюверами при кворуме min.
+// saturated — при выборе были пропущены кандидаты, достигшие своего лимита
+func coverage(assigned, min int, saturated bool) repo.Coverage {
+	needMore := assigned < min
+	return repo.Coverage{NeedMore: needMore, CapacityExhausted: needMore && saturated}
+}
+
+// Merge идемпотентно помечает PR как MERGED, если он выполняет политику merge.
+// Иначе возвращает *MergeBlockedError со списком невыполненных условий
+func (s *PRService) Merge(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Merge")
+	defer endSpan(span, &err)
+
+	return s.merge(ctx, prID, "")
+}
+
+// ForceMerge помечает PR как MERGED без проверки политики merge.
+// В журнал событий записывается причина forced
+func (s *PRService) ForceMerge(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.ForceMerge")
+	defer endSpan(span, &err)
+
+	return s.merge(ctx, prID, ReasonForcedMerge)
+}
+
+// MergeExternal помечает PR как MERGED, потому что его уже смержили во внешней
+// системе (вебхук). Политика merge не проверяется, в журнал записывается причина external
+func (s *PRService) MergeExternal(ctx context.Context, prID string) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.MergeExternal")
+	defer endSpan(span, &err)
+
+	return s.merge(ctx, prID, ReasonExternalMerge)
+}
+
+// merge помечает PR как MERGED. Непустой bypassReason отключает проверку политики
+// и записывается в журнал как причина. Проверка и запись идут в одной транзакции
+// под блокировкой PR, поэтому между ними не меняются ни статус, ни решения ревьюверов
+func (s *PRService) merge(ctx context.Context, prID string, bypassReason string) (repo.PRFull, error) {
+	merged := false
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.prs.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNotFoundPR
+			}
+			return err
+		}
+		if pr.Status == "MERGED" {
+			return nil
+		}
+		if err := checkTransition(pr.Status, domain.PRStatusMerged); err != nil {
+			return err
+		}
+		if bypassReason == "" {
+			if unmet := s.policy.Check(pr); len(unmet) > 0 {
+				return &MergeBlockedError{Unmet: unmet}
+			}
+		}
+		meta := repo.EventMeta{Actor: ActorFromContext(ctx), Reason: bypassReason}
+		if err := s.prs.MarkMerged(ctx, prID, meta); err != nil {
+			return transitionErr(err, pr.Status, domain.PRStatusMerged)
+		}
+		merged = true
+		return nil
+	})
+	if err != nil {
 		return repo.PRFull{}, err
 	}
+	if merged {
+		s.metrics.PRMerged()
+	}
 	return s.prs.GetPR(ctx, prID)
 }
 
-// Reassign заменяет одного ревьювера на случайного активного из его команды
-func (s *PRService) Reassign(ctx context.Context, prID, oldReviewerID string) (repo.PRFull, string, error) {
+// Reassign заменяет одного ревьювера на активного из его команды,
+// выбранного стратегией этой команды. Если там кандидатов нет, замена ищется
+// в команде автора и затем в её резервных командах. Автор PR и уже назначенные
+// ревьюверы не рассматриваются, как и достигшие max_open_reviews: если замены
+// нет только из-за лимитов, возвращается ErrCapacityExhausted. reason попадает в журнал событий
+func (s *PRService) Reassign(ctx context.Context, prID, oldReviewerID, reason string) (_ repo.PRFull, _ string, err error) {
+	ctx, span := startSpan(ctx, "PRService.Reassign")
+	defer endSpan(span, &err)
+
+	pr, newReviewer, err := s.reassign(ctx, prID, oldReviewerID, reason)
+	switch {
+	case err == nil:
+		s.metrics.ReviewerReassigned()
+	case errors.Is(err, ErrNoCandidate):
+		s.metrics.NoCandidate()
+	}
+	return pr, newReviewer, err
+}
+
+// ReassignInTx — Reassign без записи метрик, для вызова внутри транзакции
+// вызывающего: метрики он записывает сам после её фиксации
+func (s *PRService) ReassignInTx(ctx context.Context, prID, oldReviewerID, reason string) (_ repo.PRFull, _ string, err error) {
+	ctx, span := startSpan(ctx, "PRService.ReassignInTx")
+	defer endSpan(span, &err)
+
+	return s.reassign(ctx, prID, oldReviewerID, reason)
+}
+
+func (s *PRService) reassign(ctx context.Context, prID, oldReviewerID, reason string) (repo.PRFull, string, error) {
 	pr, err := s.prs.GetPR(ctx, prID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -89,6 +217,9 @@ func (s *PRService) Reassign(ctx context.Context, prID, oldReviewerID string) (r
 	if pr.Status == "MERGED" {
 		return repo.PRFull{}, "", ErrPRMerged
 	}
+	if pr.Status != "OPEN" {
+		return repo.PRFull{}, "", ErrPRNotOpen
+	}
 	// Убедимся, что oldReviewer назначен
 	found := false
 	for _, r := range pr.Assigned {
@@ -108,56 +239,358 @@ func (s *PRService) Reassign(ctx context.Context, prID, oldReviewerID string) (r
 		}
 		return repo.PRFull{}, "", err
 	}
-	candidates, err := s.prs.GetActiveCandidatesFromTeamExcluding(ctx, team, oldReviewerID)
+	// Кворум и резервные команды берём из настроек команды автора:
+	// они могли измениться после создания PR
+	authorTeam, err := s.userTeam(ctx, pr.AuthorID)
 	if err != nil {
 		return repo.PRFull{}, "", err
 	}
-	// Убираем тех, кто уже назначен
-	exclude := map[string]struct{}{}
+	settings, err := loadTeamSettings(ctx, s.teams, authorTeam)
+	if err != nil {
+		return repo.PRFull{}, "", err
+	}
+	exclude := map[string]struct{}{pr.AuthorID: {}}
 	for _, r := range pr.Assigned {
 		exclude[r] = struct{}{}
 	}
-	var pool []string
-	for _, id := range candidates {
-		if _, ok := exclude[id]; !ok {
-			pool = append(pool, id)
-		}
+	chain := teamChain(team, append([]string{authorTeam}, settings.FallbackTeams...))
+	picked, saturated, err := s.pickReviewers(ctx, chain, exclude, 1)
+	if err != nil {
+		return repo.PRFull{}, "", err
 	}
-	if len(pool) == 0 {
+	if len(picked) == 0 {
+		if saturated {
+			return repo.PRFull{}, "", ErrCapacityExhausted
+		}
 		return repo.PRFull{}, "", ErrNoCandidate
 	}
-	newReviewer := pool[rand.Intn(len(pool))]
-	if err := s.prs.ReplaceReviewer(ctx, prID, oldReviewerID, newReviewer); err != nil {
-		if err.Error() == "not assigned" {
+	newReviewer := picked[0]
+	// Число ревьюверов не меняется, поэтому причина недобора остаётся прежней
+	cov := coverage(len(pr.Assigned), settings.MinReviewers, pr.CapacityExhausted)
+	meta := repo.EventMeta{Actor: ActorFromContext(ctx), Reason: reason}
+	if err := s.prs.ReplaceReviewer(ctx, prID, oldReviewerID, newReviewer, cov, meta); err != nil {
+		if errors.Is(err, repo.ErrNotAssigned) {
 			return repo.PRFull{}, "", ErrNotAssigned
 		}
 		return repo.PRFull{}, "", err
 	}
 	pr2, err := s.prs.GetPR(ctx, prID)
-	return pr2, newReviewer, err
+	return pr2, newReviewer.ReviewerID, err
 }
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// Unassign снимает ревьювера с OPEN PR без замены, например когда замены не нашлось.
+// need_more_reviewers пересчитывается по настройкам команды автора. saturated — замены
+// нет из-за max_open_reviews (ErrCapacityExhausted): недобор помечается capacity_exhausted
+func (s *PRService) Unassign(ctx context.Context, prID, reviewerID, reason string, saturated bool) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.Unassign")
+	defer endSpan(span, &err)
+
+	pr, err := s.prs.GetPR(ctx, prID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.PRFull{}, ErrNotFoundPR
+		}
+		return repo.PRFull{}, err
+	}
+	if pr.Status == "MERGED" {
+		return repo.PRFull{}, ErrPRMerged
+	}
+	if pr.Status != "OPEN" {
+		return repo.PRFull{}, ErrPRNotOpen
+	}
+	authorTeam, err := s.userTeam(ctx, pr.AuthorID)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	settings, err := loadTeamSettings(ctx, s.teams, authorTeam)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	cov := coverage(len(pr.Assigned)-1, settings.MinReviewers, saturated || pr.CapacityExhausted)
+	meta := repo.EventMeta{Actor: ActorFromContext(ctx), Reason: reason}
+	if err := s.prs.RemoveReviewer(ctx, prID, reviewerID, cov, meta); err != nil {
+		if errors.Is(err, repo.ErrNotAssigned) {
+			return repo.PRFull{}, ErrNotAssigned
+		}
+		return repo.PRFull{}, err
+	}
+	return s.prs.GetPR(ctx, prID)
 }
 
-func chooseUpToTwoRandom(ids []string) []string {
-	if len(ids) <= 2 {
-		return append([]string(nil), ids...)
+// SubmitReview сохраняет решение назначенного ревьювера по OPEN PR
+// (APPROVED, CHANGES_REQUESTED или COMMENTED). Повторная отправка заменяет
+// предыдущее решение
+func (s *PRService) SubmitReview(ctx context.Context, prID, reviewerID string, verdict domain.ReviewVerdict) (_ repo.PRFull, err error) {
+	ctx, span := startSpan(ctx, "PRService.SubmitReview")
+	defer endSpan(span, &err)
+
+	if !verdict.Valid() {
+		return repo.PRFull{}, ErrInvalidVerdict
+	}
+	pr, err := s.prs.GetPR(ctx, prID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return repo.PRFull{}, ErrNotFoundPR
+		}
+		return repo.PRFull{}, err
+	}
+	if pr.Status == "MERGED" {
+		return repo.PRFull{}, ErrPRMerged
 	}
-	// Выбираем два случайных неповторяющихся
-	i := rand.Intn(len(ids))
-	j := rand.Intn(len(ids)-1)
-	if j >= i {
-		j++
+	if pr.Status != "OPEN" {
+		return repo.PRFull{}, ErrPRNotOpen
 	}
-	if i > j {
-		i, j = j, i
+	meta := repo.EventMeta{Actor: ActorFromContext(ctx)}
+	if err := s.prs.SetVerdict(ctx, prID, reviewerID, string(verdict), meta); err != nil {
+		if errors.Is(err, repo.ErrNotAssigned) {
+			return repo.PRFull{}, ErrNotAssigned
+		}
+		return repo.PRFull{}, err
 	}
-	return []string{ids[i], ids[j]}
+	return s.prs.GetPR(ctx, prID)
+}
+
+// TopUpResult — итог добора ревьюверов на один PR.
+// Changed — были ли добавлены ревьюверы или изменены флаги недобора
+type TopUpResult struct {
+	PR      repo.PRFull
+	Added   []string
+	Changed bool
 }
 
-// GetReviewerStats проксирует статистику назначений ревьюверов из репозитория.
-func (s *PRService) GetReviewerStats(ctx context.Context) ([]repo.ReviewerStatRow, error) {
-	return s.prs.GetReviewerStats(ctx)
+// TopUp добирает ревьюверов на OPEN PR, у которого их меньше min_reviewers:
+// до max_reviewers по тем же правилам, что и при создании. Когда кворум набран,
+// need_more_reviewers сбрасывается. PR с набранным кворумом не меняется.
+// Добор идёт в транзакции под блокировкой PR, поэтому параллельный добор,
+// merge или закрытие не пересекаются с ним
+func (s *PRService) TopUp(ctx context.Context, prID string) (_ TopUpResult, err error) {
+	ctx, span := startSpan(ctx, "PRService.TopUp")
+	defer endSpan(span, &err)
+
+	var res TopUpResult
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.prs.GetPRForUpdate(ctx, prID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNotFoundPR
+			}
+			return err
+		}
+		res.PR = pr
+		if pr.Status == "MERGED" {
+			return ErrPRMerged
+		}
+		if pr.Status != "OPEN" {
+			return ErrPRNotOpen
+		}
+		authorTeam, err := s.userTeam(ctx, pr.AuthorID)
+		if err != nil {
+			return err
+		}
+		settings, err := loadTeamSettings(ctx, s.teams, authorTeam)
+		if err != nil {
+			return err
+		}
+		if len(pr.Assigned) >= settings.MinReviewers && !pr.NeedMoreReviewers {
+			return nil
+		}
+
+		exclude := map[string]struct{}{pr.AuthorID: {}}
+		for _, r := range pr.Assigned {
+			exclude[r] = struct{}{}
+		}
+		added, saturated, err := s.pickReviewers(ctx, teamChain(authorTeam, settings.FallbackTeams), exclude, settings.MaxReviewers-len(pr.Assigned))
+		if err != nil {
+			return err
+		}
+		cov := coverage(len(pr.Assigned)+len(added), settings.MinReviewers, saturated)
+		if len(added) == 0 && cov.NeedMore == pr.NeedMoreReviewers && cov.CapacityExhausted == pr.CapacityExhausted {
+			return nil
+		}
+		meta := repo.EventMeta{Actor: ActorFromContext(ctx), Reason: ReasonTopUp}
+		if err := s.prs.AddReviewers(ctx, prID, added, cov, meta); err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrPRNotOpen
+			}
+			return err
+		}
+		if res.PR, err = s.prs.GetPR(ctx, prID); err != nil {
+			return err
+		}
+		res.Changed = true
+		for _, a := range added {
+			res.Added = append(res.Added, a.ReviewerID)
+		}
+		return nil
+	})
+	if err != nil {
+		return TopUpResult{}, err
+	}
+	return res, nil
+}
+
+// TopUpFailure — PR, добор ревьюверов на который не удался
+type TopUpFailure struct {
+	PullRequestID string
+	Err           error
+}
+
+// TopUpAll добирает ревьюверов на все недоукомплектованные OPEN PR.
+// Возвращает только PR, которые изменились. Ошибка добора одного PR не мешает
+// остальным: она пишется в лог и возвращается в failed, а изменения других PR
+// уже зафиксированы. PR, который успели закрыть или смержить после выборки,
+// пропускается. err — список PR получить не удалось и ничего не изменилось
+func (s *PRService) TopUpAll(ctx context.Context) (changed []TopUpResult, failed []TopUpFailure, err error) {
+	ctx, span := startSpan(ctx, "PRService.TopUpAll")
+	defer endSpan(span, &err)
+
+	ids, err := s.prs.ListOpenUnderReviewed(ctx, DefaultMinReviewers)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range ids {
+		res, err := s.TopUp(ctx, id)
+		if errors.Is(err, ErrPRNotOpen) || errors.Is(err, ErrPRMerged) || errors.Is(err, ErrNotFoundPR) {
+			continue
+		}
+		if err != nil {
+			logger.Warn("reviewer top-up of PR failed", "pull_request_id", id, "error", err)
+			failed = append(failed, TopUpFailure{PullRequestID: id, Err: err})
+			continue
+		}
+		if res.Changed {
+			changed = append(changed, res)
+		}
+	}
+	return changed, failed, nil
+}
+
+// CountUnderReviewed возвращает число OPEN PR, которые ждут добора ревьюверов
+func (s *PRService) CountUnderReviewed(ctx context.Context) (_ int, err error) {
+	ctx, span := startSpan(ctx, "PRService.CountUnderReviewed")
+	defer endSpan(span, &err)
+
+	ids, err := s.prs.ListOpenUnderReviewed(ctx, DefaultMinReviewers)
+	return len(ids), err
+}
+
+// pickReviewers добирает до n ревьюверов, проходя команды по порядку:
+// из каждой следующей команды берётся только недостающее количество.
+// Рабочие часы кандидатов учитываются по WorkingHoursPolicy, кандидаты,
+// достигшие max_open_reviews, пропускаются — тогда saturated = true.
+// Выбранные пользователи добавляются в exclude
+func (s *PRService) pickReviewers(ctx context.Context, teams []string, exclude map[string]struct{}, n int) (picked []repo.Assignment, saturated bool, err error) {
+	if s.hours.Mode != WorkingHoursRequire {
+		return s.pickFromTeams(ctx, teams, exclude, n, s.hours.Mode == WorkingHoursPrefer, false)
+	}
+	picked, saturated, err = s.pickFromTeams(ctx, teams, exclude, n, false, true)
+	if err != nil || len(picked) > 0 || s.hours.Fallback == WorkingHoursFallbackNone {
+		return picked, saturated, err
+	}
+	// В рабочих часах никого нет — выбираем без их учёта
+	picked, anySaturated, err := s.pickFromTeams(ctx, teams, exclude, n, false, false)
+	return picked, saturated || anySaturated, err
+}
+
+// pickFromTeams выбирает ревьюверов из цепочки команд. prefer — в каждой команде
+// сначала кандидаты в рабочих часах, onlyInHours — только они
+func (s *PRService) pickFromTeams(ctx context.Context, teams []string, exclude map[string]struct{}, n int, prefer, onlyInHours bool) ([]repo.Assignment, bool, error) {
+	var picked []repo.Assignment
+	saturated := false
+	for _, team := range teams {
+		if len(picked) >= n {
+			break
+		}
+		candidates, err := s.prs.GetActiveCandidatesFromTeamExcluding(ctx, team, "")
+		if err != nil {
+			return nil, false, err
+		}
+		var pool []string
+		for _, id := range candidates {
+			if _, ok := exclude[id]; !ok {
+				pool = append(pool, id)
+			}
+		}
+		if len(pool) == 0 {
+			continue
+		}
+		pool, skipped, err := s.dropSaturated(ctx, pool)
+		if err != nil {
+			return nil, false, err
+		}
+		saturated = saturated || skipped
+		if len(pool) == 0 {
+			continue
+		}
+		groups := [][]string{pool}
+		if prefer || onlyInHours {
+			in, out, err := s.splitByWorkingHours(ctx, pool)
+			if err != nil {
+				return nil, false, err
+			}
+			groups = [][]string{in}
+			if prefer {
+				groups = append(groups, out)
+			}
+		}
+		for _, group := range groups {
+			if len(group) == 0 || len(picked) >= n {
+				continue
+			}
+			ids, err := s.selectors.ForTeam(team).Select(ctx, team, group, n-len(picked))
+			if err != nil {
+				return nil, false, err
+			}
+			for _, id := range ids {
+				picked = append(picked, repo.Assignment{ReviewerID: id, SourceTeam: team})
+				exclude[id] = struct{}{}
+			}
+		}
+	}
+	return picked, saturated, nil
+}
+
+// dropSaturated убирает из pool кандидатов, у которых открытых ревью уже
+// не меньше действующего лимита. skipped — был ли убран хотя бы один
+func (s *PRService) dropSaturated(ctx context.Context, pool []string) (rest []string, skipped bool, err error) {
+	capacity, err := s.prs.ListReviewCapacity(ctx, pool)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, id := range pool {
+		if capacity[id].AtCapacity() {
+			skipped = true
+			continue
+		}
+		rest = append(rest, id)
+	}
+	return rest, skipped, nil
+}
+
+// teamChain возвращает основную команду и резервные без повторов
+func teamChain(primary string, fallbacks []string) []string {
+	chain := []string{primary}
+	seen := map[string]struct{}{primary: {}}
+	for _, t := range fallbacks {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		chain = append(chain, t)
+	}
+	return chain
+}
+
+// Timeline возвращает журнал событий PR в хронологическом порядке
+func (s *PRService) Timeline(ctx context.Context, prID string) (_ []repo.PREventRow, err error) {
+	ctx, span := startSpan(ctx, "PRService.Timeline")
+	defer endSpan(span, &err)
+
+	if _, err := s.prs.GetPR(ctx, prID); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFoundPR
+		}
+		return nil, err
+	}
+	return s.prs.ListEvents(ctx, prID)
 }
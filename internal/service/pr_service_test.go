@@ -4,11 +4,15 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/domain"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
 )
 
 // fakePRRepo — простая in-memory реализация, покрывающая методы, которые вызывает PRService.
@@ -16,7 +20,13 @@ type fakePRRepo struct {
 	usersTeam     map[string]string              // user_id -> team_name
 	activeInTeam  map[string]map[string]bool     // team_name -> user_id -> isActive
 	prs           map[string]repo.PRFull         // pr_id -> PR
-	prReviewers   map[string]map[string]struct{} // pr_id -> set(reviewer_id)
+	prReviewers   map[string]map[string]string    // pr_id -> reviewer_id -> source_team
+	settings      map[string]repo.TeamSettingsRow // team_name -> настройки
+	verdicts      map[string]map[string]string    // pr_id -> reviewer_id -> verdict
+	hours         map[string]repo.WorkingHoursRow // user_id -> рабочие часы
+	limits        map[string]int                  // user_id -> max_open_reviews
+	events        []repo.PREventRow
+	locked        []string // PR, заблокированные GetPRForUpdate
 	createdAtTime time.Time
 }
 
@@ -25,7 +35,11 @@ func newFakePRRepo() *fakePRRepo {
 		usersTeam:    make(map[string]string),
 		activeInTeam: make(map[string]map[string]bool),
 		prs:          make(map[string]repo.PRFull),
-		prReviewers:  make(map[string]map[string]struct{}),
+		prReviewers:  make(map[string]map[string]string),
+		settings:     make(map[string]repo.TeamSettingsRow),
+		verdicts:     make(map[string]map[string]string),
+		hours:        make(map[string]repo.WorkingHoursRow),
+		limits:       make(map[string]int),
 		createdAtTime: time.Now().UTC().Truncate(time.Second),
 	}
 }
@@ -42,9 +56,24 @@ func (f *fakePRRepo) GetShortByReviewer(ctx context.Context, userID string) ([]r
 	return out, nil
 }
 
-func (f *fakePRRepo) CreatePROpenWithAssigned(ctx context.Context, id, name, author string, needMore bool, reviewers []string) error {
+func (f *fakePRRepo) addEvent(ev repo.PREventRow) {
+	ev.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, ev)
+}
+
+func (f *fakePRRepo) ListEvents(ctx context.Context, prID string) ([]repo.PREventRow, error) {
+	var out []repo.PREventRow
+	for _, ev := range f.events {
+		if ev.PullRequestID == prID {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakePRRepo) CreatePROpenWithAssigned(ctx context.Context, id, name, author string, cov repo.Coverage, reviewers []repo.Assignment, meta repo.EventMeta) error {
 	if _, exists := f.prs[id]; exists {
-		return errors.New("duplicate")
+		return repo.ErrDuplicate
 	}
 	created := f.createdAtTime
 	f.prs[id] = repo.PRFull{
@@ -52,12 +81,15 @@ func (f *fakePRRepo) CreatePROpenWithAssigned(ctx context.Context, id, name, aut
 		Name:              name,
 		AuthorID:          author,
 		Status:            "OPEN",
-		NeedMoreReviewers: needMore,
+		NeedMoreReviewers: cov.NeedMore,
+		CapacityExhausted: cov.CapacityExhausted,
 		CreatedAt:         &created,
 	}
-	f.prReviewers[id] = make(map[string]struct{})
+	f.prReviewers[id] = make(map[string]string)
+	f.addEvent(repo.PREventRow{PullRequestID: id, Type: repo.EventCreated, Actor: meta.Actor, UserID: author, Reason: meta.Reason})
 	for _, r := range reviewers {
-		f.prReviewers[id][r] = struct{}{}
+		f.prReviewers[id][r.ReviewerID] = r.SourceTeam
+		f.addEvent(repo.PREventRow{PullRequestID: id, Type: repo.EventAssigned, Actor: meta.Actor, UserID: r.ReviewerID, Reason: meta.Reason})
 	}
 	return nil
 }
@@ -68,16 +100,24 @@ func (f *fakePRRepo) GetPR(ctx context.Context, id string) (repo.PRFull, error)
 		return repo.PRFull{}, pgx.ErrNoRows
 	}
 	var assigned []string
-	for rv := range f.prReviewers[id] {
+	var reviewers []repo.Assignment
+	for rv, team := range f.prReviewers[id] {
 		assigned = append(assigned, rv)
+		reviewers = append(reviewers, repo.Assignment{ReviewerID: rv, SourceTeam: team, Verdict: f.verdicts[id][rv]})
 	}
 	pr.Assigned = assigned
+	pr.Reviewers = reviewers
 	return pr, nil
 }
 
-func (f *fakePRRepo) MarkMerged(ctx context.Context, id string) error {
+func (f *fakePRRepo) GetPRForUpdate(ctx context.Context, id string) (repo.PRFull, error) {
+	f.locked = append(f.locked, id)
+	return f.GetPR(ctx, id)
+}
+
+func (f *fakePRRepo) MarkMerged(ctx context.Context, id string, meta repo.EventMeta) error {
 	pr, ok := f.prs[id]
-	if !ok {
+	if !ok || pr.Status != "OPEN" {
 		return pgx.ErrNoRows
 	}
 	pr.Status = "MERGED"
@@ -86,9 +126,68 @@ func (f *fakePRRepo) MarkMerged(ctx context.Context, id string) error {
 		pr.MergedAt = &now
 	}
 	f.prs[id] = pr
+	f.addEvent(repo.PREventRow{PullRequestID: id, Type: repo.EventMerged, Actor: meta.Actor, Reason: meta.Reason})
+	return nil
+}
+
+func (f *fakePRRepo) CreatePRDraft(ctx context.Context, id, name, author string, meta repo.EventMeta) error {
+	if _, exists := f.prs[id]; exists {
+		return repo.ErrDuplicate
+	}
+	created := f.createdAtTime
+	f.prs[id] = repo.PRFull{ID: id, Name: name, AuthorID: author, Status: "DRAFT", CreatedAt: &created}
+	f.prReviewers[id] = make(map[string]string)
+	f.addEvent(repo.PREventRow{PullRequestID: id, Type: repo.EventCreated, Actor: meta.Actor, UserID: author})
+	return nil
+}
+
+// setStatus меняет статус PR, если текущий входит в from, иначе возвращает pgx.ErrNoRows
+func (f *fakePRRepo) setStatus(id string, from []string, to, eventType string, meta repo.EventMeta) error {
+	pr, ok := f.prs[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	allowed := false
+	for _, st := range from {
+		allowed = allowed || pr.Status == st
+	}
+	if !allowed {
+		return pgx.ErrNoRows
+	}
+	pr.Status = to
+	pr.ClosedAt = nil
+	if to == "CLOSED" {
+		now := time.Now().UTC().Truncate(time.Second)
+		pr.ClosedAt = &now
+	}
+	f.prs[id] = pr
+	f.addEvent(repo.PREventRow{PullRequestID: id, Type: eventType, Actor: meta.Actor, Reason: meta.Reason})
 	return nil
 }
 
+func (f *fakePRRepo) MarkReady(ctx context.Context, id string, reviewers []repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error {
+	if err := f.setStatus(id, []string{"DRAFT"}, "OPEN", repo.EventReady, meta); err != nil {
+		return err
+	}
+	return f.AddReviewers(ctx, id, reviewers, cov, meta)
+}
+
+func (f *fakePRRepo) MarkClosed(ctx context.Context, id string, meta repo.EventMeta) error {
+	return f.setStatus(id, []string{"DRAFT", "OPEN"}, "CLOSED", repo.EventClosed, meta)
+}
+
+func (f *fakePRRepo) MarkDraft(ctx context.Context, id string, meta repo.EventMeta) error {
+	return f.setStatus(id, []string{"OPEN"}, "DRAFT", repo.EventDrafted, meta)
+}
+
+func (f *fakePRRepo) Reopen(ctx context.Context, id string, meta repo.EventMeta) error {
+	return f.setStatus(id, []string{"CLOSED"}, "OPEN", repo.EventReopened, meta)
+}
+
+func (f *fakePRRepo) ReopenDraft(ctx context.Context, id string, meta repo.EventMeta) error {
+	return f.setStatus(id, []string{"CLOSED"}, "DRAFT", repo.EventReopened, meta)
+}
+
 func (f *fakePRRepo) GetUserTeam(ctx context.Context, userID string) (string, error) {
 	tm, ok := f.usersTeam[userID]
 	if !ok {
@@ -108,37 +207,211 @@ func (f *fakePRRepo) GetActiveCandidatesFromTeamExcluding(ctx context.Context, t
 	return out, nil
 }
 
-func (f *fakePRRepo) ReplaceReviewer(ctx context.Context, prID, oldReviewer, newReviewer string) error {
+func (f *fakePRRepo) ReplaceReviewer(ctx context.Context, prID, oldReviewer string, newReviewer repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error {
 	set := f.prReviewers[prID]
 	if set == nil {
 		return pgx.ErrNoRows
 	}
 	if _, ok := set[oldReviewer]; !ok {
-		return errors.New("not assigned")
+		return repo.ErrNotAssigned
 	}
 	delete(set, oldReviewer)
-	set[newReviewer] = struct{}{}
+	delete(f.verdicts[prID], oldReviewer)
+	set[newReviewer.ReviewerID] = newReviewer.SourceTeam
+	pr := f.prs[prID]
+	pr.NeedMoreReviewers = cov.NeedMore
+	pr.CapacityExhausted = cov.CapacityExhausted
+	f.prs[prID] = pr
+	f.addEvent(repo.PREventRow{PullRequestID: prID, Type: repo.EventReassigned, Actor: meta.Actor, FromUserID: oldReviewer, ToUserID: newReviewer.ReviewerID, Reason: meta.Reason})
 	return nil
 }
 
-// GetReviewerStats реализует StatsStore для тестов, просто считает количество назначений по in-memory структурам.
-func (f *fakePRRepo) GetReviewerStats(ctx context.Context) ([]repo.ReviewerStatRow, error) {
-	counts := make(map[string]int64)
-	for _, reviewers := range f.prReviewers {
+func (f *fakePRRepo) RemoveReviewer(ctx context.Context, prID, reviewerID string, cov repo.Coverage, meta repo.EventMeta) error {
+	set := f.prReviewers[prID]
+	if _, ok := set[reviewerID]; !ok {
+		return repo.ErrNotAssigned
+	}
+	delete(set, reviewerID)
+	delete(f.verdicts[prID], reviewerID)
+	pr := f.prs[prID]
+	pr.NeedMoreReviewers = cov.NeedMore
+	pr.CapacityExhausted = cov.CapacityExhausted
+	f.prs[prID] = pr
+	f.addEvent(repo.PREventRow{PullRequestID: prID, Type: repo.EventUnassigned, Actor: meta.Actor, UserID: reviewerID, Reason: meta.Reason})
+	return nil
+}
+
+func (f *fakePRRepo) SetVerdict(ctx context.Context, prID, reviewerID, verdict string, meta repo.EventMeta) error {
+	if _, ok := f.prReviewers[prID][reviewerID]; !ok {
+		return repo.ErrNotAssigned
+	}
+	if f.verdicts[prID] == nil {
+		f.verdicts[prID] = make(map[string]string)
+	}
+	f.verdicts[prID][reviewerID] = verdict
+	f.addEvent(repo.PREventRow{PullRequestID: prID, Type: repo.EventReviewed, Actor: meta.Actor, UserID: reviewerID, Verdict: verdict})
+	return nil
+}
+
+func (f *fakePRRepo) AddReviewers(ctx context.Context, prID string, reviewers []repo.Assignment, cov repo.Coverage, meta repo.EventMeta) error {
+	if f.prs[prID].Status != "OPEN" {
+		return pgx.ErrNoRows
+	}
+	set := f.prReviewers[prID]
+	for _, rv := range reviewers {
+		if _, dup := set[rv.ReviewerID]; dup {
+			return errors.New("duplicate")
+		}
+		set[rv.ReviewerID] = rv.SourceTeam
+		f.addEvent(repo.PREventRow{PullRequestID: prID, Type: repo.EventAssigned, Actor: meta.Actor, UserID: rv.ReviewerID, Reason: meta.Reason})
+	}
+	pr := f.prs[prID]
+	pr.NeedMoreReviewers = cov.NeedMore
+	pr.CapacityExhausted = cov.CapacityExhausted
+	f.prs[prID] = pr
+	return nil
+}
+
+func (f *fakePRRepo) ListOpenUnderReviewed(ctx context.Context, defaultMin int) ([]string, error) {
+	var ids []string
+	for id, pr := range f.prs {
+		if pr.Status != "OPEN" {
+			continue
+		}
+		min := defaultMin
+		if st, ok := f.settings[f.usersTeam[pr.AuthorID]]; ok {
+			min = st.MinReviewers
+		}
+		if pr.NeedMoreReviewers || len(f.prReviewers[id]) < min {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (f *fakePRRepo) GetSettings(ctx context.Context, teamName string) (repo.TeamSettingsRow, error) {
+	row, ok := f.settings[teamName]
+	if !ok {
+		return repo.TeamSettingsRow{}, pgx.ErrNoRows
+	}
+	return row, nil
+}
+
+// GetReviewerStats считает назначения по in-memory структурам с учётом команды,
+// статуса и группировки по ревьюверам или командам. Период и медиана не поддерживаются
+func (f *fakePRRepo) GetReviewerStats(ctx context.Context, filter repo.StatsFilter) ([]repo.ReviewerStatRow, error) {
+	byKey := make(map[string]*repo.ReviewerStatRow)
+	row := func(userID string) *repo.ReviewerStatRow {
+		key := userID
+		if filter.GroupBy == repo.StatsGroupByTeam {
+			key = f.usersTeam[userID]
+		}
+		if byKey[key] == nil {
+			byKey[key] = &repo.ReviewerStatRow{Key: key}
+		}
+		return byKey[key]
+	}
+	match := func(prID, userID string) bool {
+		return (filter.TeamName == "" || f.usersTeam[userID] == filter.TeamName) &&
+			(filter.Status == "" || f.prs[prID].Status == filter.Status)
+	}
+	for prID, reviewers := range f.prReviewers {
 		for id := range reviewers {
-			counts[id]++
+			if !match(prID, id) {
+				continue
+			}
+			st := row(id)
+			st.TotalAssigned++
+			switch f.prs[prID].Status {
+			case "OPEN":
+				st.OpenReviews++
+			case "MERGED":
+				st.Merged++
+			}
+		}
+	}
+	for _, ev := range f.events {
+		if ev.Type == repo.EventReassigned && match(ev.PullRequestID, ev.FromUserID) {
+			row(ev.FromUserID).ReassignedAway++
 		}
 	}
 	var out []repo.ReviewerStatRow
-	for id, c := range counts {
-		out = append(out, repo.ReviewerStatRow{
-			ReviewerID:    id,
-			TotalAssigned: c,
-		})
+	for _, st := range byKey {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (f *fakePRRepo) ListWorkingHours(ctx context.Context, userIDs []string) (map[string]repo.WorkingHoursRow, error) {
+	out := make(map[string]repo.WorkingHoursRow, len(userIDs))
+	for _, id := range userIDs {
+		if row, ok := f.hours[id]; ok {
+			out[id] = row
+		}
+	}
+	return out, nil
+}
+
+// ListReviewCapacity берёт лимит пользователя из limits, иначе — лимит по умолчанию его команды
+func (f *fakePRRepo) ListReviewCapacity(ctx context.Context, userIDs []string) (map[string]repo.ReviewCapacityRow, error) {
+	counts, _ := f.GetOpenReviewCounts(ctx, userIDs)
+	out := make(map[string]repo.ReviewCapacityRow, len(userIDs))
+	for _, id := range userIDs {
+		row := repo.ReviewCapacityRow{UserID: id, OpenReviews: int(counts[id])}
+		if n, ok := f.limits[id]; ok {
+			row.MaxOpenReviews = &n
+			row.Limit = &n
+		} else if st, ok := f.settings[f.usersTeam[id]]; ok {
+			row.Limit = st.DefaultMaxOpenReviews
+		}
+		out[id] = row
 	}
 	return out, nil
 }
 
+func (f *fakePRRepo) ListCapacityExhausted(ctx context.Context) ([]string, error) {
+	var ids []string
+	for id, pr := range f.prs {
+		if pr.Status == "OPEN" && pr.CapacityExhausted {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// newTestPRService собирает PRService со случайной стратегией выбора
+func newTestPRService(t *testing.T, r *fakePRRepo) *PRService {
+	t.Helper()
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	return NewPRService(r, r, selectors, MergePolicy{}, WorkingHoursPolicy{}, &fakeTransactor{})
+}
+
+// GetOpenReviewCounts считает назначения только на OPEN PR
+func (f *fakePRRepo) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int64, error) {
+	want := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		want[id] = true
+	}
+	counts := make(map[string]int64)
+	for prID, reviewers := range f.prReviewers {
+		if f.prs[prID].Status != "OPEN" {
+			continue
+		}
+		for id := range reviewers {
+			if want[id] {
+				counts[id]++
+			}
+		}
+	}
+	return counts, nil
+}
+
 func TestCreate_AssignsUpToTwo(t *testing.T) {
 	r := newFakePRRepo()
 	// команда backend: u1 (author), u2, u3 активны
@@ -147,7 +420,7 @@ func TestCreate_AssignsUpToTwo(t *testing.T) {
 	r.usersTeam["u3"] = "backend"
 	r.activeInTeam["backend"] = map[string]bool{"u1": true, "u2": true, "u3": true}
 
-	svc := NewPRService(r)
+	svc := newTestPRService(t, r)
 	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -170,7 +443,12 @@ func TestMerge_Idempotent(t *testing.T) {
 	r := newFakePRRepo()
 	r.usersTeam["u1"] = "backend"
 	r.activeInTeam["backend"] = map[string]bool{"u1": true}
-	svc := NewPRService(r)
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	tx := &fakeTransactor{}
+	svc := NewPRService(r, r, selectors, MergePolicy{}, WorkingHoursPolicy{}, tx)
 	if _, err := svc.Create(context.Background(), "pr-2", "X", "u1"); err != nil {
 		t.Fatalf("create: %v", err)
 	}
@@ -189,16 +467,29 @@ func TestMerge_Idempotent(t *testing.T) {
 	if !reflect.DeepEqual(time1, pr2.MergedAt) {
 		t.Fatalf("mergedAt must be stable (idempotent)")
 	}
+	// Оба merge проверяют PR под блокировкой в транзакции, событие записано один раз
+	if !reflect.DeepEqual(r.locked, []string{"pr-2", "pr-2"}) || tx.calls != 2 {
+		t.Fatalf("locked = %v, tx calls = %d", r.locked, tx.calls)
+	}
+	merged := 0
+	for _, ev := range r.events {
+		if ev.Type == repo.EventMerged {
+			merged++
+		}
+	}
+	if merged != 1 {
+		t.Fatalf("merged events = %d, want 1", merged)
+	}
 }
 
 func TestReassign_Basic(t *testing.T) {
 	r := newFakePRRepo()
-	// team A: u1 author, u2,u3 active
-	for _, u := range []string{"u1", "u2", "u3"} {
+	// team A: u1 author, u2,u3,u4 active
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
 		r.usersTeam[u] = "A"
 	}
-	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
-	svc := NewPRService(r)
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	svc := newTestPRService(t, r)
 	pr, err := svc.Create(context.Background(), "pr-3", "Feat", "u1")
 	if err != nil {
 		t.Fatalf("create: %v", err)
@@ -208,7 +499,7 @@ func TestReassign_Basic(t *testing.T) {
 		t.Fatalf("expected at least one reviewer")
 	}
 	old = pr.Assigned[0]
-	pr2, replacedBy, err := svc.Reassign(context.Background(), "pr-3", old)
+	pr2, replacedBy, err := svc.Reassign(context.Background(), "pr-3", old, "")
 	if err != nil {
 		t.Fatalf("reassign: %v", err)
 	}
@@ -229,4 +520,763 @@ func TestReassign_Basic(t *testing.T) {
 	}
 }
 
+func TestCreate_HonoursTeamSettings(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"p1", "p2", "p3", "p4", "p5"} {
+		r.usersTeam[u] = "platform"
+	}
+	r.activeInTeam["platform"] = map[string]bool{"p1": true, "p2": true, "p3": true, "p4": true, "p5": true}
+	r.settings["platform"] = repo.TeamSettingsRow{TeamName: "platform", MinReviewers: 3, MaxReviewers: 3}
+	for _, u := range []string{"d1", "d2", "d3"} {
+		r.usersTeam[u] = "docs"
+	}
+	r.activeInTeam["docs"] = map[string]bool{"d1": true, "d2": true, "d3": true}
+	r.settings["docs"] = repo.TeamSettingsRow{TeamName: "docs", MinReviewers: 1, MaxReviewers: 1}
+
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-p", "P", "p1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Assigned) != 3 || pr.NeedMoreReviewers {
+		t.Fatalf("platform: want 3 reviewers and quorum met, got %v need_more=%v", pr.Assigned, pr.NeedMoreReviewers)
+	}
+	pr, err = svc.Create(context.Background(), "pr-d", "D", "d1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Assigned) != 1 || pr.NeedMoreReviewers {
+		t.Fatalf("docs: want 1 reviewer and quorum met, got %v need_more=%v", pr.Assigned, pr.NeedMoreReviewers)
+	}
+}
+
+func TestCreate_NeedMoreBelowMinReviewers(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"p1", "p2", "p3"} {
+		r.usersTeam[u] = "platform"
+	}
+	r.activeInTeam["platform"] = map[string]bool{"p1": true, "p2": true, "p3": true}
+	r.settings["platform"] = repo.TeamSettingsRow{TeamName: "platform", MinReviewers: 3, MaxReviewers: 4}
+
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-p", "P", "p1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Assigned) != 2 || !pr.NeedMoreReviewers {
+		t.Fatalf("want 2 reviewers and need_more=true, got %v need_more=%v", pr.Assigned, pr.NeedMoreReviewers)
+	}
+}
+
+func TestReassign_RecomputesNeedMoreFromSettings(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if pr.NeedMoreReviewers {
+		t.Fatalf("default settings: two reviewers must meet quorum")
+	}
+
+	// Команда подняла кворум после создания PR
+	r.settings["A"] = repo.TeamSettingsRow{TeamName: "A", MinReviewers: 3, MaxReviewers: 3}
+	pr2, _, err := svc.Reassign(context.Background(), "pr-1", pr.Assigned[0], "")
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if !pr2.NeedMoreReviewers {
+		t.Fatalf("need_more_reviewers must reflect the new min_reviewers")
+	}
+}
+
+func TestCreate_FillsFromFallbackTeams(t *testing.T) {
+	r := newFakePRRepo()
+	r.usersTeam["a1"] = "mobile"
+	r.usersTeam["a2"] = "mobile"
+	r.activeInTeam["mobile"] = map[string]bool{"a1": true, "a2": true}
+	r.settings["mobile"] = repo.TeamSettingsRow{TeamName: "mobile", MinReviewers: 3, MaxReviewers: 3, FallbackTeams: []string{"web", "backend"}}
+	r.usersTeam["w1"] = "web"
+	r.activeInTeam["web"] = map[string]bool{"w1": true}
+	for _, u := range []string{"b1", "b2"} {
+		r.usersTeam[u] = "backend"
+	}
+	r.activeInTeam["backend"] = map[string]bool{"b1": true, "b2": true}
+
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "a1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Reviewers) != 3 || pr.NeedMoreReviewers {
+		t.Fatalf("want 3 reviewers and quorum met, got %+v need_more=%v", pr.Reviewers, pr.NeedMoreReviewers)
+	}
+	byTeam := map[string]int{}
+	for _, rv := range pr.Reviewers {
+		if rv.SourceTeam != r.usersTeam[rv.ReviewerID] {
+			t.Fatalf("reviewer %s recorded with source team %q", rv.ReviewerID, rv.SourceTeam)
+		}
+		byTeam[rv.SourceTeam]++
+	}
+	// Сначала своя команда, затем web, и только остаток — из backend
+	if byTeam["mobile"] != 1 || byTeam["web"] != 1 || byTeam["backend"] != 1 {
+		t.Fatalf("unexpected distribution by team: %v", byTeam)
+	}
+}
+
+func TestReassign_FallsBackWhenTeamExhausted(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"a1", "a2"} {
+		r.usersTeam[u] = "mobile"
+	}
+	r.activeInTeam["mobile"] = map[string]bool{"a1": true, "a2": true}
+	r.settings["mobile"] = repo.TeamSettingsRow{TeamName: "mobile", MinReviewers: 1, MaxReviewers: 1, FallbackTeams: []string{"web"}}
+	r.usersTeam["w1"] = "web"
+	r.activeInTeam["web"] = map[string]bool{"w1": true}
+
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "a1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Assigned) != 1 || pr.Assigned[0] != "a2" {
+		t.Fatalf("want a2 from own team, got %v", pr.Assigned)
+	}
+	pr2, replacedBy, err := svc.Reassign(context.Background(), "pr-1", "a2", "")
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if replacedBy != "w1" {
+		t.Fatalf("replacedBy = %q, want w1 from fallback team", replacedBy)
+	}
+	if pr2.Reviewers[0].SourceTeam != "web" {
+		t.Fatalf("source team = %q, want web", pr2.Reviewers[0].SourceTeam)
+	}
+}
+
+func TestReassign_NeverPicksAuthor(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true}
+	svc := newTestPRService(t, r)
+	if _, err := svc.Create(context.Background(), "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, _, err := svc.Reassign(context.Background(), "pr-1", "u2", ""); !errors.Is(err, ErrNoCandidate) {
+		t.Fatalf("expected ErrNoCandidate, got %v", err)
+	}
+}
+
+func TestUnassign_FlagsNeedMore(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	pr2, err := svc.Unassign(context.Background(), "pr-1", pr.Assigned[0], "", false)
+	if err != nil {
+		t.Fatalf("unassign: %v", err)
+	}
+	if len(pr2.Assigned) != 1 || !pr2.NeedMoreReviewers {
+		t.Fatalf("want 1 reviewer and need_more=true, got %v need_more=%v", pr2.Assigned, pr2.NeedMoreReviewers)
+	}
+	if _, err := svc.Unassign(context.Background(), "pr-1", "u1", "", false); !errors.Is(err, ErrNotAssigned) {
+		t.Fatalf("expected ErrNotAssigned, got %v", err)
+	}
+}
+
+func TestTopUp_AssignsNewlyActiveTeammate(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": false}
+	svc := newTestPRService(t, r)
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !pr.NeedMoreReviewers {
+		t.Fatalf("only one candidate: need_more_reviewers must be set")
+	}
+
+	// Ничего не изменилось — добирать некого
+	res, err := svc.TopUp(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("top up: %v", err)
+	}
+	if res.Changed {
+		t.Fatalf("nothing to add yet, got %+v", res)
+	}
+	// Добор идёт под блокировкой PR, чтобы не пересечься с merge и другим добором
+	if !reflect.DeepEqual(r.locked, []string{"pr-1"}) {
+		t.Fatalf("top up must lock the PR, locked = %v", r.locked)
+	}
+
+	r.activeInTeam["A"]["u3"] = true
+	changed, _, err := svc.TopUpAll(context.Background())
+	if err != nil {
+		t.Fatalf("top up all: %v", err)
+	}
+	if len(changed) != 1 || !reflect.DeepEqual(changed[0].Added, []string{"u3"}) {
+		t.Fatalf("want u3 added to pr-1, got %+v", changed)
+	}
+	if changed[0].PR.NeedMoreReviewers {
+		t.Fatalf("quorum reached: need_more_reviewers must be cleared")
+	}
+
+	// Повторный проход ничего не меняет
+	changed, _, err = svc.TopUpAll(context.Background())
+	if err != nil {
+		t.Fatalf("top up all: %v", err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("second pass must be a no-op, got %+v", changed)
+	}
+}
+
+func TestTopUpAll_ContinuesAfterFailedPR(t *testing.T) {
+	logger.Init("test")
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true}
+	svc := newTestPRService(t, r)
+	// У обоих PR по одному ревьюверу из двух
+	for pr, author := range map[string]string{"pr-1": "u1", "pr-2": "u2"} {
+		if _, err := svc.Create(context.Background(), pr, "T", author); err != nil {
+			t.Fatalf("create %s: %v", pr, err)
+		}
+	}
+	// Добор pr-2 падает: у автора больше нет команды
+	delete(r.usersTeam, "u2")
+	r.activeInTeam["A"]["u3"] = true
+
+	changed, failed, err := svc.TopUpAll(context.Background())
+	if err != nil {
+		t.Fatalf("top up all: %v", err)
+	}
+	if len(failed) != 1 || failed[0].PullRequestID != "pr-2" || !errors.Is(failed[0].Err, ErrNotFoundUser) {
+		t.Fatalf("want ErrNotFoundUser for pr-2, got %+v", failed)
+	}
+	if len(changed) != 1 || changed[0].PR.ID != "pr-1" {
+		t.Fatalf("pr-1 must still be topped up, got %+v", changed)
+	}
+}
+
+func TestTopUp_SkipsMergedAndFilledPRs(t *testing.T) {
+	r := newFakePRRepo()
+	r.usersTeam["u1"] = "A"
+	r.activeInTeam["A"] = map[string]bool{"u1": true}
+	svc := newTestPRService(t, r)
+	if _, err := svc.Create(context.Background(), "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Merge(context.Background(), "pr-1"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if _, err := svc.TopUp(context.Background(), "pr-1"); !errors.Is(err, ErrPRMerged) {
+		t.Fatalf("expected ErrPRMerged, got %v", err)
+	}
+	if _, err := svc.TopUp(context.Background(), "missing"); !errors.Is(err, ErrNotFoundPR) {
+		t.Fatalf("expected ErrNotFoundPR, got %v", err)
+	}
+}
+
+func TestTimeline_RecordsLifecycle(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	svc := newTestPRService(t, r)
+	ctx := WithActor(context.Background(), "admin:alice")
+
+	pr, err := svc.Create(ctx, "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	old := pr.Assigned[0]
+	_, replacedBy, err := svc.Reassign(ctx, "pr-1", old, "on vacation")
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if _, err := svc.Merge(context.Background(), "pr-1"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	events, err := svc.Timeline(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("timeline: %v", err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	wantTypes := []string{repo.EventCreated, repo.EventAssigned, repo.EventAssigned, repo.EventReassigned, repo.EventMerged}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Fatalf("event types = %v, want %v", types, wantTypes)
+	}
+	re := events[3]
+	if re.FromUserID != old || re.ToUserID != replacedBy || re.Actor != "admin:alice" || re.Reason != "on vacation" {
+		t.Fatalf("unexpected reassign event: %+v", re)
+	}
+	if events[4].Actor != SystemActor {
+		t.Fatalf("merge without actor must be attributed to %q, got %q", SystemActor, events[4].Actor)
+	}
+}
+
+func TestTimeline_NotFound(t *testing.T) {
+	svc := newTestPRService(t, newFakePRRepo())
+	if _, err := svc.Timeline(context.Background(), "missing"); !errors.Is(err, ErrNotFoundPR) {
+		t.Fatalf("expected ErrNotFoundPR, got %v", err)
+	}
+}
+
+// newReviewFixture создаёт PR pr-1 автора u1 с ревьюверами u2 и u3
+func newReviewFixture(t *testing.T) (*fakePRRepo, *PRService) {
+	t.Helper()
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	svc := newTestPRService(t, r)
+	if _, err := svc.Create(context.Background(), "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return r, svc
+}
+
+func TestSubmitReview_StoresVerdictPerReviewer(t *testing.T) {
+	r, svc := newReviewFixture(t)
+
+	pr, err := svc.SubmitReview(context.Background(), "pr-1", "u2", domain.VerdictApproved)
+	if err != nil {
+		t.Fatalf("review: %v", err)
+	}
+	got := map[string]string{}
+	for _, rv := range pr.Reviewers {
+		got[rv.ReviewerID] = rv.Verdict
+	}
+	want := map[string]string{"u2": "APPROVED", "u3": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("verdicts = %v, want %v", got, want)
+	}
+
+	// Повторное ревью заменяет решение
+	if _, err := svc.SubmitReview(context.Background(), "pr-1", "u2", domain.VerdictChangesRequested); err != nil {
+		t.Fatalf("second review: %v", err)
+	}
+	if v := r.verdicts["pr-1"]["u2"]; v != "CHANGES_REQUESTED" {
+		t.Fatalf("verdict must be overwritten, got %q", v)
+	}
+	last := r.events[len(r.events)-1]
+	if last.Type != repo.EventReviewed || last.UserID != "u2" || last.Verdict != "CHANGES_REQUESTED" {
+		t.Fatalf("unexpected event: %+v", last)
+	}
+}
+
+func TestSubmitReview_Errors(t *testing.T) {
+	_, svc := newReviewFixture(t)
+	ctx := context.Background()
+
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u2", "LGTM"); !errors.Is(err, ErrInvalidVerdict) {
+		t.Fatalf("expected ErrInvalidVerdict, got %v", err)
+	}
+	if _, err := svc.SubmitReview(ctx, "missing", "u2", domain.VerdictApproved); !errors.Is(err, ErrNotFoundPR) {
+		t.Fatalf("expected ErrNotFoundPR, got %v", err)
+	}
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u1", domain.VerdictApproved); !errors.Is(err, ErrNotAssigned) {
+		t.Fatalf("author is not a reviewer, expected ErrNotAssigned, got %v", err)
+	}
+	if _, err := svc.Merge(ctx, "pr-1"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u2", domain.VerdictApproved); !errors.Is(err, ErrPRMerged) {
+		t.Fatalf("expected ErrPRMerged, got %v", err)
+	}
+}
+
+func TestMerge_BlockedByPolicy(t *testing.T) {
+	r, _ := newReviewFixture(t)
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, selectors, MergePolicy{
+		RequiredApprovals:          2,
+		BlockOnChangesRequested:    true,
+		BlockWhenNeedMoreReviewers: true,
+	}, WorkingHoursPolicy{}, &fakeTransactor{})
+	ctx := context.Background()
+	pr := r.prs["pr-1"]
+	pr.NeedMoreReviewers = true
+	r.prs["pr-1"] = pr
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u2", domain.VerdictApproved); err != nil {
+		t.Fatalf("review u2: %v", err)
+	}
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u3", domain.VerdictChangesRequested); err != nil {
+		t.Fatalf("review u3: %v", err)
+	}
+
+	_, err = svc.Merge(ctx, "pr-1")
+	var blocked *MergeBlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrMergeBlocked) {
+		t.Fatalf("expected MergeBlockedError, got %v", err)
+	}
+	var codes []string
+	for _, c := range blocked.Unmet {
+		codes = append(codes, c.Code)
+	}
+	want := []string{ConditionRequiredApprovals, ConditionChangesRequested, ConditionNeedMoreReviewers}
+	if !reflect.DeepEqual(codes, want) {
+		t.Fatalf("unmet = %v, want %v", codes, want)
+	}
+	if r.prs["pr-1"].Status != "OPEN" {
+		t.Fatalf("blocked PR must stay OPEN")
+	}
+
+	// После исправлений условия выполнены
+	pr = r.prs["pr-1"]
+	pr.NeedMoreReviewers = false
+	r.prs["pr-1"] = pr
+	if _, err := svc.SubmitReview(ctx, "pr-1", "u3", domain.VerdictApproved); err != nil {
+		t.Fatalf("review u3: %v", err)
+	}
+	merged, err := svc.Merge(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if merged.Status != "MERGED" {
+		t.Fatalf("want MERGED, got %s", merged.Status)
+	}
+}
+
+func TestForceMerge_SkipsPolicy(t *testing.T) {
+	r, _ := newReviewFixture(t)
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, selectors, MergePolicy{RequiredApprovals: 2}, WorkingHoursPolicy{}, &fakeTransactor{})
+	ctx := WithActor(context.Background(), "admin")
+
+	if _, err := svc.Merge(ctx, "pr-1"); !errors.Is(err, ErrMergeBlocked) {
+		t.Fatalf("expected ErrMergeBlocked, got %v", err)
+	}
+	pr, err := svc.ForceMerge(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("force merge: %v", err)
+	}
+	if pr.Status != "MERGED" {
+		t.Fatalf("want MERGED, got %s", pr.Status)
+	}
+	last := r.events[len(r.events)-1]
+	if last.Type != repo.EventMerged || last.Reason != ReasonForcedMerge || last.Actor != "admin" {
+		t.Fatalf("unexpected merge event: %+v", last)
+	}
+}
+
+func TestMergeExternal_SkipsPolicyWithExternalReason(t *testing.T) {
+	r, _ := newReviewFixture(t)
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, selectors, MergePolicy{RequiredApprovals: 2}, WorkingHoursPolicy{}, &fakeTransactor{})
+	ctx := WithActor(context.Background(), "github:daniil-k")
+
+	pr, err := svc.MergeExternal(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("external merge: %v", err)
+	}
+	if pr.Status != "MERGED" {
+		t.Fatalf("want MERGED, got %s", pr.Status)
+	}
+	last := r.events[len(r.events)-1]
+	if last.Type != repo.EventMerged || last.Reason != ReasonExternalMerge || last.Actor != "github:daniil-k" {
+		t.Fatalf("unexpected merge event: %+v", last)
+	}
+}
+
+func TestLifecycle_ReopenTopsUpInSameTx(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	tx := &fakeTransactor{}
+	svc := NewPRService(r, r, selectors, MergePolicy{}, WorkingHoursPolicy{}, tx)
+	ctx := context.Background()
+
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if _, err := svc.Close(ctx, "pr-1"); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// Добор не удался — ошибка возвращается из транзакции, и reopen откатывается вместе с ним.
+	// TopUp открывает вложенную транзакцию (savepoint), поэтому вызовов два
+	delete(r.usersTeam, "u1")
+	if _, err := svc.Reopen(ctx, "pr-1"); err == nil || tx.calls != 2 || tx.lastErr == nil {
+		t.Fatalf("reopen must fail inside one tx, err = %v, calls = %d, tx err = %v", err, tx.calls, tx.lastErr)
+	}
+
+	r.usersTeam["u1"] = "A"
+	r.prs["pr-1"] = repo.PRFull{ID: "pr-1", AuthorID: "u1", Status: "CLOSED"}
+	pr, err := svc.Reopen(ctx, "pr-1")
+	if err != nil || tx.calls != 4 || tx.lastErr != nil {
+		t.Fatalf("reopen: %v, calls = %d", err, tx.calls)
+	}
+	if pr.Status != "OPEN" || len(pr.Assigned) != 2 {
+		t.Fatalf("reopened PR must be topped up to 2 reviewers, got %+v", pr)
+	}
+}
+
+func TestLifecycle_ReopenDraftKeepsDraft(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	svc := newTestPRService(t, r)
+	ctx := context.Background()
+
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if _, err := svc.Close(ctx, "pr-1"); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := svc.MarkDraft(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("draft of closed PR: expected ErrInvalidTransition, got %v", err)
+	}
+	pr, err := svc.ReopenDraft(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("reopen draft: %v", err)
+	}
+	if pr.Status != "DRAFT" || pr.ClosedAt != nil || len(pr.Assigned) != 0 {
+		t.Fatalf("reopened draft must stay DRAFT without reviewers, got %+v", pr)
+	}
+	var transition *TransitionError
+	if _, err := svc.ReopenDraft(ctx, "pr-1"); !errors.As(err, &transition) || transition.From != "DRAFT" {
+		t.Fatalf("second reopen: expected DRAFT -> DRAFT transition error, got %v", err)
+	}
+}
+
+func TestLifecycle_DraftReadyCloseReopen(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	svc := newTestPRService(t, r)
+	ctx := context.Background()
+
+	pr, err := svc.CreateDraft(ctx, "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if pr.Status != "DRAFT" || len(pr.Assigned) != 0 {
+		t.Fatalf("draft must have no reviewers, got %s %v", pr.Status, pr.Assigned)
+	}
+	if _, err := svc.Merge(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("merge of draft: expected ErrInvalidTransition, got %v", err)
+	}
+	if _, _, err := svc.Reassign(ctx, "pr-1", "u2", ""); !errors.Is(err, ErrPRNotOpen) {
+		t.Fatalf("reassign on draft: expected ErrPRNotOpen, got %v", err)
+	}
+
+	pr, err = svc.Ready(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("ready: %v", err)
+	}
+	if pr.Status != "OPEN" || len(pr.Assigned) != 2 {
+		t.Fatalf("ready PR must be OPEN with 2 reviewers, got %s %v", pr.Status, pr.Assigned)
+	}
+	// Повторный ready — переход в тот же статус, вебхук считает его повторной доставкой
+	var transition *TransitionError
+	if _, err := svc.Ready(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) || !errors.As(err, &transition) || transition.From != "OPEN" {
+		t.Fatalf("second ready: expected OPEN -> OPEN transition error, got %v", err)
+	}
+
+	pr, err = svc.Close(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if pr.Status != "CLOSED" || pr.ClosedAt == nil {
+		t.Fatalf("want CLOSED with closedAt, got %s %v", pr.Status, pr.ClosedAt)
+	}
+	if _, err := svc.Merge(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("merge of closed PR: expected ErrInvalidTransition, got %v", err)
+	}
+
+	pr, err = svc.Reopen(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if pr.Status != "OPEN" || pr.ClosedAt != nil || len(pr.Assigned) != 2 {
+		t.Fatalf("reopened PR must be OPEN and keep reviewers, got %+v", pr)
+	}
+
+	if _, err := svc.Merge(ctx, "pr-1"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	for name, op := range map[string]func(context.Context, string) (repo.PRFull, error){
+		"close":  svc.Close,
+		"reopen": svc.Reopen,
+		"ready":  svc.Ready,
+		"draft":  svc.MarkDraft,
+	} {
+		if _, err := op(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("%s of merged PR: expected ErrInvalidTransition, got %v", name, err)
+		}
+	}
+}
 
+func TestMarkDraft_KeepsReviewersUntilReadyAgain(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	svc := newTestPRService(t, r)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	pr, err := svc.MarkDraft(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("draft: %v", err)
+	}
+	if pr.Status != "DRAFT" || len(pr.Assigned) != 2 {
+		t.Fatalf("draft must keep reviewers, got %s %v", pr.Status, pr.Assigned)
+	}
+	kept := append([]string(nil), pr.Assigned...)
+	if _, err := svc.MarkDraft(ctx, "pr-1"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("second draft: expected ErrInvalidTransition, got %v", err)
+	}
+
+	pr, err = svc.Ready(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("ready again: %v", err)
+	}
+	sort.Strings(pr.Assigned)
+	sort.Strings(kept)
+	if pr.Status != "OPEN" || strings.Join(pr.Assigned, ",") != strings.Join(kept, ",") {
+		t.Fatalf("ready again must keep the same reviewers %v, got %s %v", kept, pr.Status, pr.Assigned)
+	}
+	last := r.events[len(r.events)-1]
+	if last.Type != repo.EventReady {
+		t.Fatalf("ready again must not reassign, last event %+v", last)
+	}
+}
+
+func TestReopen_ClosedDraftGetsReviewers(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	svc := newTestPRService(t, r)
+	ctx := context.Background()
+
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if _, err := svc.Close(ctx, "pr-1"); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	pr, err := svc.Reopen(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if len(pr.Assigned) != 2 {
+		t.Fatalf("reopened draft must get reviewers, got %v", pr.Assigned)
+	}
+}
+
+func TestCreateDraft_Errors(t *testing.T) {
+	r := newFakePRRepo()
+	r.usersTeam["u1"] = "A"
+	svc := newTestPRService(t, r)
+	ctx := context.Background()
+
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "nobody"); !errors.Is(err, ErrNotFoundUser) {
+		t.Fatalf("expected ErrNotFoundUser, got %v", err)
+	}
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if _, err := svc.CreateDraft(ctx, "pr-1", "T", "u1"); !errors.Is(err, ErrPRExists) {
+		t.Fatalf("expected ErrPRExists, got %v", err)
+	}
+	if _, err := svc.Close(ctx, "missing"); !errors.Is(err, ErrNotFoundPR) {
+		t.Fatalf("expected ErrNotFoundPR, got %v", err)
+	}
+}
+
+// countingMetrics считает доменные события PRService
+type countingMetrics struct {
+	created, merged, reassigned, noCandidate int
+}
+
+func (m *countingMetrics) PRCreated()          { m.created++ }
+func (m *countingMetrics) PRMerged()           { m.merged++ }
+func (m *countingMetrics) ReviewerReassigned() { m.reassigned++ }
+func (m *countingMetrics) NoCandidate()        { m.noCandidate++ }
+
+func TestPRService_RecordsMetrics(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true}
+	r.settings["A"] = repo.TeamSettingsRow{TeamName: "A", MinReviewers: 1, MaxReviewers: 1}
+	svc := newTestPRService(t, r)
+	m := &countingMetrics{}
+	svc.SetMetrics(m)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Create(ctx, "pr-1", "T", "u1"); !errors.Is(err, ErrPRExists) {
+		t.Fatalf("want ErrPRExists, got %v", err)
+	}
+	// Замены для u2 нет, пока u3 не активен
+	if _, _, err := svc.Reassign(ctx, "pr-1", "u2", ""); !errors.Is(err, ErrNoCandidate) {
+		t.Fatalf("want ErrNoCandidate, got %v", err)
+	}
+	r.activeInTeam["A"]["u3"] = true
+	if _, _, err := svc.Reassign(ctx, "pr-1", "u2", ""); err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Merge(ctx, "pr-1"); err != nil {
+			t.Fatalf("merge: %v", err)
+		}
+	}
+	want := countingMetrics{created: 1, merged: 1, reassigned: 1, noCandidate: 1}
+	if *m != want {
+		t.Fatalf("metrics = %+v, want %+v", *m, want)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// TopUpper добирает ревьюверов на недоукомплектованные PR
+type TopUpper interface {
+	TopUpAll(ctx context.Context) ([]TopUpResult, []TopUpFailure, error)
+}
+
+// Reconciler периодически добирает ревьюверов на OPEN PR, которым их не хватает:
+// например, после активации пользователя или добавления его в команду
+type Reconciler struct {
+	prs      TopUpper
+	interval time.Duration
+}
+
+func NewReconciler(prs TopUpper, interval time.Duration) *Reconciler {
+	return &Reconciler{prs: prs, interval: interval}
+}
+
+// Run выполняет добор раз в interval, пока не отменён ctx
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce выполняет один проход добора и пишет итог в лог. Изменённые PR
+// пишутся и тогда, когда добор части PR не удался
+func (r *Reconciler) RunOnce(ctx context.Context) {
+	changed, failed, err := r.prs.TopUpAll(ctx)
+	if err != nil {
+		logger.Error("reviewer top-up failed", "error", err)
+		return
+	}
+	if len(failed) > 0 {
+		logger.Error("reviewer top-up of some PRs failed", "failed", len(failed), "updated", len(changed))
+	}
+	for _, res := range changed {
+		logger.Info("reviewers topped up",
+			"pull_request_id", res.PR.ID,
+			"added", res.Added,
+			"need_more_reviewers", res.PR.NeedMoreReviewers,
+		)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// fakeTopUpper считает вызовы TopUpAll
+type fakeTopUpper struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeTopUpper) TopUpAll(ctx context.Context) ([]TopUpResult, []TopUpFailure, error) {
+	f.calls.Add(1)
+	return nil, nil, f.err
+}
+
+func TestReconciler_RunsUntilCancelled(t *testing.T) {
+	logger.Init("test")
+	prs := &fakeTopUpper{err: errors.New("db error")}
+	rec := NewReconciler(prs, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rec.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(time.Second)
+	for prs.calls.Load() < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("reconciler did not tick, calls=%d", prs.calls.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Run must return after ctx is cancelled")
+	}
+}
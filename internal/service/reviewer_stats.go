@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+
+	"github.com/quasttyy/pr-reviewer/internal/domain"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// ReviewerStat — строка статистики ревьюверов. Capacity — текущий лимит и
+// загрузка ревьювера, заполняется только при группировке по ревьюверам
+type ReviewerStat struct {
+	repo.ReviewerStatRow
+	Capacity *repo.ReviewCapacityRow
+}
+
+// GetReviewerStats возвращает статистику назначений за период [From, To)
+// с группировкой по ревьюверам (по умолчанию), командам или неделям
+func (s *PRService) GetReviewerStats(ctx context.Context, f repo.StatsFilter) (_ []ReviewerStat, err error) {
+	ctx, span := startSpan(ctx, "PRService.GetReviewerStats")
+	defer endSpan(span, &err)
+
+	if f.GroupBy == "" {
+		f.GroupBy = repo.StatsGroupByReviewer
+	}
+	if err := validateStatsFilter(f); err != nil {
+		return nil, err
+	}
+	rows, err := s.prs.GetReviewerStats(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]ReviewerStat, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, ReviewerStat{ReviewerStatRow: row})
+	}
+	if f.GroupBy != repo.StatsGroupByReviewer || len(stats) == 0 {
+		return stats, nil
+	}
+	ids := make([]string, 0, len(stats))
+	for _, st := range stats {
+		ids = append(ids, st.Key)
+	}
+	capacity, err := s.prs.ListReviewCapacity(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		if c, ok := capacity[stats[i].Key]; ok {
+			stats[i].Capacity = &c
+		}
+	}
+	return stats, nil
+}
+
+func validateStatsFilter(f repo.StatsFilter) error {
+	switch f.GroupBy {
+	case repo.StatsGroupByReviewer, repo.StatsGroupByTeam, repo.StatsGroupByWeek:
+	default:
+		return ErrInvalidStatsFilter
+	}
+	switch domain.PRStatus(f.Status) {
+	case "", domain.PRStatusDraft, domain.PRStatusOpen, domain.PRStatusMerged, domain.PRStatusClosed:
+	default:
+		return ErrInvalidStatsFilter
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return ErrInvalidStatsFilter
+	}
+	return nil
+}
+
+// CapacityExhausted возвращает OPEN PR, которым не хватает ревьюверов из-за лимитов max_open_reviews
+func (s *PRService) CapacityExhausted(ctx context.Context) (_ []string, err error) {
+	ctx, span := startSpan(ctx, "PRService.CapacityExhausted")
+	defer endSpan(span, &err)
+
+	return s.prs.ListCapacityExhausted(ctx)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// newStatsFixture: команды A (u1, u2, u3) и B (u4). pr-1 смержен с ревьюверами u2 и u4,
+// на pr-2 ревьювер u3 заменён на u2
+func newStatsFixture(t *testing.T) (*fakePRRepo, *PRService) {
+	t.Helper()
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.usersTeam["u4"] = "B"
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	ctx := context.Background()
+	_ = r.CreatePROpenWithAssigned(ctx, "pr-1", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u2", SourceTeam: "A"}, {ReviewerID: "u4", SourceTeam: "B"}}, repo.EventMeta{})
+	_ = r.MarkMerged(ctx, "pr-1", repo.EventMeta{})
+	_ = r.CreatePROpenWithAssigned(ctx, "pr-2", "x", "u1", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u3", SourceTeam: "A"}}, repo.EventMeta{})
+	_ = r.ReplaceReviewer(ctx, "pr-2", "u3", repo.Assignment{ReviewerID: "u2", SourceTeam: "A"}, repo.Coverage{}, repo.EventMeta{})
+	return r, newTestPRService(t, r)
+}
+
+func TestReviewerStats_ByReviewer(t *testing.T) {
+	r, svc := newStatsFixture(t)
+	r.limits["u2"] = 1
+
+	stats, err := svc.GetReviewerStats(context.Background(), repo.StatsFilter{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	got := make(map[string]ReviewerStat)
+	for _, st := range stats {
+		got[st.Key] = st
+	}
+	u2 := got["u2"]
+	if u2.TotalAssigned != 2 || u2.OpenReviews != 1 || u2.Merged != 1 || u2.ReassignedAway != 0 {
+		t.Fatalf("unexpected u2 stats: %+v", u2.ReviewerStatRow)
+	}
+	if u2.Capacity == nil || !u2.Capacity.AtCapacity() {
+		t.Fatalf("u2 must be at capacity, got %+v", u2.Capacity)
+	}
+	if u3 := got["u3"]; u3.TotalAssigned != 0 || u3.ReassignedAway != 1 {
+		t.Fatalf("unexpected u3 stats: %+v", u3.ReviewerStatRow)
+	}
+}
+
+func TestReviewerStats_ByTeamWithFilters(t *testing.T) {
+	_, svc := newStatsFixture(t)
+
+	stats, err := svc.GetReviewerStats(context.Background(), repo.StatsFilter{GroupBy: repo.StatsGroupByTeam, Status: "MERGED"})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("want teams A and B, got %+v", stats)
+	}
+	for _, st := range stats {
+		if st.TotalAssigned != 1 || st.Merged != 1 || st.Capacity != nil {
+			t.Fatalf("unexpected %s stats: %+v", st.Key, st)
+		}
+	}
+
+	stats, err = svc.GetReviewerStats(context.Background(), repo.StatsFilter{GroupBy: repo.StatsGroupByTeam, TeamName: "B"})
+	if err != nil || len(stats) != 1 || stats[0].Key != "B" {
+		t.Fatalf("want only team B, got %+v, %v", stats, err)
+	}
+}
+
+func TestReviewerStats_InvalidFilter(t *testing.T) {
+	_, svc := newStatsFixture(t)
+	from := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, -1)
+
+	for _, f := range []repo.StatsFilter{
+		{GroupBy: "month"},
+		{Status: "REJECTED"},
+		{From: &from, To: &to},
+		{From: &from, To: &from},
+	} {
+		if _, err := svc.GetReviewerStats(context.Background(), f); !errors.Is(err, ErrInvalidStatsFilter) {
+			t.Fatalf("GetReviewerStats(%+v): want ErrInvalidStatsFilter, got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Названия стратегий выбора ревьюверов (значения reviewers.strategy в config.yaml)
+const (
+	StrategyRandom      = "random"
+	StrategyRoundRobin  = "round_robin"
+	StrategyLeastLoaded = "least_loaded"
+)
+
+var ErrUnknownStrategy = errors.New("unknown reviewer selection strategy")
+
+// ReviewerSelector выбирает до n ревьюверов из кандидатов команды
+type ReviewerSelector interface {
+	Select(ctx context.Context, team string, candidates []string, n int) ([]string, error)
+}
+
+// ReviewerLoadStore отдаёт текущую нагрузку ревьюверов для least_loaded
+type ReviewerLoadStore interface {
+	GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int64, error)
+}
+
+// NewSelector создаёт стратегию по её названию
+func NewSelector(strategy string, loads ReviewerLoadStore) (ReviewerSelector, error) {
+	switch strategy {
+	case StrategyRandom:
+		return RandomSelector{}, nil
+	case StrategyRoundRobin:
+		return NewRoundRobinSelector(), nil
+	case StrategyLeastLoaded:
+		return NewLeastLoadedSelector(loads), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, strategy)
+	}
+}
+
+// SelectorRegistry хранит глобальную стратегию и переопределения по командам
+type SelectorRegistry struct {
+	def    ReviewerSelector
+	byTeam map[string]ReviewerSelector
+}
+
+// NewSelectorRegistry собирает реестр стратегий. Одинаковые названия стратегий
+// разделяют один экземпляр, чтобы, например, round_robin хранил общее состояние
+func NewSelectorRegistry(defaultStrategy string, teamStrategies map[string]string, loads ReviewerLoadStore) (*SelectorRegistry, error) {
+	built := make(map[string]ReviewerSelector)
+	get := func(name string) (ReviewerSelector, error) {
+		if sel, ok := built[name]; ok {
+			return sel, nil
+		}
+		sel, err := NewSelector(name, loads)
+		if err != nil {
+			return nil, err
+		}
+		built[name] = sel
+		return sel, nil
+	}
+
+	def, err := get(defaultStrategy)
+	if err != nil {
+		return nil, err
+	}
+	reg := &SelectorRegistry{def: def, byTeam: make(map[string]ReviewerSelector)}
+	for team, name := range teamStrategies {
+		sel, err := get(name)
+		if err != nil {
+			return nil, fmt.Errorf("team %q: %w", team, err)
+		}
+		reg.byTeam[team] = sel
+	}
+	return reg, nil
+}
+
+// ForTeam возвращает стратегию команды или глобальную, если переопределения нет
+func (r *SelectorRegistry) ForTeam(team string) ReviewerSelector {
+	if sel, ok := r.byTeam[team]; ok {
+		return sel
+	}
+	return r.def
+}
+
+// RandomSelector выбирает кандидатов равновероятно
+type RandomSelector struct{}
+
+func (RandomSelector) Select(_ context.Context, _ string, candidates []string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if len(candidates) <= n {
+		return append([]string(nil), candidates...), nil
+	}
+	out := make([]string, 0, n)
+	for _, i := range rand.Perm(len(candidates))[:n] {
+		out = append(out, candidates[i])
+	}
+	return out, nil
+}
+
+// RoundRobinSelector обходит кандидатов команды по кругу в порядке user_id.
+// Состояние хранится в памяти процесса и сбрасывается при рестарте
+type RoundRobinSelector struct {
+	mu   sync.Mutex
+	last map[string]string // team_name -> последний выбранный user_id
+}
+
+func NewRoundRobinSelector() *RoundRobinSelector {
+	return &RoundRobinSelector{last: make(map[string]string)}
+}
+
+func (s *RoundRobinSelector) Select(_ context.Context, team string, candidates []string, n int) ([]string, error) {
+	if n <= 0 || len(candidates) == 0 {
+		return nil, nil
+	}
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Начинаем с первого кандидата после последнего выбранного
+	start := sort.SearchStrings(sorted, s.last[team])
+	if start < len(sorted) && sorted[start] == s.last[team] {
+		start++
+	}
+	out := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, sorted[(start+i)%len(sorted)])
+	}
+	s.last[team] = out[len(out)-1]
+	return out, nil
+}
+
+// LeastLoadedSelector выбирает кандидатов с наименьшим числом открытых (OPEN) PR
+// на ревью. Смерженные PR нагрузкой не считаются, равенство разрешается случайно
+type LeastLoadedSelector struct {
+	loads ReviewerLoadStore
+}
+
+func NewLeastLoadedSelector(loads ReviewerLoadStore) *LeastLoadedSelector {
+	return &LeastLoadedSelector{loads: loads}
+}
+
+func (s *LeastLoadedSelector) Select(ctx context.Context, _ string, candidates []string, n int) ([]string, error) {
+	if n <= 0 || len(candidates) == 0 {
+		return nil, nil
+	}
+	load, err := s.loads.GetOpenReviewCounts(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+	// Перемешиваем, а затем стабильно сортируем по нагрузке:
+	// среди одинаково загруженных порядок остаётся случайным
+	sorted := append([]string(nil), candidates...)
+	rand.Shuffle(len(sorted), func(i, j int) { sorted[i], sorted[j] = sorted[j], sorted[i] })
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return load[sorted[i]] < load[sorted[j]]
+	})
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	return sorted[:n], nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// fakeLoadStore — фиксированное число открытых ревью для least_loaded
+type fakeLoadStore struct {
+	open map[string]int64
+}
+
+func (f *fakeLoadStore) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int64, error) {
+	return f.open, nil
+}
+
+func TestRandomSelector_PicksDistinctCandidates(t *testing.T) {
+	sel := RandomSelector{}
+	candidates := []string{"u1", "u2", "u3", "u4"}
+	for i := 0; i < 50; i++ {
+		got, err := sel.Select(context.Background(), "A", candidates, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 2 || got[0] == got[1] {
+			t.Fatalf("want two distinct reviewers, got %v", got)
+		}
+	}
+}
+
+func TestRandomSelector_FewerCandidatesThanNeeded(t *testing.T) {
+	got, err := RandomSelector{}.Select(context.Background(), "A", []string{"u1"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"u1"}) {
+		t.Fatalf("got %v, want [u1]", got)
+	}
+}
+
+func TestRoundRobinSelector_RotatesPerTeam(t *testing.T) {
+	sel := NewRoundRobinSelector()
+	ctx := context.Background()
+	candidates := []string{"u3", "u1", "u2"}
+
+	want := [][]string{{"u1", "u2"}, {"u3", "u1"}, {"u2", "u3"}}
+	for i, w := range want {
+		got, err := sel.Select(ctx, "A", candidates, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("round %d: got %v, want %v", i, got, w)
+		}
+	}
+
+	// Другая команда имеет собственный курсор
+	got, _ := sel.Select(ctx, "B", candidates, 1)
+	if !reflect.DeepEqual(got, []string{"u1"}) {
+		t.Fatalf("team B: got %v, want [u1]", got)
+	}
+}
+
+func TestRoundRobinSelector_LastPickedLeftTeam(t *testing.T) {
+	sel := NewRoundRobinSelector()
+	ctx := context.Background()
+	_, _ = sel.Select(ctx, "A", []string{"u1", "u2"}, 1) // u1
+	got, _ := sel.Select(ctx, "A", []string{"u0", "u3"}, 1)
+	if !reflect.DeepEqual(got, []string{"u3"}) {
+		t.Fatalf("got %v, want [u3]", got)
+	}
+}
+
+func TestLeastLoadedSelector_PrefersLowestLoad(t *testing.T) {
+	loads := &fakeLoadStore{open: map[string]int64{"u1": 5, "u2": 1, "u3": 3}}
+	sel := NewLeastLoadedSelector(loads)
+	got, err := sel.Select(context.Background(), "A", []string{"u1", "u2", "u3", "u4"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// У u4 нет открытых ревью
+	if !reflect.DeepEqual(got, []string{"u4", "u2"}) {
+		t.Fatalf("got %v, want [u4 u2]", got)
+	}
+}
+
+func TestLeastLoadedSelector_BreaksTiesRandomly(t *testing.T) {
+	loads := &fakeLoadStore{open: map[string]int64{"u1": 2, "u2": 2, "u3": 2}}
+	sel := NewLeastLoadedSelector(loads)
+	seen := make(map[string]bool)
+	for i := 0; i < 200 && len(seen) < 3; i++ {
+		got, err := sel.Select(context.Background(), "A", []string{"u1", "u2", "u3"}, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen[got[0]] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("equally loaded candidates must all be picked eventually, seen %v", seen)
+	}
+}
+
+func TestCreate_LeastLoadedIgnoresMergedReviews(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"a", "b", "u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	reg, err := NewSelectorRegistry(StrategyLeastLoaded, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, reg, MergePolicy{}, WorkingHoursPolicy{}, &fakeTransactor{})
+	ctx := context.Background()
+
+	// u1 держит два открытых ревью, u2 — много смерженных
+	_ = r.CreatePROpenWithAssigned(ctx, "open-1", "x", "a", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u1", SourceTeam: "A"}}, repo.EventMeta{})
+	_ = r.CreatePROpenWithAssigned(ctx, "open-2", "x", "a", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u1", SourceTeam: "A"}}, repo.EventMeta{})
+	for _, id := range []string{"m-1", "m-2", "m-3"} {
+		_ = r.CreatePROpenWithAssigned(ctx, id, "x", "b", repo.Coverage{}, []repo.Assignment{{ReviewerID: "u2", SourceTeam: "A"}}, repo.EventMeta{})
+		_ = r.MarkMerged(ctx, id, repo.EventMeta{})
+	}
+
+	pr, err := svc.Create(ctx, "pr-new", "T", "u3")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(pr.Assigned) != 2 {
+		t.Fatalf("want 2 reviewers, got %v", pr.Assigned)
+	}
+	pr2, err := svc.Create(ctx, "pr-new-2", "T", "a")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	// u1 с тремя открытыми ревью не должен получить ещё одно, пока u2 и u3 свободнее
+	for _, id := range pr2.Assigned {
+		if id == "u1" {
+			t.Fatalf("busiest reviewer u1 must not be picked, got %v", pr2.Assigned)
+		}
+	}
+}
+
+func TestSelectorRegistry_TeamOverride(t *testing.T) {
+	reg, err := NewSelectorRegistry(StrategyRandom, map[string]string{"platform": StrategyRoundRobin}, &fakeLoadStore{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := reg.ForTeam("platform").(*RoundRobinSelector); !ok {
+		t.Fatalf("platform must use round_robin, got %T", reg.ForTeam("platform"))
+	}
+	if _, ok := reg.ForTeam("docs").(RandomSelector); !ok {
+		t.Fatalf("docs must fall back to random, got %T", reg.ForTeam("docs"))
+	}
+}
+
+func TestSelectorRegistry_UnknownStrategy(t *testing.T) {
+	if _, err := NewSelectorRegistry("fastest", nil, nil); !errors.Is(err, ErrUnknownStrategy) {
+		t.Fatalf("expected ErrUnknownStrategy, got %v", err)
+	}
+	if _, err := NewSelectorRegistry(StrategyRandom, map[string]string{"A": "nope"}, nil); !errors.Is(err, ErrUnknownStrategy) {
+		t.Fatalf("expected ErrUnknownStrategy for team override, got %v", err)
+	}
+}
+
+func TestCreate_UsesTeamStrategy(t *testing.T) {
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3", "u4"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true, "u4": true}
+	reg, err := NewSelectorRegistry(StrategyRandom, map[string]string{"A": StrategyRoundRobin}, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, reg, MergePolicy{}, WorkingHoursPolicy{}, &fakeTransactor{})
+
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	assigned := append([]string(nil), pr.Assigned...)
+	sort.Strings(assigned)
+	if !reflect.DeepEqual(assigned, []string{"u2", "u3"}) {
+		t.Fatalf("got %v, want [u2 u3]", assigned)
+	}
+	pr2, replacedBy, err := svc.Reassign(context.Background(), "pr-1", "u2", "")
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if replacedBy != "u4" {
+		t.Fatalf("replacedBy = %q, want u4 (next in rotation)", replacedBy)
+	}
+	if len(pr2.Assigned) != 2 {
+		t.Fatalf("want 2 reviewers after reassign, got %v", pr2.Assigned)
+	}
+}
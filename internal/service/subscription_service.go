@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// Размер страницы журнала доставок
+const (
+	DefaultDeliveryLimit = 100
+	MaxDeliveryLimit     = 500
+)
+
+// SubscriptionStore хранит подписки на исходящие вебхуки и очередь их доставок
+type SubscriptionStore interface {
+	CreateSubscription(ctx context.Context, row repo.SubscriptionRow) (repo.SubscriptionRow, error)
+	ListSubscriptions(ctx context.Context) ([]repo.SubscriptionRow, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	EnqueueDeliveries(ctx context.Context, eventID, eventType string, payload []byte) (int, error)
+	ListDeliveries(ctx context.Context, f repo.DeliveryFilter) ([]repo.DeliveryRow, error)
+}
+
+// SubscriptionService управляет подписками на события и ставит события в очередь
+// доставки. Отправляет их Dispatcher
+type SubscriptionService struct {
+	store SubscriptionStore
+}
+
+func NewSubscriptionService(store SubscriptionStore) *SubscriptionService {
+	return &SubscriptionService{store: store}
+}
+
+// Create добавляет подписку на события events; повторы в events отбрасываются
+func (s *SubscriptionService) Create(ctx context.Context, rawURL, secret string, events []string) (_ repo.SubscriptionRow, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Create")
+	defer endSpan(span, &err)
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return repo.SubscriptionRow{}, ErrInvalidSubscription
+	}
+	if secret == "" || len(events) == 0 {
+		return repo.SubscriptionRow{}, ErrInvalidSubscription
+	}
+	seen := make(map[string]bool, len(events))
+	var filter []string
+	for _, e := range events {
+		if !eventTypes[e] {
+			return repo.SubscriptionRow{}, ErrInvalidSubscription
+		}
+		if !seen[e] {
+			seen[e] = true
+			filter = append(filter, e)
+		}
+	}
+	return s.store.CreateSubscription(ctx, repo.SubscriptionRow{URL: u.String(), Secret: secret, Events: filter})
+}
+
+func (s *SubscriptionService) List(ctx context.Context) (_ []repo.SubscriptionRow, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.List")
+	defer endSpan(span, &err)
+
+	return s.store.ListSubscriptions(ctx)
+}
+
+// Delete удаляет подписку; недоставленные события ей больше не отправляются
+func (s *SubscriptionService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Delete")
+	defer endSpan(span, &err)
+
+	if err := s.store.DeleteSubscription(ctx, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Deliveries возвращает журнал доставок, новые первыми. Limit вне
+// [1, MaxDeliveryLimit] заменяется на DefaultDeliveryLimit
+func (s *SubscriptionService) Deliveries(ctx context.Context, f repo.DeliveryFilter) (_ []repo.DeliveryRow, err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Deliveries")
+	defer endSpan(span, &err)
+
+	if f.Limit <= 0 || f.Limit > MaxDeliveryLimit {
+		f.Limit = DefaultDeliveryLimit
+	}
+	return s.store.ListDeliveries(ctx, f)
+}
+
+// Publish ставит событие в очередь доставки всем подписанным на его тип
+func (s *SubscriptionService) Publish(ctx context.Context, ev Event) (err error) {
+	ctx, span := startSpan(ctx, "SubscriptionService.Publish")
+	defer endSpan(span, &err)
+
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = s.store.EnqueueDeliveries(ctx, ev.ID, ev.Type, payload)
+	return err
+}
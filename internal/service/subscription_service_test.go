@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// fakeWebhookStore — in-memory реализация SubscriptionStore и DeliveryQueue
+type fakeWebhookStore struct {
+	mu         sync.Mutex
+	subs       []repo.SubscriptionRow
+	deliveries []repo.DeliveryRow
+}
+
+func (f *fakeWebhookStore) CreateSubscription(ctx context.Context, row repo.SubscriptionRow) (repo.SubscriptionRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	row.ID = int64(len(f.subs) + 1)
+	f.subs = append(f.subs, row)
+	return row, nil
+}
+
+func (f *fakeWebhookStore) ListSubscriptions(ctx context.Context) ([]repo.SubscriptionRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]repo.SubscriptionRow{}, f.subs...), nil
+}
+
+func (f *fakeWebhookStore) DeleteSubscription(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.subs {
+		if s.ID == id {
+			f.subs = append(f.subs[:i], f.subs[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (f *fakeWebhookStore) EnqueueDeliveries(ctx context.Context, eventID, eventType string, payload []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, s := range f.subs {
+		for _, e := range s.Events {
+			if e == eventType {
+				f.deliveries = append(f.deliveries, repo.DeliveryRow{
+					ID:             int64(len(f.deliveries) + 1),
+					SubscriptionID: s.ID,
+					EventID:        eventID,
+					EventType:      eventType,
+					Payload:        payload,
+					Status:         repo.DeliveryPending,
+				})
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeWebhookStore) ListDeliveries(ctx context.Context, filter repo.DeliveryFilter) ([]repo.DeliveryRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []repo.DeliveryRow
+	for i := len(f.deliveries) - 1; i >= 0 && len(out) < filter.Limit; i-- {
+		d := f.deliveries[i]
+		if (filter.SubscriptionID == 0 || d.SubscriptionID == filter.SubscriptionID) && (filter.Status == "" || d.Status == filter.Status) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeWebhookStore) ClaimDueDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]repo.DueDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []repo.DueDelivery
+	for i := range f.deliveries {
+		d := &f.deliveries[i]
+		if d.Status != repo.DeliveryPending || d.NextAttemptAt.After(now) || len(out) == limit {
+			continue
+		}
+		d.NextAttemptAt = leaseUntil
+		for _, s := range f.subs {
+			if s.ID == d.SubscriptionID {
+				out = append(out, repo.DueDelivery{
+					ID: d.ID, SubscriptionID: s.ID, URL: s.URL, Secret: s.Secret,
+					EventID: d.EventID, EventType: d.EventType, Payload: d.Payload, Attempts: d.Attempts,
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeWebhookStore) RecordAttempt(ctx context.Context, id int64, a repo.DeliveryAttempt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := &f.deliveries[id-1]
+	d.Attempts++
+	d.Status = a.Status
+	d.LastStatusCode = a.StatusCode
+	d.LastError = a.Error
+	d.NextAttemptAt = a.At
+	if a.Status == repo.DeliveryDelivered {
+		at := a.At
+		d.DeliveredAt = &at
+	}
+	return nil
+}
+
+func (f *fakeWebhookStore) delivery(id int64) repo.DeliveryRow {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deliveries[id-1]
+}
+
+func TestSubscriptionService_Create(t *testing.T) {
+	ctx := context.Background()
+	svc := NewSubscriptionService(&fakeWebhookStore{})
+
+	invalid := []struct {
+		name   string
+		url    string
+		secret string
+		events []string
+	}{
+		{"relative url", "/hooks", "s", []string{EventPRCreated}},
+		{"unsupported scheme", "ftp://example.com/hooks", "s", []string{EventPRCreated}},
+		{"empty secret", "https://example.com/hooks", "", []string{EventPRCreated}},
+		{"no events", "https://example.com/hooks", "s", nil},
+		{"unknown event", "https://example.com/hooks", "s", []string{"pr.closed"}},
+	}
+	for _, tc := range invalid {
+		if _, err := svc.Create(ctx, tc.url, tc.secret, tc.events); !errors.Is(err, ErrInvalidSubscription) {
+			t.Errorf("%s: want ErrInvalidSubscription, got %v", tc.name, err)
+		}
+	}
+
+	sub, err := svc.Create(ctx, " https://example.com/hooks ", "s", []string{EventPRMerged, EventPRCreated, EventPRMerged})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if sub.URL != "https://example.com/hooks" || len(sub.Events) != 2 {
+		t.Fatalf("subscription = %+v, want trimmed url and deduplicated events", sub)
+	}
+	if err := svc.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := svc.Delete(ctx, sub.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Fatalf("want ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+func TestSubscriptionService_PublishFiltersByEventType(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeWebhookStore{}
+	svc := NewSubscriptionService(store)
+	if _, err := svc.Create(ctx, "https://a.example.com", "s", []string{EventPRMerged}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Create(ctx, "https://b.example.com", "s", []string{EventPRCreated, EventPRMerged}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	ev := Event{ID: "ev-1", Type: EventPRCreated, Data: EventData{PullRequestID: "pr-1"}}
+	if err := svc.Publish(ctx, ev); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	log, err := svc.Deliveries(ctx, repo.DeliveryFilter{})
+	if err != nil {
+		t.Fatalf("deliveries: %v", err)
+	}
+	if len(log) != 1 || log[0].SubscriptionID != 2 || log[0].EventID != "ev-1" {
+		t.Fatalf("deliveries = %+v, want one for subscription 2", log)
+	}
+	var got Event
+	if err := json.Unmarshal(log[0].Payload, &got); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if got.Type != EventPRCreated || got.Data.PullRequestID != "pr-1" {
+		t.Fatalf("payload = %+v", got)
+	}
+}
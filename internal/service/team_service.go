@@ -3,15 +3,23 @@ package service
 import (
 	"context"
 	"errors"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/quasttyy/pr-reviewer/internal/domain"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
 )
 
-var (
-	ErrTeamExists = errors.New("team already exists")
-	ErrNotFound   = errors.New("not found")
+// Число ревьюверов для команд без записи в team_settings
+const (
+	DefaultMinReviewers = 2
+	DefaultMaxReviewers = 2
 )
 
+// TeamSettingsStore описывает чтение настроек команды
+type TeamSettingsStore interface {
+	GetSettings(ctx context.Context, teamName string) (repo.TeamSettingsRow, error)
+}
+
 // TeamStore описывает минимальный интерфейс хранилища, необходимый сервису команд
 type TeamStore interface {
 	TeamExists(ctx context.Context, teamName string) (bool, error)
@@ -21,6 +29,8 @@ type TeamStore interface {
 		IsActive bool
 	}) error
 	GetTeamWithMembers(ctx context.Context, teamName string) ([]repo.TeamMemberRow, error)
+	GetSettings(ctx context.Context, teamName string) (repo.TeamSettingsRow, error)
+	UpsertSettings(ctx context.Context, row repo.TeamSettingsRow) error
 }
 
 type TeamService struct {
@@ -31,7 +41,10 @@ func NewTeamService(teams TeamStore) *TeamService {
 	return &TeamService{teams: teams}
 }
 
-func (s *TeamService) CreateTeam(ctx context.Context, team domain.Team) error {
+func (s *TeamService) CreateTeam(ctx context.Context, team domain.Team) (err error) {
+	ctx, span := startSpan(ctx, "TeamService.CreateTeam")
+	defer endSpan(span, &err)
+
 	exists, err := s.teams.TeamExists(ctx, team.Name)
 	if err != nil {
 		return err
@@ -55,10 +68,20 @@ func (s *TeamService) CreateTeam(ctx context.Context, team domain.Team) error {
 			IsActive: m.IsActive,
 		})
 	}
-	return s.teams.CreateTeamWithMembers(ctx, team.Name, members)
+	// Команду могли создать между проверкой и вставкой
+	if err := s.teams.CreateTeamWithMembers(ctx, team.Name, members); err != nil {
+		if errors.Is(err, repo.ErrDuplicate) {
+			return ErrTeamExists
+		}
+		return err
+	}
+	return nil
 }
 
-func (s *TeamService) GetTeam(ctx context.Context, teamName string) (domain.Team, error) {
+func (s *TeamService) GetTeam(ctx context.Context, teamName string) (_ domain.Team, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetTeam")
+	defer endSpan(span, &err)
+
 	exists, err := s.teams.TeamExists(ctx, teamName)
 	if err != nil {
 		return domain.Team{}, err
@@ -82,4 +105,87 @@ func (s *TeamService) GetTeam(ctx context.Context, teamName string) (domain.Team
 		Name:    teamName,
 		Members: members,
 	}, nil
-}
\ No newline at end of file
+}
+
+// GetSettings возвращает настройки команды; если они не заданы — значения по умолчанию
+func (s *TeamService) GetSettings(ctx context.Context, teamName string) (_ domain.TeamSettings, err error) {
+	ctx, span := startSpan(ctx, "TeamService.GetSettings")
+	defer endSpan(span, &err)
+
+	exists, err := s.teams.TeamExists(ctx, teamName)
+	if err != nil {
+		return domain.TeamSettings{}, err
+	}
+	if !exists {
+		return domain.TeamSettings{}, ErrNotFound
+	}
+	return loadTeamSettings(ctx, s.teams, teamName)
+}
+
+// UpdateSettings сохраняет настройки существующей команды
+func (s *TeamService) UpdateSettings(ctx context.Context, settings domain.TeamSettings) (_ domain.TeamSettings, err error) {
+	ctx, span := startSpan(ctx, "TeamService.UpdateSettings")
+	defer endSpan(span, &err)
+
+	if settings.MinReviewers < 0 || settings.MaxReviewers < 1 || settings.MinReviewers > settings.MaxReviewers {
+		return domain.TeamSettings{}, ErrInvalidSettings
+	}
+	if settings.DefaultMaxOpenReviews != nil && *settings.DefaultMaxOpenReviews < 1 {
+		return domain.TeamSettings{}, ErrInvalidSettings
+	}
+	seen := map[string]struct{}{settings.TeamName: {}}
+	for _, fb := range settings.FallbackTeams {
+		if _, dup := seen[fb]; dup || fb == "" {
+			return domain.TeamSettings{}, ErrInvalidSettings
+		}
+		seen[fb] = struct{}{}
+	}
+	exists, err := s.teams.TeamExists(ctx, settings.TeamName)
+	if err != nil {
+		return domain.TeamSettings{}, err
+	}
+	if !exists {
+		return domain.TeamSettings{}, ErrNotFound
+	}
+	for _, fb := range settings.FallbackTeams {
+		exists, err := s.teams.TeamExists(ctx, fb)
+		if err != nil {
+			return domain.TeamSettings{}, err
+		}
+		if !exists {
+			return domain.TeamSettings{}, ErrFallbackNotFound
+		}
+	}
+	if err := s.teams.UpsertSettings(ctx, repo.TeamSettingsRow{
+		TeamName:              settings.TeamName,
+		MinReviewers:          settings.MinReviewers,
+		MaxReviewers:          settings.MaxReviewers,
+		FallbackTeams:         settings.FallbackTeams,
+		DefaultMaxOpenReviews: settings.DefaultMaxOpenReviews,
+	}); err != nil {
+		return domain.TeamSettings{}, err
+	}
+	return settings, nil
+}
+
+// loadTeamSettings читает настройки команды, подставляя значения по умолчанию
+func loadTeamSettings(ctx context.Context, store TeamSettingsStore, teamName string) (domain.TeamSettings, error) {
+	row, err := store.GetSettings(ctx, teamName)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.TeamSettings{
+				TeamName:     teamName,
+				MinReviewers: DefaultMinReviewers,
+				MaxReviewers: DefaultMaxReviewers,
+			}, nil
+		}
+		return domain.TeamSettings{}, err
+	}
+	return domain.TeamSettings{
+		TeamName:              row.TeamName,
+		MinReviewers:          row.MinReviewers,
+		MaxReviewers:          row.MaxReviewers,
+		FallbackTeams:         row.FallbackTeams,
+		DefaultMaxOpenReviews: row.DefaultMaxOpenReviews,
+	}, nil
+}
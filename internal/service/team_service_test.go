@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/quasttyy/pr-reviewer/internal/domain"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
 )
@@ -14,6 +16,7 @@ import (
 type fakeTeamStore struct {
 	existingTeams map[string]bool
 	membersByTeam map[string][]repo.TeamMemberRow
+	settings      map[string]repo.TeamSettingsRow
 
 	// Настройки поведения
 	errOnExistsCheck       error
@@ -33,6 +36,7 @@ func newFakeTeamStore() *fakeTeamStore {
 	return &fakeTeamStore{
 		existingTeams: make(map[string]bool),
 		membersByTeam: make(map[string][]repo.TeamMemberRow),
+		settings:      make(map[string]repo.TeamSettingsRow),
 	}
 }
 
@@ -78,6 +82,19 @@ func (f *fakeTeamStore) GetTeamWithMembers(ctx context.Context, teamName string)
 	return append([]repo.TeamMemberRow(nil), f.membersByTeam[teamName]...), nil
 }
 
+func (f *fakeTeamStore) GetSettings(ctx context.Context, teamName string) (repo.TeamSettingsRow, error) {
+	row, ok := f.settings[teamName]
+	if !ok {
+		return repo.TeamSettingsRow{}, pgx.ErrNoRows
+	}
+	return row, nil
+}
+
+func (f *fakeTeamStore) UpsertSettings(ctx context.Context, row repo.TeamSettingsRow) error {
+	f.settings[row.TeamName] = row
+	return nil
+}
+
 func TestTeamService_CreateTeam_Success(t *testing.T) {
 	store := newFakeTeamStore()
 	svc := NewTeamService(store)
@@ -151,6 +168,18 @@ func TestTeamService_CreateTeam_CreateError(t *testing.T) {
 	}
 }
 
+func TestTeamService_CreateTeam_ConcurrentInsert(t *testing.T) {
+	store := newFakeTeamStore()
+	// Проверка прошла, но команду успели создать до вставки
+	store.errOnCreateWithMembers = fmt.Errorf("%w: teams_pkey", repo.ErrDuplicate)
+	svc := NewTeamService(store)
+
+	err := svc.CreateTeam(context.Background(), domain.Team{Name: "backend"})
+	if err != ErrTeamExists {
+		t.Fatalf("expected ErrTeamExists, got %v", err)
+	}
+}
+
 func TestTeamService_GetTeam_Success(t *testing.T) {
 	store := newFakeTeamStore()
 	store.existingTeams["backend"] = true
@@ -211,4 +240,81 @@ func TestTeamService_GetTeam_GetMembersError(t *testing.T) {
 	}
 }
 
+func TestTeamService_GetSettings_Defaults(t *testing.T) {
+	store := newFakeTeamStore()
+	store.existingTeams["backend"] = true
+	svc := NewTeamService(store)
+
+	got, err := svc.GetSettings(context.Background(), "backend")
+	if err != nil {
+		t.Fatalf("GetSettings() unexpected error: %v", err)
+	}
+	want := domain.TeamSettings{TeamName: "backend", MinReviewers: DefaultMinReviewers, MaxReviewers: DefaultMaxReviewers}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetSettings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTeamService_UpdateSettings_Success(t *testing.T) {
+	store := newFakeTeamStore()
+	store.existingTeams["platform"] = true
+	svc := NewTeamService(store)
 
+	store.existingTeams["backend"] = true
+	in := domain.TeamSettings{TeamName: "platform", MinReviewers: 3, MaxReviewers: 3, FallbackTeams: []string{"backend"}}
+	if _, err := svc.UpdateSettings(context.Background(), in); err != nil {
+		t.Fatalf("UpdateSettings() unexpected error: %v", err)
+	}
+	got, err := svc.GetSettings(context.Background(), "platform")
+	if err != nil {
+		t.Fatalf("GetSettings() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Fatalf("GetSettings() = %+v, want %+v", got, in)
+	}
+}
+
+func TestTeamService_UpdateSettings_Invalid(t *testing.T) {
+	store := newFakeTeamStore()
+	store.existingTeams["backend"] = true
+	svc := NewTeamService(store)
+
+	for _, in := range []domain.TeamSettings{
+		{TeamName: "backend", MinReviewers: 3, MaxReviewers: 2},
+		{TeamName: "backend", MinReviewers: 0, MaxReviewers: 0},
+		{TeamName: "backend", MinReviewers: -1, MaxReviewers: 2},
+		{TeamName: "backend", MinReviewers: 1, MaxReviewers: 2, FallbackTeams: []string{"backend"}},
+		{TeamName: "backend", MinReviewers: 1, MaxReviewers: 2, FallbackTeams: []string{"web", "web"}},
+		{TeamName: "backend", MinReviewers: 1, MaxReviewers: 2, DefaultMaxOpenReviews: new(int)},
+	} {
+		if _, err := svc.UpdateSettings(context.Background(), in); !errors.Is(err, ErrInvalidSettings) {
+			t.Fatalf("UpdateSettings(%+v): expected ErrInvalidSettings, got %v", in, err)
+		}
+	}
+	if len(store.settings) != 0 {
+		t.Fatalf("invalid settings must not be stored")
+	}
+}
+
+func TestTeamService_UpdateSettings_TeamNotFound(t *testing.T) {
+	store := newFakeTeamStore()
+	svc := NewTeamService(store)
+
+	_, err := svc.UpdateSettings(context.Background(), domain.TeamSettings{TeamName: "ghost", MinReviewers: 1, MaxReviewers: 1})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTeamService_UpdateSettings_FallbackNotFound(t *testing.T) {
+	store := newFakeTeamStore()
+	store.existingTeams["backend"] = true
+	svc := NewTeamService(store)
+
+	_, err := svc.UpdateSettings(context.Background(), domain.TeamSettings{
+		TeamName: "backend", MinReviewers: 1, MaxReviewers: 2, FallbackTeams: []string{"ghost"},
+	})
+	if !errors.Is(err, ErrFallbackNotFound) {
+		t.Fatalf("expected ErrFallbackNotFound, got %v", err)
+	}
+}
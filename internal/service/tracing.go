@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName — имя tracer'а для span'ов методов сервисов
+const instrumentationName = "github.com/quasttyy/pr-reviewer/internal/service"
+
+// startSpan открывает span метода сервиса. Провайдер берётся глобальный, без настройки трассировки span'ы no-op
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+// endSpan закрывает span; если метод вернул ошибку, она записывается в span.
+// Вызывается через defer с адресом именованного результата err
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestPRService_StartsSpans(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	r := newFakePRRepo()
+	r.usersTeam["u1"] = "backend"
+	r.activeInTeam["backend"] = map[string]bool{"u1": true}
+	svc := newTestPRService(t, r)
+
+	if _, err := svc.Create(context.Background(), "pr-1", "T", "u1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Create(context.Background(), "pr-2", "T", "ghost"); !errors.Is(err, ErrNotFoundUser) {
+		t.Fatalf("want ErrNotFoundUser, got %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.Name() != "PRService.Create" {
+			t.Fatalf("want span PRService.Create, got %q", span.Name())
+		}
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Fatalf("successful call must not mark span as error, got %v", spans[0].Status().Code)
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != ErrNotFoundUser.Error() {
+		t.Fatalf("failed call must record error on span, got %+v", spans[1].Status())
+	}
+}
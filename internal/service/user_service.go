@@ -4,16 +4,13 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/quasttyy/pr-reviewer/internal/repo"
 )
 
-var (
-	ErrUserNotFound = errors.New("user not found")
-)
-
 // UserStore описывает операции над пользователями, необходимые сервису
 type UserStore interface {
-	UpdateIsActive(ctx context.Context, userID string, isActive bool) (repo.UserRow, error)
+	UpdateIsActive(ctx context.Context, userID string, isActive bool, meta repo.EventMeta) (repo.UserRow, error)
 	GetByID(ctx context.Context, userID string) (repo.UserRow, error)
 }
 
@@ -22,27 +19,127 @@ type PRShortStore interface {
 	GetShortByReviewer(ctx context.Context, userID string) ([]repo.PRShortRow, error)
 }
 
+// Transactor выполняет fn в одной транзакции БД
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ReviewReassigner — операции над назначениями, нужные при деактивации ревьювера
+type ReviewReassigner interface {
+	// ReassignInTx не пишет метрики: деактивация может откатиться
+	ReassignInTx(ctx context.Context, prID, oldReviewerID, reason string) (repo.PRFull, string, error)
+	Unassign(ctx context.Context, prID, reviewerID, reason string, saturated bool) (repo.PRFull, error)
+}
+
+// ReassignedReview — ревью, переданное другому ревьюверу
+type ReassignedReview struct {
+	PullRequestID string
+	OldReviewerID string
+	NewReviewerID string
+}
+
+// DeactivationReport описывает, что стало с открытыми ревью деактивированного пользователя.
+// Uncovered — PR, для которых замены не нашлось: пользователь снят с них,
+// а PR помечен need_more_reviewers
+type DeactivationReport struct {
+	Reassigned []ReassignedReview
+	Uncovered  []string
+}
+
 type UserService struct {
-	users UserStore
-	prs   PRShortStore
+	users   UserStore
+	prs     PRShortStore
+	tx      Transactor
+	reviews ReviewReassigner
+	metrics Metrics
 }
 
-func NewUserService(users UserStore, prs PRShortStore) *UserService {
-	return &UserService{users: users, prs: prs}
+func NewUserService(users UserStore, prs PRShortStore, tx Transactor, reviews ReviewReassigner) *UserService {
+	return &UserService{users: users, prs: prs, tx: tx, reviews: reviews, metrics: noopMetrics{}}
 }
 
-func (s *UserService) SetIsActiveAdmin(ctx context.Context, userID string, isActive bool) (repo.UserRow, error) {
-	row, err := s.users.UpdateIsActive(ctx, userID, isActive)
+// SetIsActiveAdmin меняет активность пользователя. При деактивации в той же
+// транзакции переназначает все его ревью на OPEN PR по обычным правилам Reassign
+func (s *UserService) SetIsActiveAdmin(ctx context.Context, userID string, isActive bool) (_ repo.UserRow, _ DeactivationReport, err error) {
+	ctx, span := startSpan(ctx, "UserService.SetIsActiveAdmin")
+	defer endSpan(span, &err)
+
+	var row repo.UserRow
+	var report DeactivationReport
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		meta := repo.EventMeta{Actor: ActorFromContext(ctx), Reason: ReasonUserDeactivated}
+		if isActive {
+			meta.Reason = ReasonUserActivated
+		}
+		var err error
+		row, err = s.users.UpdateIsActive(ctx, userID, isActive, meta)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if isActive {
+			return nil
+		}
+		report, err = s.releaseOpenReviews(ctx, userID)
+		return err
+	})
 	if err != nil {
-		// Если пользователя нет, то вернём корректную 404 наверху
-		return repo.UserRow{}, err
+		return repo.UserRow{}, DeactivationReport{}, err
+	}
+	// Метрики пишутся только после фиксации: откаченные переназначения не считаются
+	for range report.Reassigned {
+		s.metrics.ReviewerReassigned()
+	}
+	for range report.Uncovered {
+		s.metrics.NoCandidate()
 	}
-	return row, nil
+	return row, report, nil
 }
 
-func (s *UserService) GetUserReviews(ctx context.Context, userID string) ([]repo.PRShortRow, error) {
+// releaseOpenReviews передаёт открытые ревью пользователя другим ревьюверам
+func (s *UserService) releaseOpenReviews(ctx context.Context, userID string) (DeactivationReport, error) {
+	var report DeactivationReport
+	prs, err := s.prs.GetShortByReviewer(ctx, userID)
+	if err != nil {
+		return DeactivationReport{}, err
+	}
+	for _, p := range prs {
+		if p.Status != "OPEN" {
+			continue
+		}
+		_, newReviewer, err := s.reviews.ReassignInTx(ctx, p.ID, userID, ReasonUserDeactivated)
+		switch {
+		case err == nil:
+			report.Reassigned = append(report.Reassigned, ReassignedReview{
+				PullRequestID: p.ID,
+				OldReviewerID: userID,
+				NewReviewerID: newReviewer,
+			})
+		case errors.Is(err, ErrNoCandidate):
+			// ErrCapacityExhausted уточняет ErrNoCandidate: замены нет только из-за лимитов
+			saturated := errors.Is(err, ErrCapacityExhausted)
+			if _, err := s.reviews.Unassign(ctx, p.ID, userID, ReasonUserDeactivated, saturated); err != nil {
+				return DeactivationReport{}, err
+			}
+			report.Uncovered = append(report.Uncovered, p.ID)
+		default:
+			return DeactivationReport{}, err
+		}
+	}
+	return report, nil
+}
+
+func (s *UserService) GetUserReviews(ctx context.Context, userID string) (_ []repo.PRShortRow, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetUserReviews")
+	defer endSpan(span, &err)
+
 	// Проверим, что пользователь существует (даже если неактивный)
 	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, err
 	}
 	return s.prs.GetShortByReviewer(ctx, userID)
@@ -16,6 +16,7 @@ type fakeUserStore struct {
 	errOnGetByID         error
 	lastUpdateUserID     string
 	lastUpdateIsActive   bool
+	lastUpdateMeta       repo.EventMeta
 	updateCalled         bool
 	getByIDCalledForUser string
 }
@@ -26,8 +27,9 @@ func newFakeUserStore() *fakeUserStore {
 	}
 }
 
-func (f *fakeUserStore) UpdateIsActive(ctx context.Context, userID string, isActive bool) (repo.UserRow, error) {
+func (f *fakeUserStore) UpdateIsActive(ctx context.Context, userID string, isActive bool, meta repo.EventMeta) (repo.UserRow, error) {
 	f.updateCalled = true
+	f.lastUpdateMeta = meta
 	f.lastUpdateUserID = userID
 	f.lastUpdateIsActive = isActive
 	if f.errOnUpdateIsActive != nil {
@@ -78,6 +80,46 @@ func (f *fakePRShortStore) GetShortByReviewer(ctx context.Context, userID string
 	return append([]repo.PRShortRow(nil), rows...), nil
 }
 
+// fakeTransactor просто вызывает fn и запоминает результат
+type fakeTransactor struct {
+	calls   int
+	lastErr error
+}
+
+func (f *fakeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.calls++
+	f.lastErr = fn(ctx)
+	return f.lastErr
+}
+
+// fakeReassigner — заранее заданные результаты ReassignInTx по PR
+type fakeReassigner struct {
+	replacement map[string]string // pr_id -> новый ревьювер
+	errs        map[string]error  // pr_id -> ошибка ReassignInTx
+	reassigned  []string
+	unassigned  []string
+}
+
+func newFakeReassigner() *fakeReassigner {
+	return &fakeReassigner{
+		replacement: make(map[string]string),
+		errs:        make(map[string]error),
+	}
+}
+
+func (f *fakeReassigner) ReassignInTx(ctx context.Context, prID, oldReviewerID, reason string) (repo.PRFull, string, error) {
+	if err := f.errs[prID]; err != nil {
+		return repo.PRFull{}, "", err
+	}
+	f.reassigned = append(f.reassigned, prID)
+	return repo.PRFull{ID: prID}, f.replacement[prID], nil
+}
+
+func (f *fakeReassigner) Unassign(ctx context.Context, prID, reviewerID, reason string, saturated bool) (repo.PRFull, error) {
+	f.unassigned = append(f.unassigned, prID)
+	return repo.PRFull{ID: prID}, nil
+}
+
 func TestUserService_SetIsActiveAdmin_Success(t *testing.T) {
 	users := newFakeUserStore()
 	users.users["u1"] = repo.UserRow{
@@ -87,9 +129,9 @@ func TestUserService_SetIsActiveAdmin_Success(t *testing.T) {
 		IsActive: false,
 	}
 	prs := newFakePRShortStore()
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
-	row, err := svc.SetIsActiveAdmin(context.Background(), "u1", true)
+	row, _, err := svc.SetIsActiveAdmin(context.Background(), "u1", true)
 	if err != nil {
 		t.Fatalf("SetIsActiveAdmin() unexpected error: %v", err)
 	}
@@ -110,9 +152,9 @@ func TestUserService_SetIsActiveAdmin_Success(t *testing.T) {
 func TestUserService_SetIsActiveAdmin_UserNotFound(t *testing.T) {
 	users := newFakeUserStore()
 	prs := newFakePRShortStore()
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
-	_, err := svc.SetIsActiveAdmin(context.Background(), "missing", true)
+	_, _, err := svc.SetIsActiveAdmin(context.Background(), "missing", true)
 	if err == nil {
 		t.Fatalf("expected error for missing user, got nil")
 	}
@@ -123,9 +165,9 @@ func TestUserService_SetIsActiveAdmin_UpdateError(t *testing.T) {
 	users.users["u1"] = repo.UserRow{UserID: "u1"}
 	users.errOnUpdateIsActive = errors.New("update error")
 	prs := newFakePRShortStore()
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
-	_, err := svc.SetIsActiveAdmin(context.Background(), "u1", true)
+	_, _, err := svc.SetIsActiveAdmin(context.Background(), "u1", true)
 	if err == nil || !errors.Is(err, users.errOnUpdateIsActive) {
 		t.Fatalf("expected update error, got %v", err)
 	}
@@ -144,7 +186,7 @@ func TestUserService_GetUserReviews_Success(t *testing.T) {
 		{ID: "pr1", Name: "Fix bug", AuthorID: "a1", Status: "OPEN"},
 		{ID: "pr2", Name: "Add feature", AuthorID: "a2", Status: "MERGED"},
 	}
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
 	result, err := svc.GetUserReviews(context.Background(), "u1")
 	if err != nil {
@@ -165,7 +207,7 @@ func TestUserService_GetUserReviews_Success(t *testing.T) {
 func TestUserService_GetUserReviews_UserNotFound(t *testing.T) {
 	users := newFakeUserStore()
 	prs := newFakePRShortStore()
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
 	_, err := svc.GetUserReviews(context.Background(), "missing")
 	if err == nil {
@@ -180,7 +222,7 @@ func TestUserService_GetUserReviews_GetByIDError(t *testing.T) {
 	users := newFakeUserStore()
 	users.errOnGetByID = errors.New("get error")
 	prs := newFakePRShortStore()
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
 	_, err := svc.GetUserReviews(context.Background(), "u1")
 	if err == nil || !errors.Is(err, users.errOnGetByID) {
@@ -196,7 +238,7 @@ func TestUserService_GetUserReviews_GetShortError(t *testing.T) {
 	users.users["u1"] = repo.UserRow{UserID: "u1"}
 	prs := newFakePRShortStore()
 	prs.errOnGetShort = errors.New("short error")
-	svc := NewUserService(users, prs)
+	svc := NewUserService(users, prs, &fakeTransactor{}, newFakeReassigner())
 
 	_, err := svc.GetUserReviews(context.Background(), "u1")
 	if err == nil || !errors.Is(err, prs.errOnGetShort) {
@@ -204,4 +246,113 @@ func TestUserService_GetUserReviews_GetShortError(t *testing.T) {
 	}
 }
 
+func TestUserService_Deactivate_ReassignsOpenReviews(t *testing.T) {
+	users := newFakeUserStore()
+	users.users["u2"] = repo.UserRow{UserID: "u2", Username: "bob", TeamName: "backend", IsActive: true}
+	prs := newFakePRShortStore()
+	prs.prs["u2"] = []repo.PRShortRow{
+		{ID: "pr1", Status: "OPEN"},
+		{ID: "pr2", Status: "MERGED"},
+		{ID: "pr3", Status: "OPEN"},
+	}
+	reviews := newFakeReassigner()
+	reviews.replacement["pr1"] = "u3"
+	reviews.errs["pr3"] = ErrNoCandidate
+	tx := &fakeTransactor{}
+	svc := NewUserService(users, prs, tx, reviews)
+
+	row, report, err := svc.SetIsActiveAdmin(context.Background(), "u2", false)
+	if err != nil {
+		t.Fatalf("SetIsActiveAdmin() unexpected error: %v", err)
+	}
+	if row.IsActive {
+		t.Fatalf("user must be inactive")
+	}
+	if tx.calls != 1 {
+		t.Fatalf("deactivation must run in a single transaction, got %d", tx.calls)
+	}
+	wantReassigned := []ReassignedReview{{PullRequestID: "pr1", OldReviewerID: "u2", NewReviewerID: "u3"}}
+	if !reflect.DeepEqual(report.Reassigned, wantReassigned) {
+		t.Fatalf("Reassigned = %+v, want %+v", report.Reassigned, wantReassigned)
+	}
+	if !reflect.DeepEqual(report.Uncovered, []string{"pr3"}) {
+		t.Fatalf("Uncovered = %v, want [pr3]", report.Uncovered)
+	}
+	if !reflect.DeepEqual(reviews.unassigned, []string{"pr3"}) {
+		t.Fatalf("uncovered reviewer must be unassigned, got %v", reviews.unassigned)
+	}
+	if users.lastUpdateMeta.Reason != ReasonUserDeactivated || users.lastUpdateMeta.Actor != SystemActor {
+		t.Fatalf("activity change must be logged with reason and actor, got %+v", users.lastUpdateMeta)
+	}
+}
 
+func TestUserService_Activate_DoesNotTouchReviews(t *testing.T) {
+	users := newFakeUserStore()
+	users.users["u2"] = repo.UserRow{UserID: "u2", IsActive: false}
+	prs := newFakePRShortStore()
+	prs.prs["u2"] = []repo.PRShortRow{{ID: "pr1", Status: "OPEN"}}
+	reviews := newFakeReassigner()
+	svc := NewUserService(users, prs, &fakeTransactor{}, reviews)
+
+	_, report, err := svc.SetIsActiveAdmin(context.Background(), "u2", true)
+	if err != nil {
+		t.Fatalf("SetIsActiveAdmin() unexpected error: %v", err)
+	}
+	if prs.getShortCalled || len(reviews.reassigned) != 0 {
+		t.Fatalf("activation must not reassign reviews")
+	}
+	if len(report.Reassigned) != 0 || len(report.Uncovered) != 0 {
+		t.Fatalf("report must be empty, got %+v", report)
+	}
+}
+
+func TestUserService_Deactivate_ReassignErrorAbortsTx(t *testing.T) {
+	users := newFakeUserStore()
+	users.users["u2"] = repo.UserRow{UserID: "u2", IsActive: true}
+	prs := newFakePRShortStore()
+	prs.prs["u2"] = []repo.PRShortRow{{ID: "pr1", Status: "OPEN"}}
+	reviews := newFakeReassigner()
+	reviews.errs["pr1"] = errors.New("db error")
+	tx := &fakeTransactor{}
+	svc := NewUserService(users, prs, tx, reviews)
+
+	_, _, err := svc.SetIsActiveAdmin(context.Background(), "u2", false)
+	if err == nil || !errors.Is(err, reviews.errs["pr1"]) {
+		t.Fatalf("expected reassign error, got %v", err)
+	}
+	if tx.lastErr == nil {
+		t.Fatalf("transaction callback must fail so the deactivation is rolled back")
+	}
+}
+
+func TestUserService_Deactivate_RecordsMetricsAfterCommit(t *testing.T) {
+	users := newFakeUserStore()
+	users.users["u2"] = repo.UserRow{UserID: "u2", IsActive: true}
+	prs := newFakePRShortStore()
+	prs.prs["u2"] = []repo.PRShortRow{{ID: "pr1", Status: "OPEN"}, {ID: "pr2", Status: "OPEN"}, {ID: "pr3", Status: "OPEN"}}
+	reviews := newFakeReassigner()
+	reviews.replacement["pr1"] = "u3"
+	reviews.errs["pr2"] = ErrCapacityExhausted
+	reviews.errs["pr3"] = errors.New("db error")
+	m := &countingMetrics{}
+	svc := NewUserService(users, prs, &fakeTransactor{}, reviews)
+	svc.SetMetrics(m)
+
+	// pr1 переназначен до ошибки на pr3, но транзакция откатилась
+	if _, _, err := svc.SetIsActiveAdmin(context.Background(), "u2", false); err == nil {
+		t.Fatal("expected reassign error")
+	}
+	if *m != (countingMetrics{}) {
+		t.Fatalf("rolled back deactivation must not be counted, got %+v", *m)
+	}
+
+	delete(reviews.errs, "pr3")
+	reviews.replacement["pr3"] = "u4"
+	users.users["u2"] = repo.UserRow{UserID: "u2", IsActive: true}
+	if _, _, err := svc.SetIsActiveAdmin(context.Background(), "u2", false); err != nil {
+		t.Fatalf("SetIsActiveAdmin() unexpected error: %v", err)
+	}
+	if want := (countingMetrics{reassigned: 2, noCandidate: 1}); *m != want {
+		t.Fatalf("metrics = %+v, want %+v", *m, want)
+	}
+}
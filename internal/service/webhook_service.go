@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+	logger "github.com/quasttyy/pr-reviewer/internal/utils"
+)
+
+// PRAction — изменение PR во внешней системе
+type PRAction string
+
+const (
+	PRActionOpened   PRAction = "opened"
+	PRActionReady    PRAction = "ready"
+	PRActionDraft    PRAction = "draft"
+	PRActionMerged   PRAction = "merged"
+	PRActionClosed   PRAction = "closed"
+	PRActionReopened PRAction = "reopened"
+)
+
+// PREvent — событие PR из вебхука, уже разобранное из формата провайдера.
+// PRID строит хендлер провайдера: owner/repo#42 для GitHub, group/project!42 для GitLab
+type PREvent struct {
+	Provider string
+	Action   PRAction
+	PRID     string
+	Title    string
+	// AuthorLogin и AuthorExternalID — логин и постоянный ID автора PR, нужны только
+	// для opened. Если задан AuthorExternalID, автор ищется по нему, а по логину —
+	// только когда ID не привязан; пустой AuthorLogin в этом случае запрещает поиск по логину
+	AuthorLogin      string
+	AuthorExternalID string
+	// Draft — PR черновик: opened создаёт черновик, reopened возвращает в черновик
+	Draft bool
+	// Sender — логин того, кто выполнил действие; попадает в actor журнала событий
+	Sender string
+}
+
+// PRLifecycle — операции PRService, которые выполняют вебхуки
+type PRLifecycle interface {
+	Create(ctx context.Context, prID, prName, authorID string) (repo.PRFull, error)
+	CreateDraft(ctx context.Context, prID, prName, authorID string) (repo.PRFull, error)
+	Ready(ctx context.Context, prID string) (repo.PRFull, error)
+	MarkDraft(ctx context.Context, prID string) (repo.PRFull, error)
+	MergeExternal(ctx context.Context, prID string) (repo.PRFull, error)
+	Close(ctx context.Context, prID string) (repo.PRFull, error)
+	Reopen(ctx context.Context, prID string) (repo.PRFull, error)
+	ReopenDraft(ctx context.Context, prID string) (repo.PRFull, error)
+}
+
+// IdentityResolver находит user_id по логину или постоянному ID во внешней системе
+type IdentityResolver interface {
+	Resolve(ctx context.Context, provider, login string) (string, error)
+	ResolveExternalID(ctx context.Context, provider, externalID string) (string, error)
+}
+
+// WebhookResult — чем закончилась обработка события вебхука
+type WebhookResult string
+
+const (
+	// WebhookApplied — событие изменило PR
+	WebhookApplied WebhookResult = "applied"
+	// WebhookDuplicate — событие уже было применено, например при повторной доставке
+	WebhookDuplicate WebhookResult = "duplicate"
+	// WebhookIgnored — событие не относится к PR сервиса
+	WebhookIgnored WebhookResult = "ignored"
+)
+
+// WebhookService применяет события PR из внешних систем к PRService
+type WebhookService struct {
+	prs        PRLifecycle
+	identities IdentityResolver
+}
+
+func NewWebhookService(prs PRLifecycle, identities IdentityResolver) *WebhookService {
+	return &WebhookService{prs: prs, identities: identities}
+}
+
+// Apply выполняет действие события от имени provider:sender. Провайдеры повторяют
+// доставку, пока не получат ответ 200, поэтому событие, которое уже применено
+// (PR существует или уже в нужном статусе), даёт WebhookDuplicate, а событие
+// для PR, которого сервис не знает (открыт до подключения вебхука), — WebhookIgnored
+func (s *WebhookService) Apply(ctx context.Context, ev PREvent) (_ repo.PRFull, _ WebhookResult, err error) {
+	ctx, span := startSpan(ctx, "WebhookService.Apply")
+	defer endSpan(span, &err)
+
+	if ev.Sender != "" {
+		ctx = WithActor(ctx, ev.Provider+":"+ev.Sender)
+	}
+	var pr repo.PRFull
+	switch ev.Action {
+	case PRActionOpened:
+		pr, err = s.open(ctx, ev)
+		if errors.Is(err, ErrPRExists) {
+			return repo.PRFull{}, WebhookDuplicate, nil
+		}
+	case PRActionReady:
+		pr, err = s.prs.Ready(ctx, ev.PRID)
+	case PRActionDraft:
+		pr, err = s.prs.MarkDraft(ctx, ev.PRID)
+	case PRActionMerged:
+		pr, err = s.prs.MergeExternal(ctx, ev.PRID)
+	case PRActionClosed:
+		pr, err = s.prs.Close(ctx, ev.PRID)
+	case PRActionReopened:
+		if ev.Draft {
+			pr, err = s.prs.ReopenDraft(ctx, ev.PRID)
+		} else {
+			pr, err = s.prs.Reopen(ctx, ev.PRID)
+		}
+	default:
+		return repo.PRFull{}, "", fmt.Errorf("unsupported webhook action %q", ev.Action)
+	}
+	var transition *TransitionError
+	switch {
+	case err == nil:
+		return pr, WebhookApplied, nil
+	case errors.As(err, &transition) && transition.From == string(transition.To):
+		return repo.PRFull{}, WebhookDuplicate, nil
+	case ev.Action != PRActionOpened && errors.Is(err, ErrNotFoundPR):
+		logger.Info("webhook for unknown PR ignored", "provider", ev.Provider, "pull_request_id", ev.PRID, "action", ev.Action)
+		return repo.PRFull{}, WebhookIgnored, nil
+	default:
+		return repo.PRFull{}, "", err
+	}
+}
+
+func (s *WebhookService) open(ctx context.Context, ev PREvent) (repo.PRFull, error) {
+	authorID, err := s.author(ctx, ev)
+	if err != nil {
+		return repo.PRFull{}, err
+	}
+	if ev.Draft {
+		return s.prs.CreateDraft(ctx, ev.PRID, ev.Title, authorID)
+	}
+	return s.prs.Create(ctx, ev.PRID, ev.Title, authorID)
+}
+
+func (s *WebhookService) author(ctx context.Context, ev PREvent) (string, error) {
+	if ev.AuthorExternalID == "" {
+		return s.identities.Resolve(ctx, ev.Provider, ev.AuthorLogin)
+	}
+	userID, err := s.identities.ResolveExternalID(ctx, ev.Provider, ev.AuthorExternalID)
+	if errors.Is(err, ErrUnknownIdentity) && ev.AuthorLogin != "" {
+		return s.identities.Resolve(ctx, ev.Provider, ev.AuthorLogin)
+	}
+	return userID, err
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+// Режимы учёта рабочих часов при выборе ревьюверов (working_hours.mode в config.yaml)
+const (
+	WorkingHoursOff     = "off"     // рабочие часы не учитываются
+	WorkingHoursPrefer  = "prefer"  // сначала кандидаты в рабочих часах, затем остальные
+	WorkingHoursRequire = "require" // только кандидаты в рабочих часах
+)
+
+// Что делать в режиме require, если в рабочих часах нет ни одного кандидата
+// (working_hours.fallback)
+const (
+	WorkingHoursFallbackAny  = "any"  // выбрать без учёта рабочих часов
+	WorkingHoursFallbackNone = "none" // никого не назначать; PR доберётся фоновым добором
+)
+
+var ErrUnknownWorkingHoursMode = errors.New("unknown working hours mode")
+
+// WorkingHoursPolicy — как выбор ревьюверов учитывает рабочие часы.
+// Нулевое значение рабочие часы не учитывает
+type WorkingHoursPolicy struct {
+	Mode     string
+	Fallback string
+}
+
+// Validate проверяет значения из конфига
+func (p WorkingHoursPolicy) Validate() error {
+	switch p.Mode {
+	case "", WorkingHoursOff, WorkingHoursPrefer, WorkingHoursRequire:
+	default:
+		return fmt.Errorf("%w: mode %q", ErrUnknownWorkingHoursMode, p.Mode)
+	}
+	switch p.Fallback {
+	case "", WorkingHoursFallbackAny, WorkingHoursFallbackNone:
+	default:
+		return fmt.Errorf("%w: fallback %q", ErrUnknownWorkingHoursMode, p.Fallback)
+	}
+	return nil
+}
+
+// splitByWorkingHours делит кандидатов на тех, у кого сейчас рабочее время, и остальных.
+// Порядок кандидатов внутри групп сохраняется
+func (s *PRService) splitByWorkingHours(ctx context.Context, candidates []string) (in, out []string, err error) {
+	hours, err := s.prs.ListWorkingHours(ctx, candidates)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	for _, id := range candidates {
+		if withinWorkingHours(hours[id], now) {
+			in = append(in, id)
+		} else {
+			out = append(out, id)
+		}
+	}
+	return in, out, nil
+}
+
+// withinWorkingHours сообщает, попадает ли now в рабочие часы пользователя
+// в его часовом поясе. Окно может переходить через полночь (22:00–06:00).
+// Пользователь без рабочих часов доступен всегда
+func withinWorkingHours(h repo.WorkingHoursRow, now time.Time) bool {
+	start, errStart := parseClock(h.WorkStart)
+	end, errEnd := parseClock(h.WorkEnd)
+	if errStart != nil || errEnd != nil || start == end {
+		return true
+	}
+	loc, err := time.LoadLocation(h.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+// parseClock переводит "HH:MM" в минуты от начала суток
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateWorkingHours проверяет часовой пояс и окно рабочих часов:
+// оба конца задаются вместе в формате HH:MM и не совпадают
+func validateWorkingHours(h repo.WorkingHoursRow) error {
+	if h.TimeZone == "" || h.TimeZone == "Local" {
+		return ErrInvalidWorkingHours
+	}
+	if _, err := time.LoadLocation(h.TimeZone); err != nil {
+		return ErrInvalidWorkingHours
+	}
+	if h.WorkStart == "" && h.WorkEnd == "" {
+		return nil
+	}
+	start, err := parseClock(h.WorkStart)
+	if err != nil {
+		return ErrInvalidWorkingHours
+	}
+	end, err := parseClock(h.WorkEnd)
+	if err != nil || start == end {
+		return ErrInvalidWorkingHours
+	}
+	return nil
+}
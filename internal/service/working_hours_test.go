@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/quasttyy/pr-reviewer/internal/repo"
+)
+
+func TestWithinWorkingHours(t *testing.T) {
+	// 15:30 UTC = 18:30 в Москве, 00:30 следующего дня в Токио
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		h    repo.WorkingHoursRow
+		want bool
+	}{
+		{"no hours", repo.WorkingHoursRow{TimeZone: "Europe/Moscow"}, true},
+		{"utc day", repo.WorkingHoursRow{TimeZone: "UTC", WorkStart: "09:00", WorkEnd: "18:00"}, true},
+		{"moscow after hours", repo.WorkingHoursRow{TimeZone: "Europe/Moscow", WorkStart: "09:00", WorkEnd: "18:00"}, false},
+		{"end is exclusive", repo.WorkingHoursRow{TimeZone: "UTC", WorkStart: "09:00", WorkEnd: "15:30"}, false},
+		{"overnight window", repo.WorkingHoursRow{TimeZone: "Asia/Tokyo", WorkStart: "22:00", WorkEnd: "06:00"}, true},
+		{"outside overnight window", repo.WorkingHoursRow{TimeZone: "UTC", WorkStart: "22:00", WorkEnd: "06:00"}, false},
+		{"unknown zone treated as utc", repo.WorkingHoursRow{TimeZone: "Nowhere/City", WorkStart: "09:00", WorkEnd: "18:00"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := withinWorkingHours(tc.h, now); got != tc.want {
+				t.Fatalf("withinWorkingHours = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// newWorkingHoursFixture: команда A — автор u1 и ревьюверы u2 (Москва) и u3 (Нью-Йорк),
+// оба работают 09:00–18:00; сейчас 18:30 в Москве и 11:30 в Нью-Йорке
+func newWorkingHoursFixture(t *testing.T, hours WorkingHoursPolicy) (*fakePRRepo, *PRService) {
+	t.Helper()
+	r := newFakePRRepo()
+	for _, u := range []string{"u1", "u2", "u3"} {
+		r.usersTeam[u] = "A"
+	}
+	r.activeInTeam["A"] = map[string]bool{"u1": true, "u2": true, "u3": true}
+	r.hours["u2"] = repo.WorkingHoursRow{UserID: "u2", TimeZone: "Europe/Moscow", WorkStart: "09:00", WorkEnd: "18:00"}
+	r.hours["u3"] = repo.WorkingHoursRow{UserID: "u3", TimeZone: "America/New_York", WorkStart: "09:00", WorkEnd: "18:00"}
+	selectors, err := NewSelectorRegistry(StrategyRandom, nil, r)
+	if err != nil {
+		t.Fatalf("selectors: %v", err)
+	}
+	svc := NewPRService(r, r, selectors, MergePolicy{}, hours, &fakeTransactor{})
+	svc.now = func() time.Time { return time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC) }
+	return r, svc
+}
+
+func TestWorkingHours_Require(t *testing.T) {
+	_, svc := newWorkingHoursFixture(t, WorkingHoursPolicy{Mode: WorkingHoursRequire, Fallback: WorkingHoursFallbackAny})
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !reflect.DeepEqual(pr.Assigned, []string{"u3"}) {
+		t.Fatalf("only reviewer in working hours must be assigned, got %v", pr.Assigned)
+	}
+	if !pr.NeedMoreReviewers {
+		t.Fatalf("one reviewer of two required must set need_more_reviewers")
+	}
+}
+
+func TestWorkingHours_PreferFillsFromOffHours(t *testing.T) {
+	_, svc := newWorkingHoursFixture(t, WorkingHoursPolicy{Mode: WorkingHoursPrefer})
+	pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	got := append([]string(nil), pr.Assigned...)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"u2", "u3"}) || pr.NeedMoreReviewers {
+		t.Fatalf("prefer must fill the rest from off-hours reviewers, got %v", got)
+	}
+}
+
+func TestWorkingHours_PreferOrdersInHoursFirst(t *testing.T) {
+	r, svc := newWorkingHoursFixture(t, WorkingHoursPolicy{Mode: WorkingHoursPrefer})
+	r.settings["A"] = repo.TeamSettingsRow{TeamName: "A", MinReviewers: 1, MaxReviewers: 1}
+	for i := 0; i < 20; i++ {
+		pr, err := svc.Create(context.Background(), "pr-"+string(rune('a'+i)), "T", "u1")
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if !reflect.DeepEqual(pr.Assigned, []string{"u3"}) {
+			t.Fatalf("reviewer in working hours must be preferred, got %v", pr.Assigned)
+		}
+	}
+}
+
+func TestWorkingHours_RequireFallback(t *testing.T) {
+	cases := []struct {
+		fallback string
+		want     []string
+	}{
+		{WorkingHoursFallbackAny, []string{"u2"}},
+		{WorkingHoursFallbackNone, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.fallback, func(t *testing.T) {
+			r, svc := newWorkingHoursFixture(t, WorkingHoursPolicy{Mode: WorkingHoursRequire, Fallback: tc.fallback})
+			// u3 выключен — в рабочих часах никого
+			r.activeInTeam["A"]["u3"] = false
+			pr, err := svc.Create(context.Background(), "pr-1", "T", "u1")
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			got := append([]string(nil), pr.Assigned...)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("assigned = %v, want %v", got, tc.want)
+			}
+			if !pr.NeedMoreReviewers {
+				t.Fatalf("PR must need more reviewers")
+			}
+		})
+	}
+}
+
+func TestWorkingHoursPolicy_Validate(t *testing.T) {
+	for _, p := range []WorkingHoursPolicy{{}, {Mode: WorkingHoursPrefer}, {Mode: WorkingHoursRequire, Fallback: WorkingHoursFallbackNone}} {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("Validate(%+v): %v", p, err)
+		}
+	}
+	for _, p := range []WorkingHoursPolicy{{Mode: "always"}, {Mode: WorkingHoursRequire, Fallback: "random"}} {
+		if err := p.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) must fail", p)
+		}
+	}
+}
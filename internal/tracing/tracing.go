@@ -0,0 +1,123 @@
+// Package tracing настраивает OpenTelemetry: провайдер трейсов с экспортёром из конфига,
+// HTTP-middleware со span на каждый запрос и pgx.QueryTracer со span на каждый SQL-запрос
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// instrumentationName — имя tracer'а для span'ов сервиса
+const instrumentationName = "github.com/quasttyy/pr-reviewer"
+
+// Экспортёры трейсов
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Config — настройки трассировки
+type Config struct {
+	// Экспортёр: none, stdout, file, otlp
+	Exporter string
+	// Файл для экспортёра file; span'ы дописываются в конец построчно в JSON
+	File string
+	// Адрес OTLP/HTTP коллектора (host:port) для экспортёра otlp
+	OTLPEndpoint string
+	// Подключаться к коллектору без TLS
+	OTLPInsecure bool
+	// service.name в ресурсе трейсов
+	ServiceName string
+	// Доля сэмплируемых трейсов от 0 до 1; входящий родительский span имеет приоритет
+	SampleRatio float64
+}
+
+// ShutdownFunc сбрасывает накопленные span'ы и закрывает экспортёр
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup создаёт провайдер трейсов с экспортёром из cfg и делает его глобальным.
+// Для none глобальный провайдер остаётся no-op и span'ы не пишутся
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing: sample_ratio must be in [0, 1], got %v", cfg.SampleRatio)
+	}
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("tracing: file is required for %q exporter", ExporterFile)
+		}
+		var f *os.File
+		f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: open %s: %w", cfg.File, err)
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, fmt.Errorf("tracing: create %s exporter: %w", cfg.Exporter, err)
+	}
+
+	name := cfg.ServiceName
+	if name == "" {
+		name = "pr-reviewer"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(name)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
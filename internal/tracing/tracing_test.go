@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans ставит глобальный провайдер, который складывает завершённые span'ы в память
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return sr
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestMiddleware_NamesSpanByRoutePattern(t *testing.T) {
+	sr := recordSpans(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Route("/pullRequest", func(rp chi.Router) {
+		rp.Post("/create", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		rp.Get("/stats", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/pullRequest/create", nil),
+		httptest.NewRequest(http.MethodGet, "/pullRequest/stats?team_name=a", nil),
+		httptest.NewRequest(http.MethodGet, "/nope", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("want 3 spans, got %d", len(spans))
+	}
+	wantNames := []string{"POST /pullRequest/create", "GET /pullRequest/stats", "GET unmatched"}
+	for i, want := range wantNames {
+		if spans[i].Name() != want {
+			t.Fatalf("span %d: want name %q, got %q", i, want, spans[i].Name())
+		}
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Fatalf("5xx must mark span as error, got %v", spans[0].Status().Code)
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Fatalf("2xx must not mark span as error, got %v", spans[1].Status().Code)
+	}
+	if v, ok := attr(spans[1], "http.route"); !ok || v.AsString() != "/pullRequest/stats" {
+		t.Fatalf("http.route want /pullRequest/stats, got %v", v.Emit())
+	}
+	if v, ok := attr(spans[2], "http.response.status_code"); !ok || v.AsInt64() != http.StatusNotFound {
+		t.Fatalf("status code want 404, got %v", v.Emit())
+	}
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	sr := recordSpans(t)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("want 1 span, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("span must continue incoming trace, got trace id %s", got)
+	}
+	if got := spans[0].Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("span parent want 00f067aa0ba902b7, got %s", got)
+	}
+}
+
+func TestQueryTracer_SpanPerQuery(t *testing.T) {
+	sr := recordSpans(t)
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+
+	var qt QueryTracer
+	qctx := qt.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "\n\tSELECT pull_request_id FROM pull_requests WHERE status = $1"})
+	qt.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")})
+	qctx = qt.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "insert into teams(team_name) values ($1)"})
+	qt.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("duplicate key")})
+	parent.End()
+
+	spans := sr.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("want 3 spans, got %d", len(spans))
+	}
+	sel, ins := spans[0], spans[1]
+	if sel.Name() != "SELECT" || ins.Name() != "INSERT" {
+		t.Fatalf("want SELECT and INSERT spans, got %q and %q", sel.Name(), ins.Name())
+	}
+	if sel.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("query span must be a child of the caller's span")
+	}
+	if v, ok := attr(sel, "db.system.name"); !ok || v.AsString() != "postgresql" {
+		t.Fatalf("db.system.name want postgresql, got %v", v.Emit())
+	}
+	if v, ok := attr(sel, "db.rows_affected"); !ok || v.AsInt64() != 3 {
+		t.Fatalf("db.rows_affected want 3, got %v", v.Emit())
+	}
+	if ins.Status().Code != codes.Error {
+		t.Fatalf("failed query must mark span as error, got %v", ins.Status().Code)
+	}
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "file-exporter-span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read traces: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"file-exporter-span"`) {
+		t.Fatalf("traces file does not contain span, got %s", data)
+	}
+}
+
+func TestSetup_InvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Exporter: "jaeger", SampleRatio: 1},
+		{Exporter: ExporterFile, SampleRatio: 1},
+		{Exporter: ExporterStdout, SampleRatio: 2},
+	} {
+		if _, err := Setup(context.Background(), cfg); err == nil {
+			t.Fatalf("want error for %+v", cfg)
+		}
+	}
+}
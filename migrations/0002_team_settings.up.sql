@@ -0,0 +1,7 @@
+-- Настройки команды: сколько ревьюверов назначать на PR
+CREATE TABLE IF NOT EXISTS team_settings (
+    team_name VARCHAR(100) PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    min_reviewers SMALLINT NOT NULL,
+    max_reviewers SMALLINT NOT NULL,
+    CHECK (min_reviewers >= 0 AND max_reviewers >= 1 AND min_reviewers <= max_reviewers)
+);
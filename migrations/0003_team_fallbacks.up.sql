@@ -0,0 +1,18 @@
+-- Резервные команды, из которых добираются ревьюверы, если в команде не хватает кандидатов
+CREATE TABLE IF NOT EXISTS team_fallbacks (
+    team_name VARCHAR(100) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    fallback_team VARCHAR(100) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    position SMALLINT NOT NULL,
+    PRIMARY KEY (team_name, fallback_team),
+    CHECK (team_name <> fallback_team)
+);
+
+-- Команда, из которой был взят ревьювер
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS source_team VARCHAR(100) REFERENCES teams(team_name) ON UPDATE CASCADE;
+
+UPDATE pr_reviewers r
+SET source_team = u.team_name
+FROM users u
+WHERE u.user_id = r.reviewer_id AND r.source_team IS NULL;
+
+ALTER TABLE pr_reviewers ALTER COLUMN source_team SET NOT NULL;
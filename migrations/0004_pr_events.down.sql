@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_pr_events_append_only ON pr_events;
+DROP FUNCTION IF EXISTS pr_events_append_only();
+DROP INDEX IF EXISTS idx_pr_events_pr;
+DROP TABLE IF EXISTS pr_events;
@@ -0,0 +1,25 @@
+-- Журнал событий PR (только добавление)
+CREATE TABLE IF NOT EXISTS pr_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(150) NOT NULL REFERENCES pull_requests(pull_request_id),
+    event_type VARCHAR(32) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    user_id VARCHAR(100),
+    from_user_id VARCHAR(100),
+    to_user_id VARCHAR(100),
+    reason VARCHAR(200),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_events_pr ON pr_events(pull_request_id, event_id);
+
+-- Запрещаем изменение и удаление записей журнала
+CREATE OR REPLACE FUNCTION pr_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'pr_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_pr_events_append_only
+    BEFORE UPDATE OR DELETE ON pr_events
+    FOR EACH ROW EXECUTE FUNCTION pr_events_append_only();
@@ -0,0 +1,4 @@
+ALTER TABLE pr_events DROP COLUMN IF EXISTS verdict;
+
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS verdict_at;
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS verdict;
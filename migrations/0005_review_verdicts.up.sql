@@ -0,0 +1,7 @@
+-- Решение ревьювера по PR; NULL — ревью ещё не отправлено
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS verdict VARCHAR(32)
+    CHECK (verdict IN ('APPROVED', 'CHANGES_REQUESTED', 'COMMENTED'));
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS verdict_at TIMESTAMPTZ;
+
+-- Решение, отправленное ревьювером, в журнале событий
+ALTER TABLE pr_events ADD COLUMN IF NOT EXISTS verdict VARCHAR(32);
@@ -0,0 +1,20 @@
+-- До этой миграции закрытого без merge PR не было. Выдавать CLOSED за MERGED нельзя:
+-- исказятся число merge'ей, статистика ревьюверов и время до merge. Поэтому откат
+-- останавливается, пока закрытые PR есть; их нужно удалить или перенести вручную
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pull_requests WHERE status = 'CLOSED') THEN
+        RAISE EXCEPTION 'cannot roll back: % pull requests are CLOSED, the previous schema has no such status',
+            (SELECT COUNT(*) FROM pull_requests WHERE status = 'CLOSED');
+    END IF;
+END
+$$;
+
+-- Черновики возвращаются в OPEN. Журнал событий и назначения сохраняются
+UPDATE pull_requests SET status = 'OPEN' WHERE status = 'DRAFT';
+
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS closed_at;
+
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check
+    CHECK (status IN ('OPEN', 'MERGED'));
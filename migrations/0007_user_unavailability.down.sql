@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_unavailability_user;
+DROP TABLE IF EXISTS user_unavailability;
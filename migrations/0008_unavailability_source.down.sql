@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uq_user_unavailability_external;
+
+ALTER TABLE user_unavailability DROP COLUMN IF EXISTS external_uid;
+ALTER TABLE user_unavailability DROP COLUMN IF EXISTS source;
@@ -0,0 +1,8 @@
+-- Источник периода недоступности: NULL — добавлен вручную, иначе — имя календаря
+-- и UID события в нём. Повторный импорт того же события обновляет период
+ALTER TABLE user_unavailability ADD COLUMN IF NOT EXISTS source VARCHAR(100);
+ALTER TABLE user_unavailability ADD COLUMN IF NOT EXISTS external_uid VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_unavailability_external
+    ON user_unavailability(user_id, source, external_uid)
+    WHERE external_uid IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_work_hours_check;
+
+ALTER TABLE users DROP COLUMN IF EXISTS work_end;
+ALTER TABLE users DROP COLUMN IF EXISTS work_start;
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
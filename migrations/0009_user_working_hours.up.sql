@@ -0,0 +1,8 @@
+-- Часовой пояс и рабочие часы пользователя. Если work_start/work_end не заданы,
+-- пользователь считается доступным в любое время
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_start TIME;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS work_end TIME;
+
+ALTER TABLE users ADD CONSTRAINT users_work_hours_check
+    CHECK ((work_start IS NULL) = (work_end IS NULL));
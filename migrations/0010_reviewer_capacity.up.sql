@@ -0,0 +1,9 @@
+-- Лимит одновременных ревью: у пользователя или по умолчанию для команды.
+-- NULL — без ограничения
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_open_reviews INT
+    CONSTRAINT users_max_open_reviews_check CHECK (max_open_reviews > 0);
+ALTER TABLE team_settings ADD COLUMN IF NOT EXISTS default_max_open_reviews INT
+    CONSTRAINT team_settings_default_max_open_reviews_check CHECK (default_max_open_reviews > 0);
+
+-- PR не добрал ревьюверов, потому что все кандидаты упёрлись в лимит
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS capacity_exhausted BOOLEAN NOT NULL DEFAULT false;
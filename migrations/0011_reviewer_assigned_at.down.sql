@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_pr_events_type_created;
+DROP INDEX IF EXISTS idx_pr_reviewers_assigned_at;
+
+ALTER TABLE pr_reviewers DROP COLUMN IF EXISTS assigned_at;
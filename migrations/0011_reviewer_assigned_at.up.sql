@@ -0,0 +1,17 @@
+-- Время назначения ревьювера для статистики по периодам
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Для уже существующих назначений берём время последнего события назначения,
+-- а если журнала нет — время создания PR
+UPDATE pr_reviewers r
+SET assigned_at = COALESCE(
+    (SELECT MAX(e.created_at)
+     FROM pr_events e
+     WHERE e.pull_request_id = r.pull_request_id
+       AND ((e.event_type = 'assigned' AND e.user_id = r.reviewer_id)
+            OR (e.event_type = 'reassigned' AND e.to_user_id = r.reviewer_id))),
+    (SELECT pr.created_at FROM pull_requests pr WHERE pr.pull_request_id = r.pull_request_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_assigned_at ON pr_reviewers(assigned_at);
+CREATE INDEX IF NOT EXISTS idx_pr_events_type_created ON pr_events(event_type, created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_external_identities_user;
+DROP TABLE IF EXISTS external_identities;
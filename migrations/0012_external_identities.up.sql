@@ -0,0 +1,11 @@
+-- Учётные записи пользователей во внешних системах (логин GitHub и т.п.).
+-- По ним вебхуки находят user_id автора PR
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider VARCHAR(20) NOT NULL,
+    login VARCHAR(100) NOT NULL,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, login)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_identities_user ON external_identities(user_id);
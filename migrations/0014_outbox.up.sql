@@ -0,0 +1,16 @@
+-- Транзакционный outbox: событие PR пишется в той же транзакции, что и изменение,
+-- и затем публикуется фоновым процессом. dispatched_at проставляется после публикации
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,
+    aggregate_id VARCHAR(150) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE dispatched_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_dispatched ON outbox(dispatched_at) WHERE dispatched_at IS NOT NULL;
+
+-- Повторная публикация события из outbox не создаёт вторую доставку вебхука
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries(subscription_id, event_id);
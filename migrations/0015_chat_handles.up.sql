@@ -0,0 +1,3 @@
+-- Ник пользователя в мессенджере для уведомлений о назначении на ревью.
+-- NULL — ник не задан, в сообщении используется user_id
+ALTER TABLE users ADD COLUMN IF NOT EXISTS chat_handle VARCHAR(100);
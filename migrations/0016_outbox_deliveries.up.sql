@@ -0,0 +1,16 @@
+-- Публикация события outbox в каждый приёмник отдельно: у приёмника свои попытки
+-- и время следующей попытки, поэтому его сбой не задерживает остальные.
+-- Строка создаётся, когда relay впервые берёт событие для приёмника;
+-- pending ждёт попытки в next_attempt_at, delivered и failed — конечные статусы
+CREATE TABLE IF NOT EXISTS outbox_deliveries (
+    outbox_id BIGINT NOT NULL REFERENCES outbox(id) ON DELETE CASCADE,
+    sink VARCHAR(32) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    PRIMARY KEY (outbox_id, sink)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_deliveries_pending ON outbox_deliveries(sink, outbox_id) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_external_identities_external_id;
+ALTER TABLE external_identities DROP COLUMN IF EXISTS external_id;
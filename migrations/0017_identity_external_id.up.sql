@@ -0,0 +1,7 @@
+-- Постоянный ID пользователя во внешней системе (author_id в событиях GitLab).
+-- В отличие от логина он не меняется при переименовании. NULL — ID не задан
+ALTER TABLE external_identities ADD COLUMN IF NOT EXISTS external_id VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_external_identities_external_id
+    ON external_identities(provider, external_id)
+    WHERE external_id IS NOT NULL;